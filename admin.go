@@ -0,0 +1,157 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "sync/atomic"
+
+    "app/pmlog"
+    "app/pgcore"
+    "app/pmadmin"
+)
+//
+// StartAdmin() starts optional local admin api and probe endpoints,
+// probes share admin server on the same listen address
+//
+func (this *Application) StartAdmin() error {
+    var err error
+    this.health = pmadmin.NewHealth(this.config.Health.LiveWindow, this.Ready)
+
+    if len(this.config.Admin.Listen) > 0 {
+        this.admin = pmadmin.NewServer(this.config.Admin.Listen, this.config.Admin.Token, &adminBackend{ app: this })
+        if this.config.Health.Listen == this.config.Admin.Listen {
+            this.health.Register(this.admin)
+        }
+        err = this.admin.Start()
+        if err != nil {
+            return err
+        }
+    }
+    if len(this.config.Health.Listen) > 0 && this.config.Health.Listen != this.config.Admin.Listen {
+        this.probes = pmadmin.NewHealthServer(this.config.Health.Listen, this.health)
+        err = this.probes.Start()
+        if err != nil {
+            return err
+        }
+    }
+    metricsListen := this.config.Metrics.Listen
+    if len(metricsListen) > 0 {
+        metrics := pmadmin.NewMetrics(this.Metrics)
+        switch {
+            case this.admin != nil && metricsListen == this.config.Admin.Listen:
+                metrics.Register(this.admin, this.config.Metrics.Path)
+            case this.probes != nil && metricsListen == this.config.Health.Listen:
+                metrics.Register(this.probes, this.config.Metrics.Path)
+            default:
+                this.metrics = pmadmin.NewMetricsServer(metricsListen, this.config.Metrics.Path, metrics)
+                err = this.metrics.Start()
+                if err != nil {
+                    return err
+                }
+        }
+    }
+    return err
+}
+//
+// Metrics() collects bridge counters for metrics endpoint
+//
+func (this *Application) Metrics() map[string]int64 {
+    metrics := make(map[string]int64)
+    metrics["messages_inflight"]    = atomic.LoadInt64(&this.inflight)
+    metrics["requests_pending"]     = int64(this.requester.Pending())
+    metrics["dead_letters_total"]   = atomic.LoadInt64(&this.deadLetterCount)
+    metrics["dead_letters_dropped_total"] = this.deadLetters.Dropped()
+    metrics["unmatched_total"]      = this.unmatched.Total()
+    metrics["backlog_messages"]     = int64(this.backlog.Len())
+    metrics["backlog_evicted_total"] = this.backlog.Evicted()
+    if this.pg.Breaker().IsOpen() {
+        metrics["core_circuit_open"] = 1
+    } else {
+        metrics["core_circuit_open"] = 0
+    }
+    forwarded := this.forwarder.GetStats().Total()
+    metrics["forwarded_total"]      = forwarded.Forwarded
+    metrics["forward_looped_total"] = forwarded.Looped
+    metrics["forward_errors_total"] = forwarded.Errors
+    if this.tr.IsConnected() {
+        metrics["broker_connected"] = 1
+    } else {
+        metrics["broker_connected"] = 0
+    }
+    return metrics
+}
+//
+// adminBackend exposes bridge state to admin api
+//
+type adminBackend struct {
+    app     *Application
+}
+
+func (this *adminBackend) Status() pmadmin.Status {
+    var status pmadmin.Status
+    status.ObjectId     = this.app.objectId
+    status.CoreBound    = this.app.pg.IsBound()
+    status.JWTExpire    = this.app.pg.GetJWTExpire()
+    status.Transport    = this.app.tr.IsConnected()
+    status.BrokerURL    = this.app.brokerUrl
+    status.Subscription = atomic.LoadInt32(&this.app.controlActive) == 1
+    return status
+}
+
+func (this *adminBackend) Topics() []string {
+    return this.app.topics.GetArray()
+}
+
+func (this *adminBackend) Messages() []pmadmin.Message {
+    return this.app.recent.List()
+}
+
+func (this *adminBackend) Queues() map[string]int64 {
+    queues := make(map[string]int64)
+    queues["ingest"]    = atomic.LoadInt64(&this.app.inflight)
+    queues["requests"]  = int64(this.app.requester.Pending())
+    queues["backlog"]   = int64(this.app.backlog.Len())
+    this.app.firmwareMutex.Lock()
+    queues["firmware"]  = int64(len(this.app.firmwareTransfers))
+    this.app.firmwareMutex.Unlock()
+    return queues
+}
+
+func (this *adminBackend) Config() interface{} {
+    broker := map[string]string{
+        "url":          this.app.brokerUrl,
+        "username":     this.app.username,
+        "password":     "",
+    }
+    if len(this.app.password) > 0 {
+        broker["password"] = "******"
+    }
+    return map[string]interface{}{
+        "config":       this.app.config.Masked(),
+        "broker":       broker,
+        "topics":       this.app.topics.GetArray(),
+    }
+}
+
+func (this *adminBackend) Control(name string, params string) error {
+    var controlMessage pgcore.ControlExecutionMessage
+    controlMessage.Id           = -1
+    controlMessage.ObjectId     = this.app.objectId
+    controlMessage.Params       = params
+    switch name {
+        case pmadmin.ControlPublish:
+            controlMessage.Name = controlPublishName
+        case pmadmin.ControlReload:
+            controlMessage.Name = controlReloadName
+        case pmadmin.ControlSetTopics:
+            controlMessage.Name = controlSetTopicsName
+        default:
+            return errors.New("unknown admin control " + name)
+    }
+    pmlog.LogInfo("admin api control:", controlMessage.Name)
+    return this.app.DispatchControl(controlMessage)
+}
+//EOF
@@ -0,0 +1,115 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "context"
+    "time"
+    "sync/atomic"
+
+    "app/pmlog"
+    "app/pmvalid"
+)
+//
+// CoreCircuitChanged() switches ingest to backlog while core circuit
+// is open and flushes backlog when it closes
+//
+func (this *Application) CoreCircuitChanged(open bool) {
+    if open {
+        atomic.StoreInt32(&this.buffering, 1)
+        pmlog.LogWarning("core unavailable, ingest messages go to backlog")
+        return
+    }
+    atomic.StoreInt32(&this.buffering, 0)
+    go this.FlushBacklog()
+}
+
+type backlogMessage struct {
+    topic       string
+    payload     []byte
+    deliver     func(ctx context.Context)
+}
+//
+// BacklogMessage() buffers message delivery, evicted message is
+// dead-lettered
+//
+func (this *Application) BacklogMessage(topic string, payload []byte, deliver func(ctx context.Context)) {
+    if this.backlog == nil {
+        return
+    }
+    evicted := this.backlog.Push(&backlogMessage{ topic: topic, payload: payload, deliver: deliver })
+    if evicted != nil {
+        message := evicted.(*backlogMessage)
+        this.DeadLetterMessage(message.topic, message.payload, pmvalid.ReasonOverflow,
+                                    errors.New("core backlog full"))
+        this.recent.Add(message.topic, "", len(message.payload), pmvalid.ReasonOverflow)
+    }
+}
+//
+// FlushBacklog() delivers buffered messages in arrival order, messages
+// not delivered on shutdown or new core outage go back to backlog
+//
+func (this *Application) FlushBacklog() {
+    items := this.backlog.Drain()
+    if len(items) == 0 {
+        return
+    }
+    pmlog.LogInfo("core available, deliver", len(items), "backlog messages")
+    for i, item := range items {
+        if atomic.LoadInt32(&this.stopping) == 1 || atomic.LoadInt32(&this.buffering) == 1 {
+            this.RequeueBacklog(items[i:])
+            return
+        }
+        atomic.AddInt64(&this.inflight, 1)
+        ctx, cancel := this.MessageContext()
+        item.(*backlogMessage).deliver(ctx)
+        cancel()
+        atomic.AddInt64(&this.inflight, -1)
+    }
+}
+//
+// RequeueBacklog() puts undelivered messages back, evicted message is
+// dead-lettered
+//
+func (this *Application) RequeueBacklog(items []interface{}) {
+    pmlog.LogInfo("requeue", len(items), "backlog messages")
+    for _, evicted := range this.backlog.Requeue(items) {
+        message := evicted.(*backlogMessage)
+        this.DeadLetterMessage(message.topic, message.payload, pmvalid.ReasonOverflow,
+                                    errors.New("core backlog full"))
+        this.recent.Add(message.topic, "", len(message.payload), pmvalid.ReasonOverflow)
+    }
+}
+//
+// DrainBacklog() delivers backlog on shutdown while core is available
+// and deadline is not reached, rest is dead-lettered as undelivered
+//
+func (this *Application) DrainBacklog(deadline time.Time) {
+    items := this.backlog.Drain()
+    delivered := 0
+    for ; delivered < len(items); delivered++ {
+        if atomic.LoadInt32(&this.buffering) == 1 || !time.Now().Before(deadline) {
+            break
+        }
+        msgCtx, msgCancel := this.MessageContext()
+        ctx, cancel := context.WithDeadline(msgCtx, deadline)
+        items[delivered].(*backlogMessage).deliver(ctx)
+        cancel()
+        msgCancel()
+    }
+    lost := append(items[delivered:], this.backlog.Drain()...)
+    if len(lost) == 0 {
+        return
+    }
+    pmlog.LogError("application shutdown with", len(lost), "undelivered backlog messages")
+    for _, item := range lost {
+        message := item.(*backlogMessage)
+        this.DeadLetterMessage(message.topic, message.payload, pmvalid.ReasonUndelivered,
+                                    errors.New("core unavailable on shutdown"))
+        this.recent.Add(message.topic, "", len(message.payload), pmvalid.ReasonUndelivered)
+    }
+}
+//EOF
@@ -0,0 +1,63 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "context"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "app/pmqueue"
+)
+//
+// TestFlushBacklogRequeue checks messages not delivered on shutdown
+// go back to backlog in order
+//
+func TestFlushBacklogRequeue(t *testing.T) {
+    app := NewApplication()
+    app.backlog = pmqueue.NewBacklog(10)
+    delivered := make([]string, 0)
+    for _, topic := range []string{ "a", "b", "c" } {
+        topic := topic
+        app.BacklogMessage(topic, nil, func(ctx context.Context) {
+            delivered = append(delivered, topic)
+            atomic.StoreInt32(&app.stopping, 1)
+        })
+    }
+    app.BacklogMessage("d", nil, nil)
+    app.FlushBacklog()
+    if len(delivered) != 1 || app.backlog.Len() != 3 {
+        t.Fatal("wrong flush on shutdown:", delivered, app.backlog.Len())
+    }
+    items := app.backlog.Drain()
+    if items[0].(*backlogMessage).topic != "b" || items[2].(*backlogMessage).topic != "d" {
+        t.Error("requeued messages out of order")
+    }
+}
+//
+// TestDrainBacklog checks backlog is delivered on shutdown and
+// messages left after core outage are dead-lettered
+//
+func TestDrainBacklog(t *testing.T) {
+    app := NewApplication()
+    app.backlog = pmqueue.NewBacklog(10)
+    delivered := make([]string, 0)
+    for _, topic := range []string{ "a", "b", "c" } {
+        topic := topic
+        app.BacklogMessage(topic, nil, func(ctx context.Context) {
+            delivered = append(delivered, topic)
+            atomic.StoreInt32(&app.buffering, 1)
+        })
+    }
+    app.DrainBacklog(time.Now().Add(time.Second))
+    if len(delivered) != 1 || app.backlog.Len() != 0 {
+        t.Fatal("wrong drain on shutdown:", delivered, app.backlog.Len())
+    }
+    if atomic.LoadInt64(&app.deadLetterCount) != 2 {
+        t.Error("undelivered messages not dead-lettered:", atomic.LoadInt64(&app.deadLetterCount))
+    }
+}
+//EOF
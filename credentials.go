@@ -0,0 +1,48 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "app/pmlog"
+    "app/pmsecret"
+)
+//
+// GetCredentialProperty() reads and decodes broker credential, plain
+// stored value is encrypted in place when encryption is enabled
+//
+func (this *Application) GetCredentialProperty(propertyName string) (string, error) {
+    var err error
+    value, err := this.pg.GetObjectPropertyValue(this.objectId, propertyName)
+    if err != nil {
+        return "", err
+    }
+    if this.secrets.Encrypting() && !pmsecret.IsEncrypted(value) && !pmsecret.IsReference(value) && len(value) > 0 {
+        encoded, err := this.secrets.Encode(value)
+        if err != nil {
+            return "", err
+        }
+        _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyName, encoded)
+        if err != nil {
+            pmlog.LogWarning("unable encrypt property", propertyName, "error:", err)
+        } else {
+            pmlog.LogInfo("property", propertyName, "encrypted at rest")
+        }
+    }
+    return this.secrets.Decode(value)
+}
+//
+// SetCredentialProperty() encodes and writes broker credential
+//
+func (this *Application) SetCredentialProperty(propertyName string, value string) error {
+    var err error
+    encoded, err := this.secrets.Encode(value)
+    if err != nil {
+        return err
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyName, encoded)
+    return err
+}
+
+//EOF
@@ -0,0 +1,255 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "encoding/json"
+    "context"
+    "time"
+    "sync/atomic"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmvalid"
+)
+//
+//
+const (
+    // Payload json schemas by topic pattern, json
+    propertyPayloadSchemasName          string = "PayloadSchemas"
+    propertyPayloadSchemasDefaultValue  string = `{"schemas":[]}`
+
+    // Rejected messages, empty topic disables republish
+    propertyDeadLetterTopicName         string = "DeadLetterTopic"
+    propertyDeadLetterCountName         string = "DeadLetterCount"
+)
+//
+//
+func (this *Application) SetPayloadValidator(validator *pmvalid.Validator) {
+    this.validatorMutex.Lock()
+    defer this.validatorMutex.Unlock()
+    this.payloadValidator = validator
+}
+//
+//
+func (this *Application) GetPayloadValidator() *pmvalid.Validator {
+    this.validatorMutex.Lock()
+    defer this.validatorMutex.Unlock()
+    return this.payloadValidator
+}
+//
+//
+func (this *Application) SetDeadLetterTopic(topic string) {
+    this.validatorMutex.Lock()
+    defer this.validatorMutex.Unlock()
+    this.deadLetterTopic = topic
+}
+//
+//
+func (this *Application) GetDeadLetterTopic() string {
+    this.validatorMutex.Lock()
+    defer this.validatorMutex.Unlock()
+    return this.deadLetterTopic
+}
+//
+//*********************************************************************//
+//
+func (this *Application) newSetPayloadSchemasControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set payload json schemas"
+    control.Hidden          = false
+    control.RPC             = controlSetPayloadSchemasName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetPayloadSchemasControlArgSchemas() *pgschema.Control {
+    control := this.newSetPayloadSchemasControl()
+    control.Description     = "Payload schemas"
+    control.Type            = pgschema.StringType
+    control.Argument        = "schemas"
+    return control
+}
+//
+//
+func (this *Application) newPayloadSchemasProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyPayloadSchemasName
+    property.Type           = pgschema.StringType
+    property.Description    = "Payload json schemas by topic"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = propertyPayloadSchemasDefaultValue
+    return property
+}
+//
+//
+func (this *Application) newDeadLetterTopicProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDeadLetterTopicName
+    property.Type           = pgschema.StringType
+    property.Description    = "Dead-letter topic"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = ""
+    return property
+}
+//
+//
+func (this *Application) newDeadLetterCountProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDeadLetterCountName
+    property.Type           = pgschema.IntType
+    property.Description    = "Rejected messages"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = "0"
+    return property
+}
+//
+//
+func (this *Application) SetPayloadSchemasController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackPayloadSchemasArguments(controlMessage.Params)
+
+    validator, err := pmvalid.ValidatorFromString(arguments.Schemas)
+    if err != nil {
+        pmlog.LogError("wrong payload schemas:", err)
+        return err
+    }
+    this.SetPayloadValidator(validator)
+    pmlog.LogInfo("set payload schemas:", validator.GetJSON())
+
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyPayloadSchemasName, arguments.Schemas)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+// DeadLetterMessage() counts rejected message and queues it with error
+// envelope to dead-letter topic. Publish runs out of message handler,
+// waiting for it there may block broker client.
+//
+func (this *Application) DeadLetterMessage(topic string, payload []byte, reason string, cause error) {
+    atomic.AddInt64(&this.deadLetterCount, 1)
+    pmlog.LogWarning("message of topic", topic, "rejected as", reason, "with error:", cause)
+
+    deadLetterTopic := this.GetDeadLetterTopic()
+    if len(deadLetterTopic) == 0 {
+        return
+    }
+    letter := pmvalid.NewDeadLetter(topic, payload, reason, cause)
+    if !this.deadLetters.Send(deadLetterTopic, letter) {
+        pmlog.LogWarning("dead-letter outbox full, oldest letter dropped")
+    }
+}
+//
+//
+func (this *Application) WriteDeadLetterCount() {
+    count := atomic.LoadInt64(&this.deadLetterCount)
+    if count == atomic.LoadInt64(&this.deadLetterWritten) {
+        return
+    }
+    _, err := this.pg.UpdateObjectPropertyByName(this.objectId, propertyDeadLetterCountName, strconv.FormatInt(count, 10))
+    if err != nil {
+        pmlog.LogError("error update dead-letter count property:", err)
+        return
+    }
+    atomic.StoreInt64(&this.deadLetterWritten, count)
+}
+//
+//*********************************************************************//
+//
+type PayloadSchemasArguments struct {
+    Schemas     string      `json:"schemas"`
+}
+
+func NewPayloadSchemasArguments() *PayloadSchemasArguments {
+    var arguments PayloadSchemasArguments
+    return &arguments
+}
+
+func UnpackPayloadSchemasArguments(jsonString string) (*PayloadSchemasArguments, error) {
+    var err error
+    var arguments PayloadSchemasArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *PayloadSchemasArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *PayloadSchemasArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// RetryMessage() runs delivery after delay out of mqtt handler to keep
+// message dispatch going, message counts in flight until done
+//
+func (this *Application) RetryMessage(delay time.Duration, deliver func(ctx context.Context)) {
+    atomic.AddInt64(&this.inflight, 1)
+    time.AfterFunc(delay, func() {
+        defer atomic.AddInt64(&this.inflight, -1)
+        ctx, cancel := this.MessageContext()
+        defer cancel()
+        deliver(ctx)
+    })
+}
+//
+// DeliveryResult() accounts delivered, unclaimed and failed messages
+//
+func (this *Application) DeliveryResult(mqttTopic string, topicBase string, payload []byte, claimed bool, err error) {
+    if err != nil {
+        this.DeadLetterMessage(mqttTopic, payload, pmvalid.ReasonUndelivered, err)
+        this.recent.Add(mqttTopic, topicBase, len(payload), pmvalid.ReasonUndelivered)
+        return
+    }
+    if !claimed {
+        this.UnmatchedMessage(topicBase, mqttTopic)
+        this.DeadLetterMessage(mqttTopic, payload, pmvalid.ReasonUnclaimed,
+                                errors.New("no device with topic base " + topicBase))
+        this.recent.Add(mqttTopic, topicBase, len(payload), pmvalid.ReasonUnclaimed)
+        return
+    }
+    this.unmatched.Remove(topicBase)
+    this.recent.Add(mqttTopic, topicBase, len(payload), messageResultDelivered)
+}
+//
+// GetDeadLetterProperties() reads payload schemas, dead letter topic and count of application
+//
+func (this *Application) GetDeadLetterProperties() error {
+    var err error
+
+    payloadSchemasStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyPayloadSchemasName)
+    if err != nil {
+        return err
+    }
+    payloadValidator, err := pmvalid.ValidatorFromString(payloadSchemasStr)
+    if err != nil {
+        pmlog.LogError("application payload schemas error:", err)
+        payloadValidator = pmvalid.NewValidator()
+        err = nil
+    }
+    this.SetPayloadValidator(payloadValidator)
+
+    deadLetterTopic, err := this.pg.GetObjectPropertyValue(this.objectId, propertyDeadLetterTopicName)
+    if err != nil {
+        return err
+    }
+    this.SetDeadLetterTopic(deadLetterTopic)
+
+    deadLetterCount, err := this.getInt64Property(propertyDeadLetterCountName, "0")
+    if err != nil {
+        return err
+    }
+    atomic.StoreInt64(&this.deadLetterCount, deadLetterCount)
+    atomic.StoreInt64(&this.deadLetterWritten, deadLetterCount)
+    return err
+}
+//EOF
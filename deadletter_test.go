@@ -0,0 +1,57 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "testing"
+    "time"
+
+    "app/pmvalid"
+)
+//
+// TestDeadLetterInHandler checks rejected message handler does not
+// wait for dead-letter publish
+//
+func TestDeadLetterInHandler(t *testing.T) {
+    app := NewApplication()
+    validator, err := pmvalid.ValidatorFromString(`{"schemas":[{"match":"^dev/","schema":{"type":"object"}}]}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    app.SetPayloadValidator(validator)
+    app.SetDeadLetterTopic("dead")
+
+    release := make(chan struct{})
+    published := make(chan string, 1)
+    app.deadLetters = pmvalid.NewOutbox(10, func(topic string, message string) error {
+        <-release
+        published <- topic
+        return nil
+    })
+    app.deadLetters.Start()
+
+    handled := make(chan struct{})
+    go func() {
+        app.CreateRealTopicHandler()(nil, testMessage{ topic: "dev/a", payload: []byte("not json") })
+        close(handled)
+    }()
+    select {
+        case <-handled:
+        case <-time.After(5 * time.Second):
+            t.Fatal("handler waits for dead-letter publish")
+    }
+    close(release)
+    select {
+        case topic := <-published:
+            if topic != "dead" {
+                t.Error("wrong dead-letter topic:", topic)
+            }
+        case <-time.After(5 * time.Second):
+            t.Error("dead letter not published")
+    }
+    app.deadLetters.Stop(time.Now().Add(time.Second))
+}
+
+//EOF
@@ -0,0 +1,192 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "encoding/json"
+    "fmt"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmdown"
+)
+//
+//
+const (
+    // Downlink routing
+    propertyDownlinkRoutesName          string = "DownlinkRoutes"
+    propertyDownlinkRoutesDefaultValue  string = `{"routes":[{"schemaId":"6a34e442-cc3c-4586-853e-9058e1fd7739","control":"SendCommand","topic":"<<topicBase>>/command","payload":"<<command>>"}]}`
+
+    devicePropertyDownlinkTopicName     string = "DownlinkTopic"
+)
+//
+//
+func (this *Application) SetDownlinkRoutes(routes *pmdown.Routes) {
+    this.downlinkRoutesMutex.Lock()
+    defer this.downlinkRoutesMutex.Unlock()
+    this.downlinkRoutes = routes
+}
+//
+//
+func (this *Application) GetDownlinkRoutes() *pmdown.Routes {
+    this.downlinkRoutesMutex.Lock()
+    defer this.downlinkRoutesMutex.Unlock()
+    return this.downlinkRoutes
+}
+//
+//*********************************************************************//
+//
+func (this *Application) newSetDownlinkRoutesControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set downlink routes"
+    control.Hidden          = false
+    control.RPC             = controlSetDownlinkRoutesName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetDownlinkRoutesControlArgRoutes() *pgschema.Control {
+    control := this.newSetDownlinkRoutesControl()
+    control.Description     = "Downlink routes"
+    control.Type            = pgschema.StringType
+    control.Argument        = "routes"
+    return control
+}
+//
+//
+func (this *Application) newDownlinkRoutesProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDownlinkRoutesName
+    property.Type           = pgschema.StringType
+    property.Description    = "Downlink routes for device controls"
+    property.GroupName      = propertyGroupDownlink
+    property.DefaultValue   = propertyDownlinkRoutesDefaultValue
+    return property
+}
+//
+//
+func (this *Application) SetDownlinkRoutesController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackDownlinkRoutesArguments(controlMessage.Params)
+
+    routes, err := pmdown.RoutesFromString(arguments.Routes)
+    if err != nil {
+        pmlog.LogError("wrong downlink routes:", err)
+        return err
+    }
+    this.SetDownlinkRoutes(routes)
+
+    pmlog.LogInfo("set downlink routes:", routes.GetJSON())
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyDownlinkRoutesName, arguments.Routes)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+// DownlinkController() publishes control execution on device object to device topic
+//
+func (this *Application) DownlinkController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+
+    schemaId := controlMessage.HelperData.SchemaId
+    if len(schemaId) == 0 {
+        object, err := this.pg.GetObject(controlMessage.ObjectId)
+        if err != nil {
+            return err
+        }
+        schemaId = object.SchemaId
+    }
+
+    route, exists := this.GetDownlinkRoutes().Find(schemaId, controlMessage.Name)
+    if !exists {
+        return errors.New(fmt.Sprintf("no downlink route for control %s of schema %s", controlMessage.Name, schemaId))
+    }
+
+    topicBase, err := this.pg.GetObjectPropertyValue(controlMessage.ObjectId, mqttPropertyTopicBaseName)
+    if err != nil {
+        return err
+    }
+    if len(topicBase) == 0 {
+        return errors.New(fmt.Sprintf("device %s have empty topic base", controlMessage.ObjectId))
+    }
+
+    values, err := pmdown.ArgumentValues(controlMessage.Params)
+    if err != nil {
+        return err
+    }
+    values[pmdown.TopicBaseKey] = topicBase
+
+    topicTemplate := route.Topic
+    deviceTopic, _ := this.pg.GetObjectPropertyValue(controlMessage.ObjectId, devicePropertyDownlinkTopicName)
+    if len(deviceTopic) > 0 {
+        topicTemplate = deviceTopic
+    }
+    topic   := pmdown.Render(topicTemplate, values)
+    payload, err := pgcore.DecodePayload(pmdown.Render(route.Payload, values), route.Encoding)
+    if err != nil {
+        return err
+    }
+
+    pmlog.LogDetail("downlink for device", controlMessage.ObjectId, "topic:", topic, "payload size:", len(payload))
+    err = this.tr.PublishBytes(topic, payload)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+//*********************************************************************//
+//
+type DownlinkRoutesArguments struct {
+    Routes   string      `json:"routes"`
+}
+
+func NewDownlinkRoutesArguments() *DownlinkRoutesArguments {
+    var arguments DownlinkRoutesArguments
+    return &arguments
+}
+
+func UnpackDownlinkRoutesArguments(jsonString string) (*DownlinkRoutesArguments, error) {
+    var err error
+    var arguments DownlinkRoutesArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *DownlinkRoutesArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *DownlinkRoutesArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetDownlinkProperties() reads downlink routes of application
+//
+func (this *Application) GetDownlinkProperties() error {
+    var err error
+
+    downlinkRoutesStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyDownlinkRoutesName)
+    if err != nil {
+        return err
+    }
+    if len(downlinkRoutesStr) == 0 {
+        downlinkRoutesStr = propertyDownlinkRoutesDefaultValue
+    }
+    downlinkRoutes, err := pmdown.RoutesFromString(downlinkRoutesStr)
+    if err != nil {
+        pmlog.LogError("application downlink routes error:", err)
+        downlinkRoutes = pmdown.NewRoutes()
+        err = nil
+    }
+    this.SetDownlinkRoutes(downlinkRoutes)
+    return err
+}
+//EOF
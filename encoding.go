@@ -0,0 +1,76 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+)
+//
+//
+const (
+    // Payload encoding: compat, utf8, base64, hex
+    propertyPayloadEncodingName         string = "PayloadEncoding"
+    propertyPayloadEncodingDefaultValue string = pgcore.EncodingCompatName
+    propertyPayloadEncodingValueSet     string = "compat,utf8,base64,hex"
+)
+//
+//
+func (this *Application) SetPayloadEncoding(encoding string) {
+    this.payloadEncodingMutex.Lock()
+    defer this.payloadEncodingMutex.Unlock()
+    this.payloadEncoding = encoding
+}
+//
+//
+func (this *Application) GetPayloadEncoding() string {
+    this.payloadEncodingMutex.Lock()
+    defer this.payloadEncodingMutex.Unlock()
+    return this.payloadEncoding
+}
+func (this *Application) newPublishControlArgEncoding() *pgschema.Control {
+    control := this.newPublishControl()
+    control.Description     = "Payload encoding"
+    control.Type            = pgschema.StringType
+    control.Argument        = "encoding"
+    control.ValueSet        = "utf8,base64,hex"
+    return control
+}
+//
+//
+func (this *Application) newPayloadEncodingProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyPayloadEncodingName
+    property.Type           = pgschema.StringType
+    property.Description    = "Uplink payload encoding"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = propertyPayloadEncodingDefaultValue
+    property.ValueSet       = propertyPayloadEncodingValueSet
+    return property
+}
+//
+// GetEncodingProperties() reads default payload encoding of application
+//
+func (this *Application) GetEncodingProperties() error {
+    var err error
+
+    payloadEncodingStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyPayloadEncodingName)
+    if err != nil {
+        return err
+    }
+    if len(payloadEncodingStr) == 0 {
+        payloadEncodingStr = propertyPayloadEncodingDefaultValue
+    }
+    payloadEncoding, err := pgcore.ParseEncoding(payloadEncodingStr)
+    if err != nil {
+        pmlog.LogError("application payload encoding error:", err)
+        payloadEncoding = pgcore.EncodingCompat
+        err = nil
+    }
+    this.SetPayloadEncoding(payloadEncoding)
+    return err
+}
+//EOF
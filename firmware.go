@@ -0,0 +1,256 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "encoding/json"
+    "fmt"
+    "time"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmfirm"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+//
+//
+const (
+    // Firmware transfer topic templates, json
+    propertyFirmwareLayoutName          string = "FirmwareLayout"
+)
+//
+//
+func (this *Application) SetFirmwareLayout(layout *pmfirm.Layout) {
+    this.firmwareMutex.Lock()
+    defer this.firmwareMutex.Unlock()
+    this.firmwareLayout = layout
+}
+//
+//
+func (this *Application) GetFirmwareLayout() *pmfirm.Layout {
+    this.firmwareMutex.Lock()
+    defer this.firmwareMutex.Unlock()
+    return this.firmwareLayout
+}
+//
+// LockFirmwareTransfer() allows one transfer per topic base
+//
+func (this *Application) LockFirmwareTransfer(topicBase string) bool {
+    this.firmwareMutex.Lock()
+    defer this.firmwareMutex.Unlock()
+    if this.firmwareTransfers[topicBase] {
+        return false
+    }
+    this.firmwareTransfers[topicBase] = true
+    return true
+}
+//
+//
+func (this *Application) UnlockFirmwareTransfer(topicBase string) {
+    this.firmwareMutex.Lock()
+    defer this.firmwareMutex.Unlock()
+    delete(this.firmwareTransfers, topicBase)
+}
+//
+//
+func (this *Application) newSendFirmwareControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Send firmware to device"
+    control.Hidden          = false
+    control.RPC             = controlSendFirmwareName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSendFirmwareControlArgMediaId() *pgschema.Control {
+    control := this.newSendFirmwareControl()
+    control.Description     = "Firmware media object id"
+    control.Type            = pgschema.StringType
+    control.Argument        = "mediaId"
+    return control
+}
+func (this *Application) newSendFirmwareControlArgTopicBase() *pgschema.Control {
+    control := this.newSendFirmwareControl()
+    control.Description     = "Device topic base"
+    control.Type            = pgschema.StringType
+    control.Argument        = "topicBase"
+    return control
+}
+func (this *Application) newSendFirmwareControlArgChunkSize() *pgschema.Control {
+    control := this.newSendFirmwareControl()
+    control.Description     = "Chunk size"
+    control.Type            = pgschema.IntType
+    control.Argument        = "chunkSize"
+    control.DefaultValue    = strconv.Itoa(pmfirm.DefaultChunkSize)
+    return control
+}
+func (this *Application) newSendFirmwareControlArgTimeout() *pgschema.Control {
+    control := this.newSendFirmwareControl()
+    control.Description     = "Chunk ack timeout, sec"
+    control.Type            = pgschema.IntType
+    control.Argument        = "timeout"
+    control.DefaultValue    = strconv.Itoa(pmfirm.DefaultTimeout)
+    return control
+}
+func (this *Application) newSendFirmwareControlArgRetries() *pgschema.Control {
+    control := this.newSendFirmwareControl()
+    control.Description     = "Chunk retries"
+    control.Type            = pgschema.IntType
+    control.Argument        = "retries"
+    control.DefaultValue    = strconv.Itoa(pmfirm.DefaultRetries)
+    return control
+}
+//
+//
+func (this *Application) newFirmwareLayoutProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyFirmwareLayoutName
+    property.Type           = pgschema.StringType
+    property.Description    = "Firmware transfer topics"
+    property.GroupName      = propertyGroupDownlink
+    property.DefaultValue   = pmfirm.NewLayout().GetJSON()
+    return property
+}
+//
+// FirmwareController() streams firmware image to device and reports progress
+//
+func (this *Application) FirmwareController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+
+    err = this.SendFirmware(controlMessage)
+    if err != nil {
+        pmlog.LogError("firmware transfer error:", err)
+        return this.pg.CreateControlExecutionReport(controlMessage.Id, true, true, err.Error())
+    }
+    return this.pg.CreateControlExecutionReport(controlMessage.Id, false, true, "firmware transfer done")
+}
+//
+//
+func (this *Application) SendFirmware(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+
+    arguments, err := UnpackFirmwareArguments(controlMessage.Params)
+    if err != nil {
+        return err
+    }
+    topicBase := arguments.TopicBase
+    if controlMessage.ObjectId != this.objectId {
+        topicBase, err = this.pg.GetObjectPropertyValue(controlMessage.ObjectId, mqttPropertyTopicBaseName)
+        if err != nil {
+            return err
+        }
+    }
+    if len(topicBase) == 0 {
+        return errors.New("firmware transfer: empty topic base")
+    }
+
+    transfer := pmfirm.NewTransfer(this.GetFirmwareLayout(), topicBase)
+    transfer.ChunkSize, err = parseIntArgument(arguments.ChunkSize, pmfirm.DefaultChunkSize)
+    if err != nil {
+        return err
+    }
+    timeout, err := parseIntArgument(arguments.Timeout, pmfirm.DefaultTimeout)
+    if err != nil {
+        return err
+    }
+    transfer.Timeout = time.Duration(timeout) * time.Second
+    transfer.Retries, err = parseIntArgument(arguments.Retries, pmfirm.DefaultRetries)
+    if err != nil {
+        return err
+    }
+
+    if !this.LockFirmwareTransfer(topicBase) {
+        return errors.New("firmware transfer already running for " + topicBase)
+    }
+    defer this.UnlockFirmwareTransfer(topicBase)
+
+    image, err := this.pg.DownloadMediaFile(arguments.MediaId)
+    if err != nil {
+        return err
+    }
+
+    ackHandler := func(client mqtt.Client, message mqtt.Message) {
+        transfer.Ack(message.Payload())
+    }
+    ackTopic := transfer.AckTopic()
+    err = this.SubscribeTemporary(ackTopic, ackHandler)
+    if err != nil {
+        return err
+    }
+    defer this.UnsubscribeTemporary(ackTopic)
+
+    progress := func(sent int, total int) {
+        report := fmt.Sprintf("firmware transfer %d/%d chunks", sent, total)
+        pmlog.LogInfo(report, "to", topicBase)
+        err := this.pg.CreateControlExecutionReport(controlMessage.Id, false, false, report)
+        if err != nil {
+            pmlog.LogError("firmware progress report error:", err)
+        }
+    }
+
+    pmlog.LogInfo("start firmware transfer of", arguments.MediaId, "size", len(image), "to", topicBase)
+    err = transfer.Run(this.appCtx, image, this.tr.PublishBytes, progress)
+    if err != nil {
+        return err
+    }
+    pmlog.LogInfo("firmware transfer to", topicBase, "done")
+    return err
+}
+//
+//*********************************************************************//
+//
+type FirmwareArguments struct {
+    MediaId     string      `json:"mediaId"`
+    TopicBase   string      `json:"topicBase"`
+    ChunkSize   string      `json:"chunkSize"`
+    Timeout     string      `json:"timeout"`
+    Retries     string      `json:"retries"`
+}
+
+func NewFirmwareArguments() *FirmwareArguments {
+    var arguments FirmwareArguments
+    return &arguments
+}
+
+func UnpackFirmwareArguments(jsonString string) (*FirmwareArguments, error) {
+    var err error
+    var arguments FirmwareArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *FirmwareArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *FirmwareArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetFirmwareProperties() reads firmware layout of application
+//
+func (this *Application) GetFirmwareProperties() error {
+    var err error
+
+    firmwareLayoutStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyFirmwareLayoutName)
+    if err != nil {
+        return err
+    }
+    firmwareLayout, err := pmfirm.LayoutFromString(firmwareLayoutStr)
+    if err != nil {
+        pmlog.LogError("application firmware layout error:", err)
+        firmwareLayout = pmfirm.NewLayout()
+        err = nil
+    }
+    this.SetFirmwareLayout(firmwareLayout)
+    return err
+}
+//EOF
@@ -0,0 +1,163 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmfwd"
+)
+//
+//
+const (
+    // Broker to broker forwarding, json
+    propertyForwardRulesName            string = "ForwardRules"
+    propertyForwardRulesDefaultValue    string = `{"brokers":[],"rules":[]}`
+    propertyForwardStatsName            string = "ForwardStats"
+    propertyForwardedName               string = "ForwardedMessages"
+)
+//
+//*********************************************************************//
+//
+func (this *Application) newSetForwardRulesControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set broker forwarding rules"
+    control.Hidden          = false
+    control.RPC             = controlSetForwardRulesName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetForwardRulesControlArgRules() *pgschema.Control {
+    control := this.newSetForwardRulesControl()
+    control.Description     = "Forwarding rules"
+    control.Type            = pgschema.StringType
+    control.Argument        = "rules"
+    return control
+}
+//
+//
+func (this *Application) newForwardRulesProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyForwardRulesName
+    property.Type           = pgschema.StringType
+    property.Description    = "Broker forwarding rules"
+    property.GroupName      = propertyGroupForwarding
+    property.DefaultValue   = propertyForwardRulesDefaultValue
+    return property
+}
+//
+//
+func (this *Application) newForwardStatsProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyForwardStatsName
+    property.Type           = pgschema.StringType
+    property.Description    = "Forwarding statistics by rule"
+    property.GroupName      = propertyGroupForwarding
+    property.DefaultValue   = "{}"
+    return property
+}
+//
+//
+func (this *Application) newForwardedProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyForwardedName
+    property.Type           = pgschema.IntType
+    property.Description    = "Forwarded messages"
+    property.GroupName      = propertyGroupForwarding
+    property.DefaultValue   = "0"
+    return property
+}
+//
+//
+func (this *Application) SetForwardRulesController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackForwardRulesArguments(controlMessage.Params)
+
+    config, err := pmfwd.ConfigFromString(arguments.Rules)
+    if err != nil {
+        pmlog.LogError("wrong forward rules:", err)
+        return err
+    }
+    this.forwarder.SetConfig(config)
+    pmlog.LogInfo("set forward rules with", len(config.Rules), "rules")
+
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyForwardRulesName, arguments.Rules)
+    if err != nil {
+        return err
+    }
+    return this.forwarder.Connect()
+}
+//
+// WriteForwardStats() exposes forwarding counters on bridge object
+//
+func (this *Application) WriteForwardStats() {
+    var err error
+    if len(this.forwarder.GetConfig().Rules) == 0 {
+        return
+    }
+    stats := this.forwarder.GetStats()
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyForwardStatsName, stats.GetJSON())
+    if err != nil {
+        pmlog.LogError("error update forward stats property:", err)
+    }
+    total := stats.Total()
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyForwardedName, strconv.FormatInt(total.Forwarded, 10))
+    if err != nil {
+        pmlog.LogError("error update forwarded property:", err)
+    }
+}
+//
+//*********************************************************************//
+//
+type ForwardRulesArguments struct {
+    Rules   string      `json:"rules"`
+}
+
+func NewForwardRulesArguments() *ForwardRulesArguments {
+    var arguments ForwardRulesArguments
+    return &arguments
+}
+
+func UnpackForwardRulesArguments(jsonString string) (*ForwardRulesArguments, error) {
+    var err error
+    var arguments ForwardRulesArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *ForwardRulesArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *ForwardRulesArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetForwardProperties() reads topic forwarding rules of application
+//
+func (this *Application) GetForwardProperties() error {
+    var err error
+
+    forwardRulesStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyForwardRulesName)
+    if err != nil {
+        return err
+    }
+    forwardConfig, err := pmfwd.ConfigFromString(forwardRulesStr)
+    if err != nil {
+        pmlog.LogError("application forward rules error:", err)
+        forwardConfig = pmfwd.NewConfig()
+        err = nil
+    }
+    this.forwarder.SetConfig(forwardConfig)
+    return err
+}
+//EOF
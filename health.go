@@ -0,0 +1,27 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "sync/atomic"
+)
+//
+// Ready() reports core binding, control subscription and broker connection
+//
+func (this *Application) Ready() error {
+    var err error
+    if !this.pg.IsBound() {
+        return errors.New("core not bound")
+    }
+    if atomic.LoadInt32(&this.controlActive) == 0 {
+        return errors.New("control subscription not active")
+    }
+    if !this.tr.IsConnected() {
+        return errors.New("mqtt transport not connected")
+    }
+    return err
+}
+//EOF
@@ -0,0 +1,282 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "context"
+    "time"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmdevs"
+)
+//
+//
+const (
+    // Device lifecycle properties, sec
+    propertyDeviceOfflineAfterName              string = "DeviceOfflineAfter"
+    propertyDeviceDisableAfterName              string = "DeviceDisableAfter"
+    propertyDeviceDeleteAfterName               string = "DeviceDeleteAfter"
+
+    propertyDeviceOfflineAfterDefaultValue      string = "3600"
+    propertyDeviceDisableAfterDefaultValue      string = "604800"
+    propertyDeviceDeleteAfterDefaultValue       string = "0"
+)
+//
+//
+func (this *Application) SetLifecyclePolicy(policy pmdevs.Policy) {
+    this.lifecycleMutex.Lock()
+    defer this.lifecycleMutex.Unlock()
+    this.lifecyclePolicy = policy
+}
+//
+//
+func (this *Application) GetLifecyclePolicy() pmdevs.Policy {
+    this.lifecycleMutex.Lock()
+    defer this.lifecycleMutex.Unlock()
+    return this.lifecyclePolicy
+}
+//
+//
+func (this *Application) LoadManagedDevices() error {
+    var err error
+    pmlog.LogInfo("application trying to load managed devices")
+
+    schemaIds := []pgschema.UUID{ genericDriverSchemaId }
+    rule := this.GetProvisionRule()
+    if len(rule.SchemaId) > 0 && rule.SchemaId != genericDriverSchemaId {
+        schemaIds = append(schemaIds, rule.SchemaId)
+    }
+
+    for _, schemaId := range schemaIds {
+        objects, err := this.pg.ListObjectItems(pgcore.ObjectFilter{
+            SchemaId:       schemaId,
+            GroupName:      propertyGroupCredential,
+            PropertyName:   mqttPropertyBridgeObjectIdName,
+            Value:          this.objectId,
+            Properties:     []string{ mqttPropertyTopicBaseName, devicePropertyLastSeenName },
+        })
+        if err != nil {
+            return err
+        }
+        for i := range objects {
+            topicBase := objects[i].Properties[mqttPropertyTopicBaseName]
+            if len(topicBase) == 0 {
+                continue
+            }
+            var lastSeen int64
+            seenTime, err := time.Parse(time.RFC3339, objects[i].Properties[devicePropertyLastSeenName])
+            if err == nil {
+                lastSeen = seenTime.Unix()
+            }
+            this.devices.Register(topicBase, objects[i].Id, objects[i].Enabled, lastSeen)
+        }
+    }
+    pmlog.LogInfo("application loaded managed devices:", len(this.devices.List()))
+    return err
+}
+
+//
+//*********************************************************************//
+//
+func (this *Application) newSetDeviceLifecycleControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set device lifecycle policy"
+    control.Hidden          = false
+    control.RPC             = controlSetDeviceLifecycleName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetDeviceLifecycleControlArgOffline() *pgschema.Control {
+    control := this.newSetDeviceLifecycleControl()
+    control.Description     = "Offline after, sec"
+    control.Type            = pgschema.IntType
+    control.Argument        = "offlineAfter"
+    control.DefaultValue    = propertyDeviceOfflineAfterDefaultValue
+    return control
+}
+func (this *Application) newSetDeviceLifecycleControlArgDisable() *pgschema.Control {
+    control := this.newSetDeviceLifecycleControl()
+    control.Description     = "Disable after, sec"
+    control.Type            = pgschema.IntType
+    control.Argument        = "disableAfter"
+    control.DefaultValue    = propertyDeviceDisableAfterDefaultValue
+    return control
+}
+func (this *Application) newSetDeviceLifecycleControlArgDelete() *pgschema.Control {
+    control := this.newSetDeviceLifecycleControl()
+    control.Description     = "Delete after, sec"
+    control.Type            = pgschema.IntType
+    control.Argument        = "deleteAfter"
+    control.DefaultValue    = propertyDeviceDeleteAfterDefaultValue
+    return control
+}
+//
+//
+func (this *Application) newDeviceOfflineAfterProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDeviceOfflineAfterName
+    property.Type           = pgschema.IntType
+    property.Description    = "Mark device offline after inactivity"
+    property.GroupName      = propertyGroupLifecycle
+    property.DefaultValue   = propertyDeviceOfflineAfterDefaultValue
+    property.Units          = "sec"
+    return property
+}
+//
+//
+func (this *Application) newDeviceDisableAfterProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDeviceDisableAfterName
+    property.Type           = pgschema.IntType
+    property.Description    = "Disable device after inactivity"
+    property.GroupName      = propertyGroupLifecycle
+    property.DefaultValue   = propertyDeviceDisableAfterDefaultValue
+    property.Units          = "sec"
+    return property
+}
+//
+//
+func (this *Application) newDeviceDeleteAfterProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyDeviceDeleteAfterName
+    property.Type           = pgschema.IntType
+    property.Description    = "Delete device after inactivity, zero is never"
+    property.GroupName      = propertyGroupLifecycle
+    property.DefaultValue   = propertyDeviceDeleteAfterDefaultValue
+    property.Units          = "sec"
+    return property
+}
+//
+// TouchDevice() marks managed device as seen and re-enables it if disabled
+//
+func (this *Application) TouchDevice(ctx context.Context, topicBase string) {
+    device, prevState, exists := this.devices.Touch(topicBase)
+    if !exists || prevState != pmdevs.StateDisabled {
+        return
+    }
+    pmlog.LogInfo("application re-enable device", device.ObjectId, "with topic base", topicBase)
+    err := this.pg.EnableObjectCtx(ctx, device.ObjectId)
+    if err != nil {
+        pmlog.LogError("error enable device:", err)
+    }
+}
+//
+// CheckDeviceLifecycle() applies lifecycle policy to inactive managed devices
+//
+func (this *Application) CheckDeviceLifecycle() {
+    var err error
+    transitions := this.devices.Expire(this.GetLifecyclePolicy())
+    for _, transition := range transitions {
+        device := transition.Device
+        pmlog.LogInfo("device", device.ObjectId, "with topic base", device.TopicBase, "changed state to", transition.To)
+        switch transition.To {
+            case pmdevs.StateDisabled:
+                err = this.pg.DisableObject(device.ObjectId)
+                if err != nil {
+                    pmlog.LogError("error disable device:", err)
+                }
+            case pmdevs.StateDeleted:
+                err = this.pg.DeleteObject(device.ObjectId)
+                if err != nil {
+                    pmlog.LogError("error delete device:", err)
+                }
+                this.presence.Forget(device.TopicBase)
+        }
+    }
+}
+//
+//
+func (this *Application) SetDeviceLifecycleController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackLifecycleArguments(controlMessage.Params)
+
+    var policy pmdevs.Policy
+    policy.OfflineAfter, err = strconv.ParseInt(arguments.OfflineAfter, 10, 64)
+    if err != nil {
+        return err
+    }
+    policy.DisableAfter, err = strconv.ParseInt(arguments.DisableAfter, 10, 64)
+    if err != nil {
+        return err
+    }
+    policy.DeleteAfter, err = strconv.ParseInt(arguments.DeleteAfter, 10, 64)
+    if err != nil {
+        return err
+    }
+    this.SetLifecyclePolicy(policy)
+
+    pmlog.LogInfo("set device lifecycle:", arguments.GetJSON())
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyDeviceOfflineAfterName, arguments.OfflineAfter)
+    if err != nil {
+        return err
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyDeviceDisableAfterName, arguments.DisableAfter)
+    if err != nil {
+        return err
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyDeviceDeleteAfterName, arguments.DeleteAfter)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+//*********************************************************************//
+//
+type LifecycleArguments struct {
+    OfflineAfter    string      `json:"offlineAfter"`
+    DisableAfter    string      `json:"disableAfter"`
+    DeleteAfter     string      `json:"deleteAfter"`
+}
+
+func NewLifecycleArguments() *LifecycleArguments {
+    var arguments LifecycleArguments
+    return &arguments
+}
+
+func UnpackLifecycleArguments(jsonString string) (*LifecycleArguments, error) {
+    var err error
+    var arguments LifecycleArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *LifecycleArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *LifecycleArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetLifecycleProperties() reads offline, disable and delete periods of managed devices
+//
+func (this *Application) GetLifecycleProperties() error {
+    var err error
+
+    var policy pmdevs.Policy
+    policy.OfflineAfter, err = this.getInt64Property(propertyDeviceOfflineAfterName, propertyDeviceOfflineAfterDefaultValue)
+    if err != nil {
+        return err
+    }
+    policy.DisableAfter, err = this.getInt64Property(propertyDeviceDisableAfterName, propertyDeviceDisableAfterDefaultValue)
+    if err != nil {
+        return err
+    }
+    policy.DeleteAfter, err = this.getInt64Property(propertyDeviceDeleteAfterName, propertyDeviceDeleteAfterDefaultValue)
+    if err != nil {
+        return err
+    }
+    this.SetLifecyclePolicy(policy)
+    return err
+}
+//EOF
@@ -0,0 +1,227 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "context"
+    "fmt"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmtools"
+)
+//
+//
+const (
+    propertyBinaryAsMediaName           string = "BinaryPayloadAsMedia"
+    propertyBinaryAsMediaDefaultValue   string = "false"
+)
+//
+//
+const (
+    // Larger payloads are stored as media files, bytes
+    propertyMaxPayloadSizeName          string = "MaxPayloadSize"
+    propertyMaxPayloadSizeDefaultValue  string = "65536"
+)
+//
+//
+func (this *Application) SetBinaryAsMedia(state bool) {
+    this.binaryAsMediaMutex.Lock()
+    defer this.binaryAsMediaMutex.Unlock()
+    this.binaryAsMedia = state
+}
+//
+//
+func (this *Application) GetBinaryAsMedia() bool {
+    this.binaryAsMediaMutex.Lock()
+    defer this.binaryAsMediaMutex.Unlock()
+    return this.binaryAsMedia
+}
+//
+//
+func (this *Application) newSendFileControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Send media file to topic"
+    control.Hidden          = false
+    control.RPC             = controlSendFileName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSendFileControlArgMediaId() *pgschema.Control {
+    control := this.newSendFileControl()
+    control.Description     = "Media object id"
+    control.Type            = pgschema.StringType
+    control.Argument        = "mediaId"
+    return control
+}
+func (this *Application) newSendFileControlArgTopicName() *pgschema.Control {
+    control := this.newSendFileControl()
+    control.Description     = "Topic name"
+    control.Type            = pgschema.StringType
+    control.Argument        = "topicName"
+    return control
+}
+func (this *Application) newSendFileControlArgChunkSize() *pgschema.Control {
+    control := this.newSendFileControl()
+    control.Description     = "Chunk size, zero sends whole file"
+    control.Type            = pgschema.IntType
+    control.Argument        = "chunkSize"
+    control.DefaultValue    = "0"
+    return control
+}
+//
+//
+func (this *Application) newBinaryAsMediaProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyBinaryAsMediaName
+    property.Type           = pgschema.BoolType
+    property.Description    = "Store binary payloads as media"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = propertyBinaryAsMediaDefaultValue
+    return property
+}
+//
+//
+func (this *Application) SendFileController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+
+    arguments, err := UnpackSendFileArguments(controlMessage.Params)
+    if err != nil {
+        return err
+    }
+    chunkSize := 0
+    if len(arguments.ChunkSize) > 0 {
+        chunkSize, err = strconv.Atoi(arguments.ChunkSize)
+        if err != nil {
+            return err
+        }
+    }
+
+    data, err := this.pg.DownloadMediaFile(arguments.MediaId)
+    if err != nil {
+        return err
+    }
+    chunks := pmtools.SplitChunks(data, chunkSize)
+    pmlog.LogInfo("send media file", arguments.MediaId, "size", len(data), "to topic", arguments.TopicName, "in", len(chunks), "chunks")
+
+    for _, chunk := range chunks {
+        err = this.tr.PublishBytes(arguments.TopicName, chunk)
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+//
+//*********************************************************************//
+//
+type SendFileArguments struct {
+    MediaId     string      `json:"mediaId"`
+    TopicName   string      `json:"topicName"`
+    ChunkSize   string      `json:"chunkSize"`
+}
+
+func NewSendFileArguments() *SendFileArguments {
+    var arguments SendFileArguments
+    return &arguments
+}
+
+func UnpackSendFileArguments(jsonString string) (*SendFileArguments, error) {
+    var err error
+    var arguments SendFileArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *SendFileArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *SendFileArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+
+//
+//
+func (this *Application) SetMaxPayloadSize(size int64) {
+    this.maxPayloadSizeMutex.Lock()
+    defer this.maxPayloadSizeMutex.Unlock()
+    this.maxPayloadSize = size
+}
+//
+//
+func (this *Application) GetMaxPayloadSize() int64 {
+    this.maxPayloadSizeMutex.Lock()
+    defer this.maxPayloadSizeMutex.Unlock()
+    return this.maxPayloadSize
+}
+//
+//
+func (this *Application) newMaxPayloadSizeProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyMaxPayloadSizeName
+    property.Type           = pgschema.IntType
+    property.Description    = "Max inline payload size, larger stored as media"
+    property.GroupName      = propertyGroupPayload
+    property.DefaultValue   = propertyMaxPayloadSizeDefaultValue
+    property.Units          = "bytes"
+    return property
+}
+//
+// StorePayloadMedia() replaces payload with reference to media file
+//
+func (this *Application) StorePayloadMedia(ctx context.Context, argument *pgcore.TopicArguments) error {
+    var err error
+    name := fmt.Sprintf("%s %s", argument.TopicName, pmtools.GetIsoTimestamp())
+    mediaId, err := this.pg.StoreMediaFileCtx(ctx, name, this.config.Media.SchemaId, argument.Payload)
+    if err != nil {
+        return err
+    }
+    pmlog.LogInfo("payload of topic", argument.TopicName, "stored as media file", mediaId)
+    argument.MediaId    = mediaId
+    argument.Size       = len(argument.Payload)
+    argument.Payload    = nil
+    return err
+}
+//
+// GetPayloadSizeProperties() reads maximum payload size of application
+//
+func (this *Application) GetPayloadSizeProperties() error {
+    var err error
+
+    maxPayloadSize, err := this.getInt64Property(propertyMaxPayloadSizeName, propertyMaxPayloadSizeDefaultValue)
+    if err != nil {
+        return err
+    }
+    this.SetMaxPayloadSize(maxPayloadSize)
+    return err
+}
+//
+// GetMediaProperties() reads binary as media flag of application
+//
+func (this *Application) GetMediaProperties() error {
+    var err error
+
+    binaryAsMediaStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyBinaryAsMediaName)
+    if err != nil {
+        return err
+    }
+    if len(binaryAsMediaStr) == 0 {
+        binaryAsMediaStr = propertyBinaryAsMediaDefaultValue
+    }
+    binaryAsMediaBool, err := strconv.ParseBool(binaryAsMediaStr)
+    if err != nil {
+        return err
+    }
+    this.SetBinaryAsMedia(binaryAsMediaBool)
+    return err
+}
+//EOF
@@ -0,0 +1,271 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+)
+//
+// DataDir() returns configured directory of state files or
+// executable directory
+//
+func (this *Application) DataDir() (string, error) {
+    if len(this.config.DataDir) > 0 {
+        return this.config.DataDir, nil
+    }
+    exePath, err := os.Executable()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Dir(exePath), err
+}
+//
+// appSchemaRenames and deviceSchemaRenames list renamed properties of
+// bridge and generic device schemas, values of old properties are copied
+// to new ones on schema upgrade
+//
+var appSchemaRenames    = []pgschema.Rename{}
+var deviceSchemaRenames = []pgschema.Rename{
+    { From: "TopicBase", To: mqttPropertyTopicBaseName },   // device schema 1.29
+}
+
+type ownedSchema struct {
+    schema      *pgschema.Schema
+    renames     []pgschema.Rename
+    install     bool    // import even if not deployed
+}
+//
+// OwnedSchemas() returns schemas upgraded by bridge, application schema
+// first. Device schema is upgraded only when already deployed.
+//
+func (this *Application) OwnedSchemas() ([]ownedSchema, error) {
+    var err error
+    deviceSchema, err := this.DefineDeviceSchema()
+    if err != nil {
+        return nil, err
+    }
+    owned := []ownedSchema{
+        { schema: this.schema,  renames: appSchemaRenames,     install: true },
+        { schema: deviceSchema, renames: deviceSchemaRenames },
+    }
+    return owned, err
+}
+//
+// SetupAppSchema() imports bridge schemas if deployed versions differ.
+// Values of renamed properties are written to migration file before import
+// and kept there until MigrateAppSchema() writes them to new properties,
+// so migration is repeated on next start if bridge stops in between.
+//
+func (this *Application) SetupAppSchema() error {
+    var err error
+    this.schemaChanges = nil
+    this.schemaMigration, err = this.ReadMigration()
+    if err != nil {
+        return err
+    }
+    if len(this.schemaMigration) > 0 {
+        pmlog.LogWarning("found unfinished schema migration of", len(this.schemaMigration), "objects")
+    }
+    owned, err := this.OwnedSchemas()
+    if err != nil {
+        return err
+    }
+
+    imports := make([]*pgschema.Schema, 0)
+    for _, item := range owned {
+        schemaId := item.schema.Metadata.Id
+        version := item.schema.Metadata.MVersion
+        deployedVersion, exists, err := this.pg.GetSchemaVersion(schemaId)
+        if err != nil {
+            return err
+        }
+        if exists && deployedVersion == version {
+            pmlog.LogInfo("schema", schemaId, "is up to date, version", deployedVersion)
+            continue
+        }
+        if !exists && !item.install {
+            continue
+        }
+        if exists {
+            changes, err := this.DiffSchema(item.schema, item.renames)
+            if err != nil {
+                pmlog.LogWarning("unable compare schema", schemaId, "error:", err)
+            }
+            if changes != nil {
+                pmlog.LogInfo("schema", schemaId, "changes", changes.String())
+                err = this.CollectMigration(schemaId, changes.RenamedProperties)
+                if err != nil {
+                    return err
+                }
+                this.schemaChanges = append(this.schemaChanges, changes)
+            }
+        }
+        pmlog.LogInfo("schema", schemaId, "deployed version", deployedVersion, "new version", version)
+        imports = append(imports, item.schema)
+    }
+
+    err = this.WriteMigration()
+    if err != nil {
+        return err
+    }
+    for _, schema := range imports {
+        pmlog.LogInfo("trying to import schema", schema.Metadata.Id)
+        schemaId, err := this.pg.ImportSchema(schema.GetJSON())
+        if err != nil {
+            return err
+        }
+        pmlog.LogInfo("done import schema", schemaId)
+    }
+    return err
+}
+//
+// DiffSchema() compares deployed schema with own one
+//
+func (this *Application) DiffSchema(schema *pgschema.Schema, renames []pgschema.Rename) (*pgschema.Changes, error) {
+    var err error
+    deployedJson, err := this.pg.ExportSchema(schema.Metadata.Id)
+    if err != nil {
+        return nil, err
+    }
+    deployed := pgschema.NewSchema()
+    err = json.Unmarshal([]byte(deployedJson), deployed)
+    if err != nil {
+        return nil, err
+    }
+    return pgschema.Diff(deployed, schema, renames), err
+}
+//
+// CollectMigration() reads values of renamed properties before import
+//
+func (this *Application) CollectMigration(schemaId pgschema.UUID, renames []pgschema.Rename) error {
+    var err error
+    if len(renames) == 0 {
+        return err
+    }
+    filter := pgcore.ObjectFilter{
+        SchemaId:   schemaId,
+        Properties: make([]string, 0),
+    }
+    for _, rename := range renames {
+        filter.Properties = append(filter.Properties, rename.From)
+    }
+    objects, err := this.pg.ListObjectItems(filter)
+    if err != nil {
+        return err
+    }
+    for i := range objects {
+        values := this.schemaMigration[objects[i].Id]
+        if values == nil {
+            values = make(map[string]string)
+        }
+        for _, rename := range renames {
+            value := objects[i].Properties[rename.From]
+            if len(value) == 0 {
+                continue
+            }
+            values[rename.To] = value
+        }
+        if len(values) > 0 {
+            this.schemaMigration[objects[i].Id] = values
+        }
+    }
+    return err
+}
+//
+// ReadMigration() reads unfinished migration, missing file is not error
+//
+func (this *Application) ReadMigration() (map[pgschema.UUID]map[string]string, error) {
+    var err error
+    migration := make(map[pgschema.UUID]map[string]string)
+    if len(this.migrationFile) == 0 {
+        return migration, err
+    }
+    data, err := ioutil.ReadFile(this.migrationFile)
+    if os.IsNotExist(err) {
+        return migration, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    err = json.Unmarshal(data, &migration)
+    if err != nil {
+        return nil, fmt.Errorf("migration file %s: %s", this.migrationFile, err)
+    }
+    return migration, err
+}
+//
+// WriteMigration() stores pending migration, empty one removes file
+//
+func (this *Application) WriteMigration() error {
+    var err error
+    if len(this.migrationFile) == 0 {
+        return err
+    }
+    if len(this.schemaMigration) == 0 {
+        err = os.Remove(this.migrationFile)
+        if os.IsNotExist(err) {
+            err = nil
+        }
+        return err
+    }
+    data, err := json.Marshal(this.schemaMigration)
+    if err != nil {
+        return err
+    }
+    tmpName := this.migrationFile + "~"
+    err = ioutil.WriteFile(tmpName, data, 0640)
+    if err != nil {
+        return err
+    }
+    return os.Rename(tmpName, this.migrationFile)
+}
+//
+// MigrateAppSchema() writes kept values to renamed properties and
+// reports schema changes to bridge message. Values failed to write
+// stay in migration file for next start.
+//
+func (this *Application) MigrateAppSchema() {
+    var err error
+    for objectId, values := range this.schemaMigration {
+        for name, value := range values {
+            _, err = this.pg.UpdateObjectPropertyByName(objectId, name, value)
+            if err != nil {
+                pmlog.LogError("error migrate property", name, "of object", objectId, "error:", err)
+                continue
+            }
+            delete(values, name)
+            pmlog.LogInfo("migrated property", name, "of object", objectId)
+        }
+        if len(values) == 0 {
+            delete(this.schemaMigration, objectId)
+        }
+    }
+    err = this.WriteMigration()
+    if err != nil {
+        pmlog.LogError("error write migration file:", err)
+    }
+
+    if len(this.schemaChanges) == 0 {
+        return
+    }
+    message := "schema upgraded"
+    for _, changes := range this.schemaChanges {
+        message += " " + changes.String()
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyMessageName, message)
+    if err != nil {
+        pmlog.LogError("error update message property:", err)
+    }
+    this.schemaChanges = nil
+}
+//EOF
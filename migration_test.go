@@ -0,0 +1,228 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+
+    "app/pgschema"
+)
+//
+// TestMigrationFile checks pending schema migration survives restart
+// and file is removed when migration is done
+//
+func TestMigrationFile(t *testing.T) {
+    app := NewApplication()
+    app.migrationFile = filepath.Join(t.TempDir(), "pmbri.migration.json")
+    migration, err := app.ReadMigration()
+    if err != nil || len(migration) != 0 {
+        t.Fatal("missing migration file not empty:", migration, err)
+    }
+
+    app.schemaMigration = map[pgschema.UUID]map[string]string{
+        "object": { "BaseTopic": "dev/a" },
+    }
+    err = app.WriteMigration()
+    if err != nil {
+        t.Fatal(err)
+    }
+    restarted := NewApplication()
+    restarted.migrationFile = app.migrationFile
+    migration, err = restarted.ReadMigration()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if migration["object"]["BaseTopic"] != "dev/a" {
+        t.Error("migration not restored:", migration)
+    }
+
+    app.schemaMigration = nil
+    err = app.WriteMigration()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = os.Stat(app.migrationFile); !os.IsNotExist(err) {
+        t.Error("done migration file not removed:", err)
+    }
+}
+
+type testCore struct {
+    schemas     map[pgschema.UUID]*pgschema.Schema
+    objects     map[pgschema.UUID]map[string]string     // object id, property name, value
+    schemaIds   map[pgschema.UUID]pgschema.UUID         // object id, schema id
+    imports     int
+    mutex       sync.Mutex
+}
+//
+// testPropertyId() makes uuid length id of object property
+//
+func testPropertyId(objectId pgschema.UUID, name string) string {
+    return fmt.Sprintf("%-36s", string(objectId) + "/" + name)
+}
+//
+// serve() answers core requests used by schema setup and migration,
+// import drops values of properties missing in new schema
+//
+func (this *testCore) serve(w http.ResponseWriter, r *http.Request) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    var gqReq struct {
+        Query       string                      `json:"query"`
+        Variables   map[string]json.RawMessage  `json:"variables"`
+    }
+    body, _ := ioutil.ReadAll(r.Body)
+    json.Unmarshal(body, &gqReq)
+    variable := func(name string) string {
+        var value string
+        json.Unmarshal(gqReq.Variables[name], &value)
+        return value
+    }
+    respond := func(data interface{}) {
+        json.NewEncoder(w).Encode(map[string]interface{}{ "data": data })
+    }
+    switch {
+        case strings.Contains(gqReq.Query, "schemata"):
+            schemas := make([]map[string]string, 0)
+            for id, schema := range this.schemas {
+                schemas = append(schemas, map[string]string{ "id": id, "mVersion": schema.Metadata.MVersion })
+            }
+            respond(map[string]interface{}{ "schemata": schemas })
+        case strings.Contains(gqReq.Query, "exportSchema"):
+            respond(map[string]string{ "exportSchema": this.schemas[variable("schemaId")].GetJSON() })
+        case strings.Contains(gqReq.Query, "importSchema"):
+            schema := pgschema.NewSchema()
+            json.Unmarshal(gqReq.Variables["jsonSchema"], schema)
+            this.schemas[schema.Metadata.Id] = schema
+            this.imports += 1
+            for objectId, values := range this.objects {
+                if this.schemaIds[objectId] != schema.Metadata.Id {
+                    continue
+                }
+                kept := make(map[string]string)
+                for _, property := range schema.Properties {
+                    kept[property.Property] = values[property.Property]
+                }
+                this.objects[objectId] = kept
+            }
+            respond(map[string]interface{}{ "importSchema": map[string]string{ "uuid": schema.Metadata.Id } })
+        case strings.Contains(gqReq.Query, "objectsConnection"):
+            nodes := make([]interface{}, 0)
+            for objectId, values := range this.objects {
+                properties := make([]map[string]string, 0)
+                for name, value := range values {
+                    properties = append(properties, map[string]string{ "property": name, "value": value })
+                }
+                nodes = append(nodes, map[string]interface{}{
+                    "id":               objectId,
+                    "objectProperties": map[string]interface{}{ "nodes": properties },
+                })
+            }
+            respond(map[string]interface{}{ "objectsConnection": map[string]interface{}{
+                "totalCount":   len(nodes),
+                "pageInfo":     map[string]interface{}{ "hasNextPage": false },
+                "nodes":        nodes,
+            }})
+        case strings.Contains(gqReq.Query, "updateObjectProperty"):
+            parts := strings.SplitN(strings.TrimSpace(variable("id")), "/", 2)
+            if values, exists := this.objects[parts[0]]; exists {
+                values[parts[1]] = variable("value")
+            }
+            respond(map[string]interface{}{ "updateObjectProperty": map[string]string{
+                "clientMutationId": variable("clientMutationId"),
+            }})
+        case strings.Contains(gqReq.Query, "objectProperties"):
+            objectId := variable("objectId")
+            name := variable("property")
+            properties := make([]map[string]string, 0)
+            if _, exists := this.objects[objectId][name]; exists {
+                properties = append(properties, map[string]string{ "id": testPropertyId(objectId, name) })
+            }
+            respond(map[string]interface{}{ "objectProperties": properties })
+        default:
+            respond(map[string]interface{}{})
+    }
+}
+//
+// TestSchemaMigration upgrades pre-rename device schema, stops bridge
+// after import and checks next start migrates kept values
+//
+func TestSchemaMigration(t *testing.T) {
+    app := NewApplication()
+    app.migrationFile = filepath.Join(t.TempDir(), "pmbri.migration.json")
+    err := app.DefineAppSchema()
+    if err != nil {
+        t.Fatal(err)
+    }
+    deviceSchema, err := app.DefineDeviceSchema()
+    if err != nil {
+        t.Fatal(err)
+    }
+    deployed := pgschema.NewSchema()
+    err = json.Unmarshal([]byte(strings.Replace(deviceSchema.GetJSON(),
+                    `"` + mqttPropertyTopicBaseName + `"`, `"TopicBase"`, -1)), deployed)
+    if err != nil {
+        t.Fatal(err)
+    }
+    deployed.Metadata.MVersion = "1.28"
+
+    core := &testCore{
+        schemas: map[pgschema.UUID]*pgschema.Schema{
+            app.schema.Metadata.Id:     app.schema,
+            genericDriverSchemaId:      deployed,
+        },
+        objects: map[pgschema.UUID]map[string]string{
+            "device":   { "TopicBase": "dev/a", "BRIDGE": "bridge" },
+            "bridge":   { propertyMessageName: "" },
+        },
+        schemaIds: map[pgschema.UUID]pgschema.UUID{
+            "device":   genericDriverSchemaId,
+            "bridge":   app.schema.Metadata.Id,
+        },
+    }
+    server := httptest.NewServer(http.HandlerFunc(core.serve))
+    defer server.Close()
+    err = app.pg.Setup(server.URL + "/graphql", "user", "password", 1, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = app.SetupAppSchema()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if core.imports != 1 || core.objects["device"][mqttPropertyTopicBaseName] != "" {
+        t.Fatal("device schema not imported:", core.imports, core.objects["device"])
+    }
+
+    restarted := NewApplication()
+    restarted.migrationFile = app.migrationFile
+    restarted.schema = app.schema
+    restarted.objectId = "bridge"
+    err = restarted.pg.Setup(server.URL + "/graphql", "user", "password", 1, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = restarted.SetupAppSchema()
+    if err != nil {
+        t.Fatal(err)
+    }
+    restarted.MigrateAppSchema()
+    if core.objects["device"][mqttPropertyTopicBaseName] != "dev/a" || core.objects["device"]["BRIDGE"] != "bridge" {
+        t.Error("renamed property not migrated:", core.objects["device"])
+    }
+    if _, err = os.Stat(app.migrationFile); !os.IsNotExist(err) {
+        t.Error("migration file not removed:", err)
+    }
+}
+//EOF
@@ -5,6 +5,7 @@
 package mqtrans
 
 import (
+    "crypto/tls"
     "errors"
     "time"
 
@@ -30,6 +31,7 @@ type Transport struct {
     mc          mqtt.Client
     pg          *pgcore.Pixcore
     clientId    string
+    tlsConfig   *tls.Config
 }
 
 func NewTransport() *Transport {
@@ -39,6 +41,13 @@ func NewTransport() *Transport {
     }
 }
 
+//
+// SetTLSConfig() sets tls config for ssl:// and wss:// brokers
+//
+func (this *Transport) SetTLSConfig(config *tls.Config) {
+    this.tlsConfig = config
+}
+
 func (this *Transport) Bind(url string, username string, password string) error {
     var err error
 
@@ -49,6 +58,9 @@ func (this *Transport) Bind(url string, username string, password string) error
     opts.SetUsername(username)
     opts.SetPassword(password)
     opts.SetClientID(this.clientId)
+    if this.tlsConfig != nil {
+        opts.SetTLSConfig(this.tlsConfig)
+    }
 
     //opts.SetOrderMatters(true)
     opts.SetAutoReconnect(false)
@@ -95,6 +107,20 @@ func (this *Transport) Publish(topic string, message string) error {
     return err
 }
 
+func (this *Transport) PublishBytes(topic string, message []byte) error {
+    var err error
+    if this.mc == nil {
+        return errors.New("mqtt transport yet not exist")
+    }
+    token := this.mc.Publish(topic, QosL1, false, message)
+    for !token.WaitTimeout(waitTimeout * time.Second) {}
+    err = token.Error()
+    if err != nil {
+        return err
+    }
+    return err
+}
+
 func (this *Transport) Subscribe(topic string, handler Handler) error {
     var err error
     if this.mc == nil {
@@ -110,6 +136,48 @@ func (this *Transport) Subscribe(topic string, handler Handler) error {
     return err
 }
 
+func (this *Transport) SubscribeQos(topic string, qos byte, handler Handler) error {
+    var err error
+    if this.mc == nil {
+        return errors.New("mqtt transport yet not exist")
+    }
+    token := this.mc.Subscribe(topic, qos, handler)
+    for !token.WaitTimeout(waitTimeout * time.Second) {}
+    err = token.Error()
+    if err != nil {
+        return err
+    }
+    return err
+}
+
+func (this *Transport) Unsubscribe(topic string) error {
+    var err error
+    if this.mc == nil {
+        return errors.New("mqtt transport yet not exist")
+    }
+    token := this.mc.Unsubscribe(topic)
+    for !token.WaitTimeout(waitTimeout * time.Second) {}
+    err = token.Error()
+    if err != nil {
+        return err
+    }
+    return err
+}
+func (this *Transport) PublishQos(topic string, qos byte, message []byte) error {
+    var err error
+    if this.mc == nil {
+        return errors.New("mqtt transport yet not exist")
+    }
+    token := this.mc.Publish(topic, qos, false, message)
+    for !token.WaitTimeout(waitTimeout * time.Second) {}
+    err = token.Error()
+    if err != nil {
+        return err
+    }
+    return err
+}
+
+
 func (this *Transport) IsConnected() bool {
     if this.mc == nil {
         return false
@@ -128,4 +196,14 @@ func (this *Transport) Disconnect() error {
     }
     return err
 }
+//
+// Close() disconnects after in-flight messages done or quiesce expired
+//
+func (this *Transport) Close(quiesce time.Duration) error {
+    var err error
+    if this.mc != nil && this.mc.IsConnected() {
+        this.mc.Disconnect(uint(quiesce / time.Millisecond))
+    }
+    return err
+}
 //EOF
@@ -6,8 +6,12 @@
 package pgcore
 
 import (
+    "encoding/base64"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "regexp"
+    "unicode/utf8"
 )
 
 const (
@@ -18,6 +22,138 @@ const (
     ArgumentsTypeBool     string = "bool"
 )
 
+//*********************************************************************//
+//
+// Payload encodings
+//
+const (
+    EncodingCompat      string = ""         // legacy heuristic, base64 on uplink
+    EncodingUTF8        string = "utf8"
+    EncodingBase64      string = "base64"
+    EncodingHex         string = "hex"
+
+    EncodingCompatName  string = "compat"
+)
+
+func CheckEncoding(encoding string) error {
+    switch encoding {
+        case EncodingCompat, EncodingUTF8, EncodingBase64, EncodingHex:
+            return nil
+    }
+    return errors.New("unknown payload encoding " + encoding)
+}
+//
+// ParseEncoding() maps property value to encoding, "compat" is legacy mode
+//
+func ParseEncoding(value string) (string, error) {
+    if value == EncodingCompatName {
+        return EncodingCompat, nil
+    }
+    return value, CheckEncoding(value)
+}
+//
+// encodePayload() returns json value and effective encoding,
+// utf8 falls back to base64 for binary payload
+//
+func encodePayload(payload []byte, encoding string) (interface{}, string, error) {
+    switch encoding {
+        case EncodingCompat:
+            return []byte(payload), encoding, nil
+        case EncodingUTF8:
+            if !utf8.Valid(payload) {
+                return base64.StdEncoding.EncodeToString(payload), EncodingBase64, nil
+            }
+            return string(payload), encoding, nil
+        case EncodingBase64:
+            return base64.StdEncoding.EncodeToString(payload), encoding, nil
+        case EncodingHex:
+            return hex.EncodeToString(payload), encoding, nil
+    }
+    return nil, encoding, CheckEncoding(encoding)
+}
+
+func decodePayload(data json.RawMessage, encoding string) ([]byte, error) {
+    var err error
+    var result []byte
+    if len(data) == 0 || string(data) == "null" {
+        return result, err
+    }
+    if encoding == EncodingCompat {
+        var payload Bytes
+        err = payload.UnmarshalJSON(data)
+        return []byte(payload), err
+    }
+    var payload string
+    err = json.Unmarshal(data, &payload)
+    if err != nil {
+        return result, err
+    }
+    switch encoding {
+        case EncodingUTF8:
+            result = []byte(payload)
+        case EncodingBase64:
+            result, err = base64.StdEncoding.DecodeString(payload)
+        case EncodingHex:
+            result, err = hex.DecodeString(payload)
+        default:
+            err = CheckEncoding(encoding)
+    }
+    return result, err
+}
+
+//
+// DecodePayload() converts text payload, e.g. rendered template, to bytes
+//
+func DecodePayload(payload string, encoding string) ([]byte, error) {
+    if encoding == EncodingCompat {
+        return []byte(payload), nil
+    }
+    jsonBytes, _ := json.Marshal(payload)
+    return decodePayload(jsonBytes, encoding)
+}
+
+type encodedPayload struct {
+    TopicName   string          `json:"topicName"`
+    Payload     interface{}     `json:"payload"`
+    Encoding    string          `json:"encoding,omitempty"`
+    MediaId     UUID            `json:"mediaId,omitempty"`
+    Size        int             `json:"size,omitempty"`
+    Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type rawPayload struct {
+    TopicName   string          `json:"topicName"`
+    Payload     json.RawMessage `json:"payload"`
+    Encoding    string          `json:"encoding"`
+    MediaId     UUID            `json:"mediaId"`
+    Size        int             `json:"size"`
+    Tags        map[string]string `json:"tags"`
+}
+
+func marshalPayload(topicName string, payload []byte, encoding string, mediaId UUID, size int, tags map[string]string) ([]byte, error) {
+    var err error
+    var result encodedPayload
+    result.TopicName    = topicName
+    result.MediaId      = mediaId
+    result.Size         = size
+    result.Tags         = tags
+    result.Payload, result.Encoding, err = encodePayload(payload, encoding)
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(result)
+}
+
+func unmarshalPayload(data []byte) (rawPayload, []byte, error) {
+    var raw rawPayload
+    err := json.Unmarshal(data, &raw)
+    if err != nil {
+        return raw, nil, err
+    }
+    payload, err := decodePayload(raw.Payload, raw.Encoding)
+    return raw, payload, err
+}
+
 //*********************************************************************//
 
 type Bytes []byte
@@ -106,6 +242,19 @@ type PublishArguments struct {
     //TopicArguments
     TopicName   string      `json:"topicName"`
     Payload     Bytes      `json:"payload"`
+    Encoding    string      `json:"encoding,omitempty"`
+}
+
+func (this PublishArguments) MarshalJSON() ([]byte, error) {
+    return marshalPayload(this.TopicName, this.Payload, this.Encoding, "", 0, nil)
+}
+
+func (this *PublishArguments) UnmarshalJSON(data []byte) error {
+    raw, payload, err := unmarshalPayload(data)
+    this.TopicName  = raw.TopicName
+    this.Encoding   = raw.Encoding
+    this.Payload    = payload
+    return err
 }
 
 func NewPublishArguments() *PublishArguments {
@@ -136,6 +285,25 @@ type TopicArguments struct {
     BasicArguments
     TopicName   string      `json:"topicName"`
     Payload     Bytes      `json:"payload"`
+    Encoding    string      `json:"encoding,omitempty"`
+    MediaId     UUID        `json:"mediaId,omitempty"`     // payload stored as media file
+    Size        int         `json:"size,omitempty"`
+    Tags        map[string]string `json:"tags,omitempty"`  // set by topic rules
+}
+
+func (this TopicArguments) MarshalJSON() ([]byte, error) {
+    return marshalPayload(this.TopicName, this.Payload, this.Encoding, this.MediaId, this.Size, this.Tags)
+}
+
+func (this *TopicArguments) UnmarshalJSON(data []byte) error {
+    raw, payload, err := unmarshalPayload(data)
+    this.TopicName  = raw.TopicName
+    this.Encoding   = raw.Encoding
+    this.MediaId    = raw.MediaId
+    this.Size       = raw.Size
+    this.Tags       = raw.Tags
+    this.Payload    = payload
+    return err
 }
 
 func NewTopicArguments() *TopicArguments {
@@ -0,0 +1,54 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "bytes"
+    "encoding/json"
+    "testing"
+)
+
+
+func TestTopicArgumentsEncoding(t *testing.T) {
+    binary := []byte{ 0x00, 0xff, 0x3a, 0x10 }
+
+    for _, encoding := range []string{ EncodingCompat, EncodingBase64, EncodingHex, EncodingUTF8 } {
+        argument := NewTopicArguments()
+        argument.TopicName  = "gw/a1/status"
+        argument.Payload    = binary
+        argument.Encoding   = encoding
+
+        result, err := UnpackTopicArguments(argument.GetJSON())
+        if err != nil {
+            t.Fatal(encoding, err)
+        }
+        if !bytes.Equal(result.Payload, binary) {
+            t.Error("encoding", encoding, "damaged payload", result.Payload)
+        }
+    }
+}
+
+func TestPublishArgumentsCompat(t *testing.T) {
+    arguments, err := UnpackPublishArguments(`{"topicName": "a/b", "payload": "{\"cmd\": 1}"}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(arguments.Payload) != `{"cmd": 1}` {
+        t.Error("wrong compat payload", string(arguments.Payload))
+    }
+
+    arguments, err = UnpackPublishArguments(`{"topicName": "a/b", "payload": "00ff", "encoding": "hex"}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(arguments.Payload, []byte{ 0x00, 0xff }) {
+        t.Error("wrong hex payload", arguments.Payload)
+    }
+
+    jsonBytes, _ := json.Marshal(arguments)
+    if !bytes.Contains(jsonBytes, []byte(`"encoding":"hex"`)) {
+        t.Error("encoding marker lost", string(jsonBytes))
+    }
+}
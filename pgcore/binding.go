@@ -5,13 +5,10 @@
 package pgcore
 
 import (
-    "bytes"
     "fmt"
     "context"
     "encoding/json"
     "errors"
-    "io/ioutil"
-    "net/http"
     "net/url"
     "time"
     "sync"
@@ -35,6 +32,7 @@ type UUID = string
 
 type Pixcore struct {
     gqURL                   *url.URL
+    mediaURL                *url.URL
 
     authToken               string
     jwtToken                string
@@ -51,16 +49,38 @@ type Pixcore struct {
     pixcoreCancel           context.CancelFunc
 
     profileTags             []string
+
+    verifyKeys              *pgjwt.KeySet
+    tokenListeners          []TokenListener
+    subscrs                 map[*subscription]bool
+    tokenMutex              sync.Mutex
+
+    policy                  RequestPolicy
+    policyMutex             sync.RWMutex
+    breaker                 *Breaker
 }
+//
+// TokenListener is called with new jwt after bind or refresh
+//
+type TokenListener = func(jwtToken string)
+//
+// ErrTokenRejected reports refresh token not accepted by core,
+// new Bind() is required
+//
+var ErrTokenRejected = errors.New("refresh token rejected")
 
 func New(parentCtx context.Context) *Pixcore {
     pixcoreCtx, pixcoreCancel := context.WithCancel(parentCtx)
     profileTags :=   make([]string, 0)
+    policy := DefaultRequestPolicy()
     return &Pixcore{
         pixcoreCtx:       pixcoreCtx,
         pixcoreCancel:    pixcoreCancel,
         jwtTTL:             defaultTTL,
         profileTags:        profileTags,
+        subscrs:            make(map[*subscription]bool),
+        policy:             policy,
+        breaker:            NewBreaker(policy.BreakerThreshold, policy.BreakerCooldown),
     }
 }
 
@@ -108,6 +128,12 @@ func (this *Pixcore) GetJWTExpire() int64 {
     defer this.jwtExpireMutex.RUnlock()
     return this.jwtExpire
 }
+//
+// IsBound() reports valid not expired token
+//
+func (this *Pixcore) IsBound() bool {
+    return len(this.GetJWTToken()) > 0 && this.GetJWTExpire() > time.Now().Unix()
+}
 func (this *Pixcore) SetJWTExpire(expire int64) {
     this.jwtExpireMutex.Lock()
     defer this.jwtExpireMutex.Unlock()
@@ -115,6 +141,51 @@ func (this *Pixcore) SetJWTExpire(expire int64) {
 }
 
 
+//
+// SetVerifyKeys() enables jwt signature verification
+//
+func (this *Pixcore) SetVerifyKeys(keys *pgjwt.KeySet) {
+    this.tokenMutex.Lock()
+    defer this.tokenMutex.Unlock()
+    this.verifyKeys = keys
+}
+//
+// OnTokenChange() adds listener of jwt changes
+//
+func (this *Pixcore) OnTokenChange(listener TokenListener) {
+    this.tokenMutex.Lock()
+    defer this.tokenMutex.Unlock()
+    this.tokenListeners = append(this.tokenListeners, listener)
+}
+//
+// storeJWToken() verifies and stores jwt, renews active subscriptions
+// and notifies listeners
+//
+func (this *Pixcore) storeJWToken(jwtToken string) (*pgjwt.JWT, error) {
+    var err error
+    var jwt *pgjwt.JWT
+    this.tokenMutex.Lock()
+    keys := this.verifyKeys
+    listeners := append([]TokenListener{}, this.tokenListeners...)
+    this.tokenMutex.Unlock()
+
+    if keys != nil {
+        jwt, err = pgjwt.Verify(jwtToken, keys)
+    } else {
+        jwt, err = pgjwt.Parse(jwtToken)
+    }
+    if err != nil {
+        return jwt, err
+    }
+    this.SetJWTToken(jwtToken)
+    this.SetJWTExpire(jwt.Expire())
+
+    this.RenewSubscriptions()
+    for _, listener := range listeners {
+        listener(jwtToken)
+    }
+    return jwt, err
+}
 //
 //
 func (this *Pixcore) GetPureURL() (string, error) {
@@ -162,51 +233,48 @@ func (this *Pixcore) Setup(gqRef string, name string, password string, ttl int,
 
 
 func (this *Pixcore) Bind() error {
+    return this.BindCtx(this.pixcoreCtx)
+}
+
+func (this *Pixcore) BindCtx(ctx context.Context) error {
     var err error
 
-    authToken, tokenId, err := this.getAuthToken()
+    authToken, tokenId, err := this.getAuthToken(ctx)
     if err != nil {
         return err
     }
     this.SetAuthToken(authToken)
     this.SetTokenId(tokenId)
 
-    pmlog.LogDebug("pixcore auth token:", authToken, tokenId)
+    pmlog.LogDebug("pixcore got auth token, id:", tokenId)
 
-    jwtToken, err := this.getJwtToken(this.GetAuthToken(), this.jwtTTL, this.profileTags)
+    jwtToken, err := this.getJwtToken(ctx, this.GetAuthToken(), this.jwtTTL, this.profileTags)
     if err != nil {
         return err
     }
-
-    jwt, err := pgjwt.Parse(jwtToken)
-    
-    //pmlog.LogDebug("bind jwt:", jwt.ToJSON())
-
+    _, err = this.storeJWToken(jwtToken)
     if err != nil {
         return err
     }
-    this.SetJWTToken(jwtToken)
-    this.SetJWTExpire(jwt.Expire())
-
     pmlog.LogInfo("pixcore binded to core successfully")
     return err
 }
 
 func (this *Pixcore) UpdateJWToken() error {
+    return this.UpdateJWTokenCtx(this.pixcoreCtx)
+}
+
+func (this *Pixcore) UpdateJWTokenCtx(ctx context.Context) error {
     var err error
 
-    jwtToken, err := this.getJwtToken(this.GetAuthToken(), this.jwtTTL, this.profileTags)
+    jwtToken, err := this.getJwtToken(ctx, this.GetAuthToken(), this.jwtTTL, this.profileTags)
     if err != nil {
         return err
     }
-
-    jwt, err := pgjwt.Parse(jwtToken)
-    //pmlog.LogDebug("bind jwt:", jwt.ToJSON())
+    jwt, err := this.storeJWToken(jwtToken)
     if err != nil {
         return err
     }
-    this.SetJWTToken(jwtToken)
-    this.SetJWTExpire(jwt.Expire())
 
     pmlog.LogDebug("jwt expire after:", jwt.Expire() - time.Now().Unix())
 
@@ -229,7 +297,7 @@ type authRefreshTokenResponse struct {
 //
 // getAuthToken()
 //
-func (this *Pixcore) getAuthToken() (string, string, error) {
+func (this *Pixcore) getAuthToken(ctx context.Context) (string, string, error) {
     var err     error
     var token   string
     var id      string
@@ -259,25 +327,7 @@ func (this *Pixcore) getAuthToken() (string, string, error) {
     tmpl.SetStrRepl("password", password)
     gqReq = tmpl.Pack()
 
-    url, err := this.GetPureURL()
-    if err != nil {
-        return token, id, err
-    }
-    httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(gqReq)))
-    if err != nil {
-        pmlog.LogInfo("error reading request. ", err)
-        return token, id, err
-    }
-    httpReq.Header.Set("Content-Type", "application/json")
-
-    httpClient := &http.Client{Timeout: time.Second * httpTimeout}
-    httpResp, err := httpClient.Do(httpReq)
-    if err != nil {
-        return token, id, err
-    }
-    defer httpResp.Body.Close()
-
-    httpRespBody, err := ioutil.ReadAll(httpResp.Body)
+    httpRespBody, err := this.doRequest(ctx, gqReq, false)
     if err != nil {
         return token, id, err
     }
@@ -315,7 +365,7 @@ type authJwtTokenResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 
-func (this *Pixcore) getJwtToken(authToken string, ttl int, profileTags []string) (string, error) {
+func (this *Pixcore) getJwtToken(ctx context.Context, authToken string, ttl int, profileTags []string) (string, error) {
     var jwtToken string
     var err error
 
@@ -337,24 +387,11 @@ func (this *Pixcore) getJwtToken(authToken string, ttl int, profileTags []string
     tmpl.SetStrArrayRepl("profileTags", profileTags)
     gqReq = tmpl.Pack()
 
-    url, err := this.GetPureURL()
-    if err != nil {
-        return jwtToken, err
+    httpRespBody, err := this.doRequest(ctx, gqReq, false)
+    var httpErr *HTTPError
+    if errors.As(err, &httpErr) && httpErr.Unauthorized() {
+        return jwtToken, fmt.Errorf("get jwt: %w: %s", ErrTokenRejected, err)
     }
-    httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(gqReq)))
-    if err != nil {
-        return jwtToken, err
-    }
-    httpReq.Header.Set("Content-Type", "application/json")
-
-    httpClient := &http.Client{Timeout: time.Second * httpTimeout}
-    httpResp, err := httpClient.Do(httpReq)
-    if err != nil {
-        return jwtToken, err
-    }
-    defer httpResp.Body.Close()
-
-    httpRespBody, err := ioutil.ReadAll(httpResp.Body)
     if err != nil {
         return jwtToken, err
     }
@@ -366,12 +403,16 @@ func (this *Pixcore) getJwtToken(authToken string, ttl int, profileTags []string
     }
     jwtToken = gqResp.Data.AuthAccessToken.JwtToken
 
+    if gqResp.Errors != nil && gqResp.Errors.Unauthorized() {
+        err = fmt.Errorf("get jwt: %w: %s", ErrTokenRejected, gqResp.Errors.GetMessages())
+        return jwtToken, err
+    }
     if gqResp.Errors != nil {
-        err = errors.New("get jwt: " + gqResp.Errors.GetMessages())
+        err = fmt.Errorf("get jwt: %s", gqResp.Errors.GetMessages())
         return jwtToken, err
     }
     if len(jwtToken) == 0 {
-        err = errors.New("get jwt: got zero length token")
+        err = fmt.Errorf("get jwt: %w: got zero length token", ErrTokenRejected)
         return jwtToken, err
     }
     return jwtToken, err
@@ -0,0 +1,136 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "errors"
+    "sync"
+    "time"
+
+    "app/pmlog"
+)
+
+var ErrCircuitOpen = errors.New("core circuit open")
+
+type BreakerListener = func(open bool)
+//
+// Breaker
+//
+// Breaker opens after threshold of consecutive core failures and
+// rejects requests until cooldown passes. Then one trial request is
+// let through, its success closes the breaker, failure opens it again.
+// Listeners are called on open and close.
+//
+type Breaker struct {
+    threshold   int
+    cooldown    time.Duration
+    failures    int
+    open        bool
+    trial       bool
+    openedAt    time.Time
+    listeners   []BreakerListener
+    mutex       sync.Mutex
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+    return &Breaker{
+        threshold:  threshold,
+        cooldown:   cooldown,
+        listeners:  make([]BreakerListener, 0),
+    }
+}
+//
+// SetLimits() changes threshold and cooldown, zero threshold disables breaker
+//
+func (this *Breaker) SetLimits(threshold int, cooldown time.Duration) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.threshold  = threshold
+    this.cooldown   = cooldown
+}
+
+func (this *Breaker) OnChange(listener BreakerListener) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.listeners = append(this.listeners, listener)
+}
+
+func (this *Breaker) IsOpen() bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.open
+}
+//
+// Allow() returns ErrCircuitOpen when request must not be sent
+//
+func (this *Breaker) Allow() error {
+    var err error
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    if !this.open {
+        return err
+    }
+    if this.trial || time.Since(this.openedAt) < this.cooldown {
+        return ErrCircuitOpen
+    }
+    this.trial = true
+    return err
+}
+
+//
+// Release() frees trial slot of canceled request, breaker state
+// is not changed
+//
+func (this *Breaker) Release() {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.trial = false
+}
+
+func (this *Breaker) Success() {
+    this.mutex.Lock()
+    this.failures = 0
+    this.trial = false
+    if !this.open {
+        this.mutex.Unlock()
+        return
+    }
+    this.open = false
+    listeners := append([]BreakerListener{}, this.listeners...)
+    this.mutex.Unlock()
+
+    pmlog.LogInfo("core circuit closed")
+    for _, listener := range listeners {
+        listener(false)
+    }
+}
+
+func (this *Breaker) Failure() {
+    this.mutex.Lock()
+    this.failures += 1
+    if this.open {
+        if this.trial {
+            this.trial = false
+            this.openedAt = time.Now()
+        }
+        this.mutex.Unlock()
+        return
+    }
+    if this.threshold <= 0 || this.failures < this.threshold {
+        this.mutex.Unlock()
+        return
+    }
+    this.open = true
+    this.openedAt = time.Now()
+    listeners := append([]BreakerListener{}, this.listeners...)
+    failures := this.failures
+    this.mutex.Unlock()
+
+    pmlog.LogWarning("core circuit opened after", failures, "failures")
+    for _, listener := range listeners {
+        listener(true)
+    }
+}
+//EOF
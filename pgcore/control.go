@@ -5,6 +5,7 @@
 package pgcore
 
 import (
+    "context"
     "encoding/json"
     "errors"
     "time"
@@ -38,6 +39,10 @@ type CreateControlExecutionResponse struct {
 }
 
 func (this *Pixcore) CreateControlExecution(objectId pgschema.UUID, controlName string, params JSON) (int, error) {
+    return this.CreateControlExecutionCtx(this.pixcoreCtx, objectId, controlName, params)
+}
+
+func (this *Pixcore) CreateControlExecutionCtx(ctx context.Context, objectId pgschema.UUID, controlName string, params JSON) (int, error) {
     var err error
     var result int
 
@@ -63,7 +68,7 @@ func (this *Pixcore) CreateControlExecution(objectId pgschema.UUID, controlName
     tmpl.SetStrRepl("params",       params)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -93,7 +98,20 @@ type CreateControlExecutionStealthResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 
+type CreateControlExecutionStealthByPropertyValueResponse struct {
+    Data struct {
+		CreateControlExecutionStealthByPropertyValue struct {
+			Boolean          bool   `json:"boolean"`
+		} `json:"createControlExecutionStealthByPropertyValue"`
+    } `json:"data"`
+    Errors  pgerrors.Errors  `json:"errors"`
+}
+
 func (this *Pixcore) CreateControlExecutionStealth(objectId pgschema.UUID, controlName string, params JSON) error {
+    return this.CreateControlExecutionStealthCtx(this.pixcoreCtx, objectId, controlName, params)
+}
+
+func (this *Pixcore) CreateControlExecutionStealthCtx(ctx context.Context, objectId pgschema.UUID, controlName string, params JSON) error {
     var err error
 
     gqReq := `{
@@ -114,7 +132,7 @@ func (this *Pixcore) CreateControlExecutionStealth(objectId pgschema.UUID, contr
     tmpl.SetStrRepl("params",       params)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -144,6 +162,10 @@ type UpdateControlExecutionAckResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) UpdateControlExecutionAck(controlsExecutionId int64) error {
+    return this.UpdateControlExecutionAckCtx(this.pixcoreCtx, controlsExecutionId)
+}
+
+func (this *Pixcore) UpdateControlExecutionAckCtx(ctx context.Context, controlsExecutionId int64) error {
     var err error
 
     if controlsExecutionId < 0 {
@@ -168,7 +190,7 @@ func (this *Pixcore) UpdateControlExecutionAck(controlsExecutionId int64) error
     tmpl.SetStrRepl("clientMutationId",     clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -203,6 +225,10 @@ type CreateControlExecutionReportResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) CreateControlExecutionReport(controlId int64, wError bool, done bool, report string) error {
+    return this.CreateControlExecutionReportCtx(this.pixcoreCtx, controlId, wError, done, report)
+}
+
+func (this *Pixcore) CreateControlExecutionReportCtx(ctx context.Context, controlId int64, wError bool, done bool, report string) error {
     var err error
 
     if controlId < 0 {
@@ -253,7 +279,7 @@ func (this *Pixcore) CreateControlExecutionReport(controlId int64, wError bool,
 
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -277,7 +303,11 @@ func (this *Pixcore) CreateControlExecutionReport(controlId int64, wError bool,
 }
 
 func (this *Pixcore) CreateControlExecutionEmptyReport(controlId int64, wError bool, done bool) error {
-    return this.CreateControlExecutionReport(controlId, wError, done, "")
+    return this.CreateControlExecutionEmptyReportCtx(this.pixcoreCtx, controlId, wError, done)
+}
+
+func (this *Pixcore) CreateControlExecutionEmptyReportCtx(ctx context.Context, controlId int64, wError bool, done bool) error {
+    return this.CreateControlExecutionReportCtx(ctx, controlId, wError, done, "")
 }
 
 //
@@ -290,6 +320,10 @@ type EnrichWith struct {
 }
 
 func (this *Pixcore) CreateControlExecutionStealthByPropertyPattern(controlName string, params JSON, groupName string, property string, pattern string) error {
+    return this.CreateControlExecutionStealthByPropertyPatternCtx(this.pixcoreCtx, controlName, params, groupName, property, pattern)
+}
+
+func (this *Pixcore) CreateControlExecutionStealthByPropertyPatternCtx(ctx context.Context, controlName string, params JSON, groupName string, property string, pattern string) error {
     var err error
 
     gqReq := `{
@@ -317,7 +351,7 @@ func (this *Pixcore) CreateControlExecutionStealthByPropertyPattern(controlName
 
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -337,8 +371,13 @@ func (this *Pixcore) CreateControlExecutionStealthByPropertyPattern(controlName
 //
 // CreateControlExecutionStealthByPropertyValue()
 //
-func (this *Pixcore) CreateControlExecutionStealthByPropertyValue(controlName string, params JSON, groupName string, property string, value string) error {
+func (this *Pixcore) CreateControlExecutionStealthByPropertyValue(controlName string, params JSON, groupName string, property string, value string) (bool, error) {
+    return this.CreateControlExecutionStealthByPropertyValueCtx(this.pixcoreCtx, controlName, params, groupName, property, value)
+}
+
+func (this *Pixcore) CreateControlExecutionStealthByPropertyValueCtx(ctx context.Context, controlName string, params JSON, groupName string, property string, value string) (bool, error) {
     var err error
+    var claimed bool
 
     gqReq := `{
         "variables": {
@@ -365,21 +404,22 @@ func (this *Pixcore) CreateControlExecutionStealthByPropertyValue(controlName st
 
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
-        return err
+        return claimed, err
     }
 
-    var gqResp CreateControlExecutionStealthResponse
+    var gqResp CreateControlExecutionStealthByPropertyValueResponse
     err = json.Unmarshal(httpRespBody, &gqResp)
     if err != nil {
-        return err
+        return claimed, err
     }
 
     if gqResp.Errors != nil {
         err = errors.New("create control execution stealth: " + gqResp.Errors.GetMessages())
-        return err
+        return claimed, err
     }
-    return err
+    claimed = gqResp.Data.CreateControlExecutionStealthByPropertyValue.Boolean
+    return claimed, err
 }
 //EOF
@@ -1,41 +1,223 @@
 /*
- * Copyright:  Pixel Networks <support@pixel-networks.com> 
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
  */
 
 package pgcore
 
 import (
     "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
     "io/ioutil"
     "net/http"
+    "regexp"
+    "strings"
     "time"
+
+    "app/pgerrors"
+    "app/pmlog"
 )
 
 const (
     httpTimeout             time.Duration   = 30  // sec
+    httpErrorBodyLen        int             = 200
 )
 //
-// httpRequest 
+// RequestPolicy
+//
+// RequestPolicy sets timeout of single core request, retries of
+// idempotent queries and circuit breaker limits.
+//
+type RequestPolicy struct {
+    Timeout             time.Duration
+    Attempts            int
+    Backoff             time.Duration
+    MaxBackoff          time.Duration
+    BreakerThreshold    int
+    BreakerCooldown     time.Duration
+}
+
+func DefaultRequestPolicy() RequestPolicy {
+    return RequestPolicy{
+        Timeout:            httpTimeout * time.Second,
+        Attempts:           3,
+        Backoff:            500 * time.Millisecond,
+        MaxBackoff:         5 * time.Second,
+        BreakerThreshold:   5,
+        BreakerCooldown:    30 * time.Second,
+    }
+}
+//
+// HTTPError reports non-2xx core response, message is taken from
+// graphql errors when core sent them or from body otherwise
+//
+type HTTPError struct {
+    StatusCode  int
+    Status      string
+    Message     string
+}
+
+func (this *HTTPError) Error() string {
+    return fmt.Sprintf("core http %s: %s", this.Status, this.Message)
+}
+//
+// Temporary() reports error worth retry
+//
+func (this *HTTPError) Temporary() bool {
+    switch this.StatusCode {
+        case http.StatusRequestTimeout, http.StatusTooManyRequests:
+            return true
+    }
+    return this.StatusCode >= http.StatusInternalServerError
+}
+
+//
+// Unauthorized() reports credentials not accepted by core
+//
+func (this *HTTPError) Unauthorized() bool {
+    return this.StatusCode == http.StatusUnauthorized || this.StatusCode == http.StatusForbidden
+}
+
+func newHTTPError(httpResp *http.Response, body []byte) *HTTPError {
+    var gqResp struct {
+        Errors  pgerrors.Errors  `json:"errors"`
+    }
+    message := ""
+    if json.Unmarshal(body, &gqResp) == nil && len(gqResp.Errors) > 0 {
+        message = gqResp.Errors.GetMessages()
+    } else {
+        message = strings.Join(strings.Fields(string(body)), " ")
+        if len(message) > httpErrorBodyLen {
+            message = message[:httpErrorBodyLen] + "..."
+        }
+    }
+    return &HTTPError{
+        StatusCode: httpResp.StatusCode,
+        Status:     httpResp.Status,
+        Message:    message,
+    }
+}
 //
-func (this *Pixcore) httpRequest(gqReq string) ([]byte, error) {
+// isTemporary() reports failures of core itself: transport errors,
+// timeouts and 5xx responses
+//
+func isTemporary(err error) bool {
+    if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+        return false
+    }
+    var httpErr *HTTPError
+    if errors.As(err, &httpErr) {
+        return httpErr.Temporary()
+    }
+    return true
+}
+
+var operationPattern = regexp.MustCompile(`"query"\s*:\s*"\s*(\w*)`)
+//
+// isIdempotent() reports graphql query, mutations are not retried
+//
+func isIdempotent(gqReq string) bool {
+    match := operationPattern.FindStringSubmatch(gqReq)
+    if match == nil {
+        return false
+    }
+    return match[1] != "mutation" && match[1] != "subscription"
+}
+//
+// SetRequestPolicy()
+//
+func (this *Pixcore) SetRequestPolicy(policy RequestPolicy) {
+    this.policyMutex.Lock()
+    defer this.policyMutex.Unlock()
+    this.policy = policy
+    this.breaker.SetLimits(policy.BreakerThreshold, policy.BreakerCooldown)
+}
+
+func (this *Pixcore) getRequestPolicy() RequestPolicy {
+    this.policyMutex.RLock()
+    defer this.policyMutex.RUnlock()
+    return this.policy
+}
+//
+// Breaker() returns core circuit breaker
+//
+func (this *Pixcore) Breaker() *Breaker {
+    return this.breaker
+}
+//
+// httpRequestContext() sends authorized request, idempotent queries are
+// retried with backoff on temporary failures. Every request method Foo()
+// has FooCtx() variant taking context, Foo() uses pixcore context which
+// is canceled with parent context given to New().
+//
+func (this *Pixcore) httpRequestContext(ctx context.Context, gqReq string) ([]byte, error) {
+    return this.doRequest(ctx, gqReq, true)
+}
+
+func (this *Pixcore) doRequest(ctx context.Context, gqReq string, authorized bool) ([]byte, error) {
     var err error
-    httpRespBody := make([]byte, 0) 
+    var httpRespBody []byte
+    policy := this.getRequestPolicy()
+    attempts := 1
+    if isIdempotent(gqReq) && policy.Attempts > 1 {
+        attempts = policy.Attempts
+    }
+    backoff := policy.Backoff
+    for attempt := 1; attempt <= attempts; attempt++ {
+        err = this.breaker.Allow()
+        if err != nil {
+            return httpRespBody, err
+        }
+        httpRespBody, err = this.sendRequest(ctx, gqReq, authorized, policy.Timeout)
+        if ctx.Err() != nil {
+            this.breaker.Release()
+            return httpRespBody, err
+        }
+        if !isTemporary(err) {
+            this.breaker.Success()
+            return httpRespBody, err
+        }
+        this.breaker.Failure()
+        if attempt == attempts {
+            break
+        }
+        pmlog.LogWarning("core request attempt", attempt, "error:", err, "retry after", backoff)
+        select {
+            case <- ctx.Done():
+                return httpRespBody, ctx.Err()
+            case <- time.After(backoff):
+        }
+        backoff *= 2
+        if backoff > policy.MaxBackoff {
+            backoff = policy.MaxBackoff
+        }
+    }
+    return httpRespBody, err
+}
+
+func (this *Pixcore) sendRequest(ctx context.Context, gqReq string, authorized bool, timeout time.Duration) ([]byte, error) {
+    var err error
+    httpRespBody := make([]byte, 0)
 
     url, err := this.GetPureURL()
     if err != nil {
         return httpRespBody, err
     }
-    httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(gqReq)))
+    httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(gqReq)))
     if err != nil {
         return httpRespBody, err
     }
 
     httpReq.Close = true // https://golang.org/pkg/net/http/#Request
     httpReq.Header.Set("Content-Type", "application/json")
-    httpReq.Header.Set("Authorization", "Bearer " + this.GetJWTToken())
+    if authorized {
+        httpReq.Header.Set("Authorization", "Bearer " + this.GetJWTToken())
+    }
 
     httpClient := &http.Client{
-        Timeout: httpTimeout * time.Second,
+        Timeout: timeout,
     }
     httpResp, err := httpClient.Do(httpReq)
     if err != nil {
@@ -47,7 +229,9 @@ func (this *Pixcore) httpRequest(gqReq string) ([]byte, error) {
     if err != nil {
         return httpRespBody, err
     }
-
+    if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+        return httpRespBody, newHTTPError(httpResp, httpRespBody)
+    }
     return httpRespBody, err
 }
 //EOF
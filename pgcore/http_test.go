@@ -0,0 +1,126 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func newTestPixcore(t *testing.T, handler http.HandlerFunc) (*Pixcore, *int32) {
+    var calls int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&calls, 1)
+        handler(w, r)
+    }))
+    t.Cleanup(server.Close)
+
+    pg := New(context.Background())
+    err := pg.Setup(server.URL + "/graphql", "user", "password", 1, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    policy := DefaultRequestPolicy()
+    policy.Backoff      = time.Millisecond
+    policy.MaxBackoff   = time.Millisecond
+    pg.SetRequestPolicy(policy)
+    return pg, &calls
+}
+
+func TestHTTPErrorStatus(t *testing.T) {
+    pg, calls := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusBadGateway)
+        w.Write([]byte("<html><body>Bad Gateway</body></html>"))
+    })
+
+    _, err := pg.httpRequestContext(context.Background(), `{ "query": "query GetUser { user { id } }" }`)
+    var httpErr *HTTPError
+    if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+        t.Fatal("status not reported:", err)
+    }
+    if atomic.LoadInt32(calls) != 3 {
+        t.Error("query not retried, calls:", atomic.LoadInt32(calls))
+    }
+
+    atomic.StoreInt32(calls, 0)
+    _, err = pg.httpRequestContext(context.Background(), `{ "query": "mutation CreateObject { createObject { id } }" }`)
+    if err == nil || atomic.LoadInt32(calls) != 1 {
+        t.Error("mutation retried, calls:", atomic.LoadInt32(calls))
+    }
+}
+
+func TestHTTPErrorMessages(t *testing.T) {
+    pg, _ := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusBadRequest)
+        w.Write([]byte(`{"errors":[{"message":"first"},{"message":"second"}]}`))
+    })
+    _, err := pg.httpRequestContext(context.Background(), `{ "query": "query GetUser { user { id } }" }`)
+    var httpErr *HTTPError
+    if !errors.As(err, &httpErr) || httpErr.Message != "first; second" || httpErr.Temporary() {
+        t.Error("wrong error:", err)
+    }
+}
+
+func TestBreaker(t *testing.T) {
+    opened := make([]bool, 0)
+    breaker := NewBreaker(2, time.Hour)
+    breaker.OnChange(func(open bool) {
+        opened = append(opened, open)
+    })
+    breaker.Failure()
+    if breaker.Allow() != nil {
+        t.Error("breaker opened before threshold")
+    }
+    breaker.Failure()
+    if breaker.Allow() != ErrCircuitOpen || !breaker.IsOpen() {
+        t.Error("breaker not opened")
+    }
+
+    breaker.SetLimits(2, 0)
+    if breaker.Allow() != nil {
+        t.Error("trial request not allowed after cooldown")
+    }
+    if breaker.Allow() != ErrCircuitOpen {
+        t.Error("second trial request allowed")
+    }
+    breaker.Success()
+    if breaker.IsOpen() || len(opened) != 2 || !opened[0] || opened[1] {
+        t.Error("wrong breaker changes:", opened)
+    }
+}
+
+func TestBreakerTrialCanceled(t *testing.T) {
+    pg, _ := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{"data":{}}`))
+    })
+    policy := pg.getRequestPolicy()
+    policy.BreakerThreshold = 1
+    policy.BreakerCooldown  = 0
+    pg.SetRequestPolicy(policy)
+    pg.Breaker().Failure()
+    if !pg.Breaker().IsOpen() {
+        t.Fatal("breaker not opened")
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    _, err := pg.httpRequestContext(ctx, `{ "query": "mutation CreateObject { createObject { id } }" }`)
+    if err == nil || errors.Is(err, ErrCircuitOpen) {
+        t.Fatal("canceled trial request not sent:", err)
+    }
+    _, err = pg.httpRequestContext(context.Background(), `{ "query": "mutation CreateObject { createObject { id } }" }`)
+    if err != nil {
+        t.Fatal("request after canceled trial rejected:", err)
+    }
+    if pg.Breaker().IsOpen() {
+        t.Error("breaker not closed after trial")
+    }
+}
+//EOF
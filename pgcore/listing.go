@@ -0,0 +1,217 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strings"
+
+    "app/pgerrors"
+    "app/pgquery"
+    "app/pgschema"
+)
+
+const (
+    DefaultPageSize         int     = 100
+)
+//
+// ObjectFilter
+//
+// ObjectFilter selects objects on core side: by schema id and by
+// property with given group, name and value, empty fields are not
+// checked. Properties lists property values included into result.
+// Property filter needs connection filter plugin with relations.
+//
+type ObjectFilter struct {
+    SchemaId        pgschema.UUID
+    GroupName       string
+    PropertyName    string
+    Value           string
+    Properties      []string
+    PageSize        int
+}
+//
+// ObjectItem is object with selected property values
+//
+type ObjectItem struct {
+    pgschema.Object
+    Properties      map[string]string   `json:"properties"`
+}
+
+type ObjectPage struct {
+    Objects         []ObjectItem
+    TotalCount      int
+    EndCursor       string
+    HasNextPage     bool
+}
+
+type listObjectsPageResponse struct {
+    Data struct {
+        ObjectsConnection struct {
+            TotalCount  int     `json:"totalCount"`
+            PageInfo struct {
+                HasNextPage bool    `json:"hasNextPage"`
+                EndCursor   string  `json:"endCursor"`
+            } `json:"pageInfo"`
+            Nodes []struct {
+                pgschema.Object
+                ObjectProperties struct {
+                    Nodes   []ObjectProperty    `json:"nodes"`
+                } `json:"objectProperties"`
+            } `json:"nodes"`
+        } `json:"objectsConnection"`
+    } `json:"data"`
+    Errors  pgerrors.Errors  `json:"errors"`
+}
+
+func (this *ObjectFilter) condition() map[string]interface{} {
+    condition := make(map[string]interface{})
+    if len(this.SchemaId) > 0 {
+        condition["schemaId"] = this.SchemaId
+    }
+    return condition
+}
+
+func (this *ObjectFilter) filter() map[string]interface{} {
+    property := make(map[string]interface{})
+    if len(this.GroupName) > 0 {
+        property["groupName"] = map[string]string{ "equalTo": this.GroupName }
+    }
+    if len(this.PropertyName) > 0 {
+        property["property"] = map[string]string{ "equalTo": this.PropertyName }
+    }
+    if len(this.Value) > 0 {
+        property["value"] = map[string]string{ "equalTo": this.Value }
+    }
+    filter := make(map[string]interface{})
+    if len(property) > 0 {
+        filter["objectProperties"] = map[string]interface{}{ "some": property }
+    }
+    return filter
+}
+//
+// ListObjectsPage() fetches one page of filtered objects after cursor,
+// empty cursor starts from the first page
+//
+func (this *Pixcore) ListObjectsPage(filter ObjectFilter, after string) (ObjectPage, error) {
+    return this.ListObjectsPageCtx(this.pixcoreCtx, filter, after)
+}
+
+func (this *Pixcore) ListObjectsPageCtx(ctx context.Context, filter ObjectFilter, after string) (ObjectPage, error) {
+    var err error
+    var result ObjectPage
+    result.Objects = make([]ObjectItem, 0)
+
+    pageSize := filter.PageSize
+    if pageSize <= 0 {
+        pageSize = DefaultPageSize
+    }
+    gQuery := pgquery.NewGQuery()
+    gQuery.AddIntVar("first", pageSize)
+    if len(after) > 0 {
+        gQuery.AddStrVar("after", after)
+    }
+    gQuery.Variables["condition"]   = filter.condition()
+    gQuery.Variables["filter"]      = filter.filter()
+
+    declarations := ""
+    properties := ""
+    if len(filter.Properties) > 0 {
+        gQuery.Variables["properties"] = filter.Properties
+        declarations = `, $properties: [String!]`
+        properties = `objectProperties(filter: { property: { in: $properties } }) {
+                            nodes {
+                                id
+                                objectId
+                                property
+                                groupName
+                                value
+                                type
+                            }
+                        }`
+    }
+
+    gQuery.Query = `query ListObjectsPage($first: Int!, $after: Cursor, $condition: ObjectCondition,
+                                        $filter: ObjectFilter ##declarations##) {
+                objectsConnection(first: $first, after: $after, condition: $condition,
+                                        filter: $filter, orderBy: ID_ASC) {
+                    totalCount
+                    pageInfo {
+                        hasNextPage
+                        endCursor
+                    }
+                    nodes {
+                        id
+                        name
+                        schemaId
+                        enabled
+                        description
+                        editorgroup
+                        usergroup
+                        readergroup
+                        ##properties##
+                    }
+                }
+        }`
+    gQuery.Query = strings.Replace(gQuery.Query, "##declarations##", declarations, 1)
+    gQuery.Query = strings.Replace(gQuery.Query, "##properties##", properties, 1)
+
+    httpRespBody, err := this.httpRequestContext(ctx, gQuery.ToJson())
+    if err != nil {
+        return result, err
+    }
+    var gqResp listObjectsPageResponse
+    err = json.Unmarshal(httpRespBody, &gqResp)
+    if err != nil {
+        return result, err
+    }
+    if gqResp.Errors != nil {
+        err = errors.New("list objects page: " + gqResp.Errors.GetMessages())
+        return result, err
+    }
+
+    connection := gqResp.Data.ObjectsConnection
+    result.TotalCount   = connection.TotalCount
+    result.HasNextPage  = connection.PageInfo.HasNextPage
+    result.EndCursor    = connection.PageInfo.EndCursor
+    for _, node := range connection.Nodes {
+        item := ObjectItem{
+            Object:     node.Object,
+            Properties: make(map[string]string),
+        }
+        for _, property := range node.ObjectProperties.Nodes {
+            item.Properties[property.Property] = property.Value
+        }
+        result.Objects = append(result.Objects, item)
+    }
+    return result, err
+}
+//
+// ListObjectItems() walks all pages of filtered objects
+//
+func (this *Pixcore) ListObjectItems(filter ObjectFilter) ([]ObjectItem, error) {
+    return this.ListObjectItemsCtx(this.pixcoreCtx, filter)
+}
+
+func (this *Pixcore) ListObjectItemsCtx(ctx context.Context, filter ObjectFilter) ([]ObjectItem, error) {
+    var err error
+    result := make([]ObjectItem, 0)
+    cursor := ""
+    for {
+        page, err := this.ListObjectsPageCtx(ctx, filter, cursor)
+        if err != nil {
+            return result, err
+        }
+        result = append(result, page.Objects...)
+        if !page.HasNextPage || len(page.EndCursor) == 0 {
+            break
+        }
+        cursor = page.EndCursor
+    }
+    return result, err
+}
+//EOF
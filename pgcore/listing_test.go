@@ -0,0 +1,72 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "net/http"
+    "testing"
+)
+
+func TestListObjectItems(t *testing.T) {
+    pages := []string{
+        `{"data":{"objectsConnection":{"totalCount":3,
+            "pageInfo":{"hasNextPage":true,"endCursor":"c1"},
+            "nodes":[
+                {"id":"o1","name":"first","objectProperties":{"nodes":[{"property":"TOPIC_BASE","value":"t1"}]}},
+                {"id":"o2","name":"second","objectProperties":{"nodes":[]}}]}}}`,
+        `{"data":{"objectsConnection":{"totalCount":3,
+            "pageInfo":{"hasNextPage":false,"endCursor":"c2"},
+            "nodes":[
+                {"id":"o3","name":"third","objectProperties":{"nodes":[{"property":"TOPIC_BASE","value":"t3"}]}}]}}}`,
+    }
+    cursors := make([]interface{}, 0)
+    var variables map[string]interface{}
+    pg, _ := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+        body, _ := ioutil.ReadAll(r.Body)
+        var gqReq struct {
+            Variables map[string]interface{} `json:"variables"`
+        }
+        json.Unmarshal(body, &gqReq)
+        variables = gqReq.Variables
+        cursors = append(cursors, variables["after"])
+        w.Write([]byte(pages[len(cursors) - 1]))
+    })
+
+    items, err := pg.ListObjectItems(ObjectFilter{
+        SchemaId:       "schema",
+        GroupName:      "Credentials",
+        PropertyName:   "BRIDGE",
+        Value:          "bridge",
+        Properties:     []string{ "TOPIC_BASE" },
+        PageSize:       2,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(items) != 3 || items[2].Id != "o3" {
+        t.Fatal("wrong items:", items)
+    }
+    if items[0].Properties["TOPIC_BASE"] != "t1" || len(items[1].Properties) != 0 {
+        t.Error("wrong properties:", items[0].Properties, items[1].Properties)
+    }
+    if len(cursors) != 2 || cursors[0] != nil || cursors[1] != "c1" {
+        t.Error("cursor not followed:", cursors)
+    }
+
+    condition, _ := variables["condition"].(map[string]interface{})
+    if condition["schemaId"] != "schema" || variables["first"] != float64(2) {
+        t.Error("wrong condition:", variables)
+    }
+    filter, _ := variables["filter"].(map[string]interface{})
+    objectProperties, _ := filter["objectProperties"].(map[string]interface{})
+    some, _ := objectProperties["some"].(map[string]interface{})
+    value, _ := some["value"].(map[string]interface{})
+    if value["equalTo"] != "bridge" {
+        t.Error("wrong filter:", filter)
+    }
+}
+//EOF
@@ -5,19 +5,81 @@
 package pgcore
 
 import (
+    "context"
+    "bytes"
     "encoding/json"
     "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "time"
 
     "app/pgschema"
     "app/pgerrors"
     "app/pgtmpl"
+    "app/pmtools"
 )
 
 const (
     defaultReadPerission    string  = "ffffffff-ffff-ffff-ffff-ffffffffffff"
     defaultEditPermission   string  = "ffffffff-ffff-ffff-ffff-ffffffffffff"
     defaultUsePermission    string  = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+
+    mediaUploadPath         string  = "/upload/"
+    mediaDownloadPath       string  = "/download/"
+    mediaTimeout            time.Duration   = 60  // sec
 )
+//
+// SetMediaURL()
+//
+func (this *Pixcore) SetMediaURL(mediaRef string) error {
+    var err error
+    mediaURL, err := url.Parse(mediaRef)
+    if err != nil {
+        return err
+    }
+    this.mediaURL = mediaURL
+    return err
+}
+//
+// UploadMediaFile() stores file content in media service
+//
+func (this *Pixcore) UploadMediaFile(id pgschema.UUID, data []byte) error {
+    return this.UploadMediaFileCtx(this.pixcoreCtx, id, data)
+}
+
+func (this *Pixcore) UploadMediaFileCtx(ctx context.Context, id pgschema.UUID, data []byte) error {
+    var err error
+
+    if this.mediaURL == nil {
+        return errors.New("upload media file: null media url object")
+    }
+    mediaRef := this.mediaURL.String() + mediaUploadPath + id
+
+    httpReq, err := http.NewRequestWithContext(ctx, "POST", mediaRef, bytes.NewBuffer(data))
+    if err != nil {
+        return err
+    }
+    httpReq.Close = true
+    httpReq.Header.Set("Content-Type", "application/octet-stream")
+    httpReq.Header.Set("Authorization", "Bearer " + this.GetJWTToken())
+
+    httpClient := &http.Client{
+        Timeout: mediaTimeout * time.Second,
+    }
+    httpResp, err := httpClient.Do(httpReq)
+    if err != nil {
+        return err
+    }
+    defer httpResp.Body.Close()
+
+    httpRespBody, _ := ioutil.ReadAll(httpResp.Body)
+    if httpResp.StatusCode != http.StatusOK && httpResp.StatusCode != http.StatusCreated {
+        return fmt.Errorf("upload media file: status %d: %s", httpResp.StatusCode, string(httpRespBody))
+    }
+    return err
+}
 
 type RegisterMediaFileResponse struct {
 	Data struct {
@@ -30,6 +92,10 @@ type RegisterMediaFileResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) RegisterMediaFile(id pgschema.UUID, name string, schemaId pgschema.UUID) (string, error) {
+    return this.RegisterMediaFileCtx(this.pixcoreCtx, id, name, schemaId)
+}
+
+func (this *Pixcore) RegisterMediaFileCtx(ctx context.Context, id pgschema.UUID, name string, schemaId pgschema.UUID) (string, error) {
     var err error
     var result string
 
@@ -73,7 +139,7 @@ func (this *Pixcore) RegisterMediaFile(id pgschema.UUID, name string, schemaId p
  
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -91,4 +157,71 @@ func (this *Pixcore) RegisterMediaFile(id pgschema.UUID, name string, schemaId p
 
     return result, err
 }
+//
+// DownloadMediaFile() fetches file content from media service
+//
+func (this *Pixcore) DownloadMediaFile(id pgschema.UUID) ([]byte, error) {
+    return this.DownloadMediaFileCtx(this.pixcoreCtx, id)
+}
+
+func (this *Pixcore) DownloadMediaFileCtx(ctx context.Context, id pgschema.UUID) ([]byte, error) {
+    var err error
+    result := make([]byte, 0)
+
+    if this.mediaURL == nil {
+        return result, errors.New("download media file: null media url object")
+    }
+    mediaRef := this.mediaURL.String() + mediaDownloadPath + id
+
+    httpReq, err := http.NewRequestWithContext(ctx, "GET", mediaRef, nil)
+    if err != nil {
+        return result, err
+    }
+    httpReq.Close = true
+    httpReq.Header.Set("Authorization", "Bearer " + this.GetJWTToken())
+
+    httpClient := &http.Client{
+        Timeout: mediaTimeout * time.Second,
+    }
+    httpResp, err := httpClient.Do(httpReq)
+    if err != nil {
+        return result, err
+    }
+    defer httpResp.Body.Close()
+
+    httpRespBody, err := ioutil.ReadAll(httpResp.Body)
+    if err != nil {
+        return result, err
+    }
+    if httpResp.StatusCode != http.StatusOK {
+        return result, fmt.Errorf("download media file: status %d: %s", httpResp.StatusCode, string(httpRespBody))
+    }
+    result = httpRespBody
+    return result, err
+}
+//
+// StoreMediaFile() uploads content and registers media object
+//
+func (this *Pixcore) StoreMediaFile(name string, schemaId pgschema.UUID, data []byte) (pgschema.UUID, error) {
+    return this.StoreMediaFileCtx(this.pixcoreCtx, name, schemaId, data)
+}
+
+func (this *Pixcore) StoreMediaFileCtx(ctx context.Context, name string, schemaId pgschema.UUID, data []byte) (pgschema.UUID, error) {
+    var err error
+    var result pgschema.UUID
+
+    if len(schemaId) == 0 {
+        return result, errors.New("store media file: media schema id is empty")
+    }
+    id := pmtools.GetNewUUID()
+    err = this.UploadMediaFileCtx(ctx, id, data)
+    if err != nil {
+        return result, err
+    }
+    result, err = this.RegisterMediaFileCtx(ctx, id, name, schemaId)
+    if err != nil {
+        return result, err
+    }
+    return result, err
+}
 //EOF
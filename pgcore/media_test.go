@@ -0,0 +1,92 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "bytes"
+    "io/ioutil"
+    "net/http"
+    "strings"
+    "sync"
+    "testing"
+)
+
+func newTestMedia(t *testing.T) (*Pixcore, map[string][]byte) {
+    files := make(map[string][]byte)
+    var mutex sync.Mutex
+    pg, _ := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        body, _ := ioutil.ReadAll(r.Body)
+        switch {
+            case strings.HasPrefix(r.URL.Path, mediaUploadPath):
+                if r.Header.Get("Authorization") == "" {
+                    w.WriteHeader(http.StatusUnauthorized)
+                    return
+                }
+                files[strings.TrimPrefix(r.URL.Path, mediaUploadPath)] = body
+                w.WriteHeader(http.StatusCreated)
+            case strings.HasPrefix(r.URL.Path, mediaDownloadPath):
+                data, exists := files[strings.TrimPrefix(r.URL.Path, mediaDownloadPath)]
+                if !exists {
+                    w.WriteHeader(http.StatusNotFound)
+                    w.Write([]byte("not found"))
+                    return
+                }
+                w.Write(data)
+            default:
+                w.Write([]byte(`{"data":{"createObject":{"object":{"id":"media"}}}}`))
+        }
+    })
+    return pg, files
+}
+
+func TestStoreMediaFile(t *testing.T) {
+    pg, files := newTestMedia(t)
+    err := pg.SetMediaURL(pg.gqURL.Scheme + "://" + pg.gqURL.Host)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    data := []byte{ 0, 1, 2, 255 }
+    objectId, err := pg.StoreMediaFile("file.bin", "schema", data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if objectId != "media" || len(files) != 1 {
+        t.Fatal("media not stored:", objectId, len(files))
+    }
+    var id string
+    for key := range files {
+        id = key
+    }
+    stored, err := pg.DownloadMediaFile(id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(stored, data) {
+        t.Error("wrong content:", stored)
+    }
+
+    _, err = pg.DownloadMediaFile("unknown")
+    if err == nil || !strings.Contains(err.Error(), "404") {
+        t.Error("missing file not reported:", err)
+    }
+    _, err = pg.StoreMediaFile("file.bin", "", data)
+    if err == nil {
+        t.Error("empty schema id accepted")
+    }
+}
+
+func TestMediaURLUnset(t *testing.T) {
+    pg, _ := newTestMedia(t)
+    if _, err := pg.DownloadMediaFile("id"); err == nil {
+        t.Error("download without media url")
+    }
+    if err := pg.UploadMediaFile("id", nil); err == nil {
+        t.Error("upload without media url")
+    }
+}
+//EOF
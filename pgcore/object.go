@@ -5,6 +5,7 @@
 package pgcore
 
 import (
+    "context"
     "encoding/json"
     "errors"
 
@@ -28,6 +29,10 @@ type CreateObjectResponse struct {
 }
 
 func (this *Pixcore) CreateObject(object *pgschema.Object) (pgschema.UUID, error) {
+    return this.CreateObjectCtx(this.pixcoreCtx, object)
+}
+
+func (this *Pixcore) CreateObjectCtx(ctx context.Context, object *pgschema.Object) (pgschema.UUID, error) {
     var err error
     var result string
 
@@ -65,7 +70,7 @@ func (this *Pixcore) CreateObject(object *pgschema.Object) (pgschema.UUID, error
     gqReq = tmpl.Pack()
 
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -94,6 +99,10 @@ type DeleteObjectResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) DeleteObject(uuid pgschema.UUID) error {
+    return this.DeleteObjectCtx(this.pixcoreCtx, uuid)
+}
+
+func (this *Pixcore) DeleteObjectCtx(ctx context.Context, uuid pgschema.UUID) error {
     var err error
     //var result string
 
@@ -115,7 +124,7 @@ func (this *Pixcore) DeleteObject(uuid pgschema.UUID) error {
     tmpl.SetStrRepl("mutationId", clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -140,6 +149,10 @@ func (this *Pixcore) DeleteObject(uuid pgschema.UUID) error {
 
 
 func (this *Pixcore) DisableObject(id pgschema.UUID) error {
+    return this.DisableObjectCtx(this.pixcoreCtx, id)
+}
+
+func (this *Pixcore) DisableObjectCtx(ctx context.Context, id pgschema.UUID) error {
     var err error
     //var result string
 
@@ -161,7 +174,7 @@ func (this *Pixcore) DisableObject(id pgschema.UUID) error {
     tmpl.SetStrRepl("clientMutationId", clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -185,6 +198,10 @@ func (this *Pixcore) DisableObject(id pgschema.UUID) error {
 }
 
 func (this *Pixcore) EnableObject(id pgschema.UUID) error {
+    return this.EnableObjectCtx(this.pixcoreCtx, id)
+}
+
+func (this *Pixcore) EnableObjectCtx(ctx context.Context, id pgschema.UUID) error {
     var err error
     //var result string
 
@@ -206,7 +223,7 @@ func (this *Pixcore) EnableObject(id pgschema.UUID) error {
     tmpl.SetStrRepl("clientMutationId", clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return err
     }
@@ -242,6 +259,10 @@ type checkObjectExistsRespone struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) CheckObjectExists(id pgschema.UUID) (bool, error) {
+    return this.CheckObjectExistsCtx(this.pixcoreCtx, id)
+}
+
+func (this *Pixcore) CheckObjectExistsCtx(ctx context.Context, id pgschema.UUID) (bool, error) {
     var err error
     var result bool
 
@@ -259,7 +280,7 @@ func (this *Pixcore) CheckObjectExists(id pgschema.UUID) (bool, error) {
     tmpl.SetStrRepl("id", id)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
 
@@ -282,101 +303,107 @@ func (this *Pixcore) CheckObjectExists(id pgschema.UUID) (bool, error) {
     return result, err
 }
 
-
-type ListObjectsResponse struct {
+//
+// GetObject
+//
+type getObjectResponse struct {
     Data struct {
-        Objects []pgschema.Object    `json:"objects"`
-        //Objects []struct {
-        //  Id       string `json:"id"`
-        //  Name     string `json:"name"`
-        //  SchemaId string `json:"schemaId"`
-        //} `json:"objects"`
+        Object pgschema.Object `json:"object"`
     } `json:"data"`
     Errors  pgerrors.Errors  `json:"errors"`
 }
+func (this *Pixcore) GetObject(id pgschema.UUID) (pgschema.Object, error) {
+    return this.GetObjectCtx(this.pixcoreCtx, id)
+}
 
-//
-// ListObjects()
-//
-func (this *Pixcore) ListObjects() ([]pgschema.Object, error) {
+func (this *Pixcore) GetObjectCtx(ctx context.Context, id pgschema.UUID) (pgschema.Object, error) {
     var err error
-    var result []pgschema.Object = make([]pgschema.Object, 0)
+    var result pgschema.Object
 
     gqReq := `{
-        "query": "query {
-             objects {
-                id
-                name
-                schemaId
-            }
+        "variables": {
+            "id": "<<id>>"
+        },
+        "query": "query GetObject($id: UUID!) {
+                object(id: $id) {
+                    id
+                    name
+                    schemaId
+                    enabled
+                    description
+                }
         }"
     }`
-
     tmpl := pgtmpl.NewTemplate(gqReq)
+    tmpl.SetStrRepl("id", id)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
-
-    var gqResp ListObjectsResponse
+    var gqResp getObjectResponse
     err = json.Unmarshal(httpRespBody, &gqResp)
     if err != nil {
         return result, err
     }
-
     if gqResp.Errors != nil {
-        err = errors.New("list objects by schema id: " + gqResp.Errors.GetMessages())
+        err = errors.New("get object: " + gqResp.Errors.GetMessages())
         return result, err
     }
-
-    result = gqResp.Data.Objects
+    if gqResp.Data.Object.Id != id {
+        err = errors.New("get object: object not found")
+        return result, err
+    }
+    result = gqResp.Data.Object
     return result, err
 }
 
+
+type ListObjectsResponse struct {
+    Data struct {
+        Objects []pgschema.Object    `json:"objects"`
+        //Objects []struct {
+        //  Id       string `json:"id"`
+        //  Name     string `json:"name"`
+        //  SchemaId string `json:"schemaId"`
+        //} `json:"objects"`
+    } `json:"data"`
+    Errors  pgerrors.Errors  `json:"errors"`
+}
+
 //
-// ListObjectsBySchemaId()
+// ListObjects()
 //
-func (this *Pixcore) ListObjectsBySchemaId(schemaId pgschema.UUID) ([]pgschema.Object, error) {
-    var err error
-    var result []pgschema.Object = make([]pgschema.Object, 0)
+func (this *Pixcore) ListObjects() ([]pgschema.Object, error) {
+    return this.ListObjectsCtx(this.pixcoreCtx)
+}
 
-    gqReq := `{
-        "variables": {
-                "schemaId": "<<schemaId>>"
-        },
-        "query": "query ListObjectsBySchemaId($schemaId: UUID) {
-                     objects (condition: { schemaId: $schemaId }) {
-                        id
-                        name
-                        schemaId
-                        editorgroup
-                        usergroup
-                        readergroup
-                    }
-        }"
-    }`
+func (this *Pixcore) ListObjectsCtx(ctx context.Context) ([]pgschema.Object, error) {
+    return this.listObjects(ctx, ObjectFilter{})
+}
 
-    tmpl := pgtmpl.NewTemplate(gqReq)
-    tmpl.SetStrRepl("schemaId", schemaId)
-    gqReq = tmpl.Pack()
+//
+// ListObjectsBySchemaId()
+//
+func (this *Pixcore) ListObjectsBySchemaId(schemaId pgschema.UUID) ([]pgschema.Object, error) {
+    return this.ListObjectsBySchemaIdCtx(this.pixcoreCtx, schemaId)
+}
 
-    httpRespBody, err := this.httpRequest(gqReq)
-    if err != nil {
-        return result, err
-    }
+func (this *Pixcore) ListObjectsBySchemaIdCtx(ctx context.Context, schemaId pgschema.UUID) ([]pgschema.Object, error) {
+    return this.listObjects(ctx, ObjectFilter{ SchemaId: schemaId })
+}
 
-    var gqResp ListObjectsResponse
-    err = json.Unmarshal(httpRespBody, &gqResp)
+func (this *Pixcore) listObjects(ctx context.Context, filter ObjectFilter) ([]pgschema.Object, error) {
+    var err error
+    result := make([]pgschema.Object, 0)
+    items, err := this.ListObjectItemsCtx(ctx, filter)
     if err != nil {
         return result, err
     }
-    if gqResp.Errors != nil {
-        err = errors.New("list objects by schema id: " + gqResp.Errors.GetMessages())
-        return result, err
+    for _, item := range items {
+        result = append(result, item.Object)
     }
-    result = gqResp.Data.Objects
     return result, err
 }
 
@@ -397,6 +424,10 @@ func (this *Pixcore) ListObjectsBySchemaId(schemaId pgschema.UUID) ([]pgschema.O
 // GetObjectPropertyValue
 //
 func (this *Pixcore) GetObjectPropertyValue(objectId pgschema.UUID, propertyName string) (string, error) {
+    return this.GetObjectPropertyValueCtx(this.pixcoreCtx, objectId, propertyName)
+}
+
+func (this *Pixcore) GetObjectPropertyValueCtx(ctx context.Context, objectId pgschema.UUID, propertyName string) (string, error) {
     var err error
     var result string
 
@@ -420,7 +451,7 @@ func (this *Pixcore) GetObjectPropertyValue(objectId pgschema.UUID, propertyName
     tmpl.SetStrRepl("propertyName", propertyName)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -5,6 +5,7 @@
 package pgcore
 
 import (
+    "context"
     "encoding/json"
     "errors"
 
@@ -18,10 +19,14 @@ import (
 // UpdateObjectPropertyByName
 //
 func (this *Pixcore) UpdateObjectPropertyByName(objectId pgschema.UUID, propertyName string, value string) (string, error) {
+    return this.UpdateObjectPropertyByNameCtx(this.pixcoreCtx, objectId, propertyName, value)
+}
+
+func (this *Pixcore) UpdateObjectPropertyByNameCtx(ctx context.Context, objectId pgschema.UUID, propertyName string, value string) (string, error) {
     var err error
     var result string
 
-    propertyId, err := this.GetObjectPropertyId(objectId, propertyName)
+    propertyId, err := this.GetObjectPropertyIdCtx(ctx, objectId, propertyName)
     if err != nil {
         return result, err
     }
@@ -31,7 +36,7 @@ func (this *Pixcore) UpdateObjectPropertyByName(objectId pgschema.UUID, property
         return result, err
     }
 
-    mutId, err := this.UpdateObjectProperty(propertyId, value)
+    mutId, err := this.UpdateObjectPropertyCtx(ctx, propertyId, value)
     if err != nil {
         return result, err
     }
@@ -54,6 +59,10 @@ type GetObjectPropertyResult struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) GetObjectPropertyId(objectId pgschema.UUID, propertyName string) (string, error) {
+    return this.GetObjectPropertyIdCtx(this.pixcoreCtx, objectId, propertyName)
+}
+
+func (this *Pixcore) GetObjectPropertyIdCtx(ctx context.Context, objectId pgschema.UUID, propertyName string) (string, error) {
     var err error
     var result string
 
@@ -76,7 +85,7 @@ func (this *Pixcore) GetObjectPropertyId(objectId pgschema.UUID, propertyName st
     tmpl.SetStrRepl("property", propertyName)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -110,6 +119,10 @@ type UpdateObjectPropertyResponse struct {
 }
 
 func (this *Pixcore) UpdateObjectProperty(propertyId pgschema.UUID, value string) (string, error) {
+    return this.UpdateObjectPropertyCtx(this.pixcoreCtx, propertyId, value)
+}
+
+func (this *Pixcore) UpdateObjectPropertyCtx(ctx context.Context, propertyId pgschema.UUID, value string) (string, error) {
     var err error
     var result string
 
@@ -133,7 +146,7 @@ func (this *Pixcore) UpdateObjectProperty(propertyId pgschema.UUID, value string
     tmpl.SetStrRepl("clientMutationId", clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -189,6 +202,10 @@ func (this *ObjectProperty) GetJSON() string {
 }
 
 func (this *Pixcore) ListPropertiesByObjectGroup(objectId pgschema.UUID, groupName string) ([]ObjectProperty, error) {
+    return this.ListPropertiesByObjectGroupCtx(this.pixcoreCtx, objectId, groupName)
+}
+
+func (this *Pixcore) ListPropertiesByObjectGroupCtx(ctx context.Context, objectId pgschema.UUID, groupName string) ([]ObjectProperty, error) {
     var err error
     var result []ObjectProperty
 
@@ -214,7 +231,7 @@ func (this *Pixcore) ListPropertiesByObjectGroup(objectId pgschema.UUID, groupNa
     tmpl.SetStrRepl("groupName",  groupName)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -236,7 +253,67 @@ func (this *Pixcore) ListPropertiesByObjectGroup(objectId pgschema.UUID, groupNa
     return result, err
 }
 
+//
+// ListObjectIdsByPropertyValue
+//
+func (this *Pixcore) ListObjectIdsByPropertyValue(groupName string, propertyName string, value string) ([]pgschema.UUID, error) {
+    return this.ListObjectIdsByPropertyValueCtx(this.pixcoreCtx, groupName, propertyName, value)
+}
+
+func (this *Pixcore) ListObjectIdsByPropertyValueCtx(ctx context.Context, groupName string, propertyName string, value string) ([]pgschema.UUID, error) {
+    var err error
+    var result []pgschema.UUID = make([]pgschema.UUID, 0)
+
+    gqReq := `{
+        "variables": {
+                "groupName": "<<groupName>>",
+                "property": "<<property>>",
+                "value": "<<value>>"
+        },
+        "query": "query ListObjectIdsByPropertyValue($groupName: String, $property: String, $value: String) {
+                      objectProperties(condition: { groupName: $groupName, property: $property, value: $value }) {
+                        objectId
+                        value
+                        property
+                        groupName
+                        id
+                        type
+                      }
+        }"
+    }`
+
+    tmpl := pgtmpl.NewTemplate(gqReq)
+    tmpl.SetStrRepl("groupName",  groupName)
+    tmpl.SetStrRepl("property",   propertyName)
+    tmpl.SetStrRepl("value",      value)
+    gqReq = tmpl.Pack()
+
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
+    if err != nil {
+        return result, err
+    }
+
+    var gqResp ListPropertiesByObjectGroupResponse
+    err = json.Unmarshal(httpRespBody, &gqResp)
+    if err != nil {
+        return result, err
+    }
+
+    if gqResp.Errors != nil {
+        err = errors.New("list object ids by property value: " + gqResp.Errors.GetMessages())
+        return result, err
+    }
+    for _, property := range gqResp.Data.ObjectProperties {
+        result = append(result, property.ObjectId)
+    }
+    return result, err
+}
+
 func (this *Pixcore) ListProperties(objectId pgschema.UUID, groupName string) ([]ObjectProperty, error) {
+    return this.ListPropertiesCtx(this.pixcoreCtx, objectId, groupName)
+}
+
+func (this *Pixcore) ListPropertiesCtx(ctx context.Context, objectId pgschema.UUID, groupName string) ([]ObjectProperty, error) {
     var err error
     var result []ObjectProperty
 
@@ -261,7 +338,7 @@ func (this *Pixcore) ListProperties(objectId pgschema.UUID, groupName string) ([
     tmpl.SetStrRepl("groupName",  groupName)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -5,6 +5,7 @@
 package pgcore
 
 import (
+    "context"
     "encoding/json"
     "errors"
     "strings"
@@ -28,6 +29,10 @@ type checkSchemaExistsRespone struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) CheckSchemaExists(id string) (bool, error) {
+    return this.CheckSchemaExistsCtx(this.pixcoreCtx, id)
+}
+
+func (this *Pixcore) CheckSchemaExistsCtx(ctx context.Context, id string) (bool, error) {
     var err error
     var result bool
 
@@ -45,7 +50,7 @@ func (this *Pixcore) CheckSchemaExists(id string) (bool, error) {
     tmpl.SetStrRepl("id", id)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -80,6 +85,10 @@ type DeleteSchemaResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) DeleteSchema(uuid pgschema.UUID) (string, error) {
+    return this.DeleteSchemaCtx(this.pixcoreCtx, uuid)
+}
+
+func (this *Pixcore) DeleteSchemaCtx(ctx context.Context, uuid pgschema.UUID) (string, error) {
     var err error
     var result string
 
@@ -101,7 +110,7 @@ func (this *Pixcore) DeleteSchema(uuid pgschema.UUID) (string, error) {
     tmpl.SetStrRepl("mutationId", clientMutationId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -138,6 +147,10 @@ type ImportSchemaResponse struct {
 }
 
 func (this *Pixcore) ImportSchema(schema string) (pgschema.UUID, error) {
+    return this.ImportSchemaCtx(this.pixcoreCtx, schema)
+}
+
+func (this *Pixcore) ImportSchemaCtx(ctx context.Context, schema string) (pgschema.UUID, error) {
     var err     error
     var result  string
     var tmpl    *pgtmpl.Template
@@ -163,7 +176,7 @@ func (this *Pixcore) ImportSchema(schema string) (pgschema.UUID, error) {
 
     pmlog.LogDebug(gqReq)
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -196,6 +209,10 @@ type ExportSchemaResponse struct {
 }
 
 func (this *Pixcore) ExportSchema(schemaId pgschema.UUID) (string, error) {
+    return this.ExportSchemaCtx(this.pixcoreCtx, schemaId)
+}
+
+func (this *Pixcore) ExportSchemaCtx(ctx context.Context, schemaId pgschema.UUID) (string, error) {
     var err     error
     var result  string
     var tmpl    *pgtmpl.Template
@@ -212,7 +229,7 @@ func (this *Pixcore) ExportSchema(schemaId pgschema.UUID) (string, error) {
     tmpl.SetStrRepl("schemaId", schemaId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -259,6 +276,10 @@ type ListSchemasResponse struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) ListSchemas() ([]Schema, error) {
+    return this.ListSchemasCtx(this.pixcoreCtx)
+}
+
+func (this *Pixcore) ListSchemasCtx(ctx context.Context) ([]Schema, error) {
     var err error
     var result []Schema = make([]Schema, 0)
 
@@ -278,7 +299,7 @@ func (this *Pixcore) ListSchemas() ([]Schema, error) {
     tmpl := pgtmpl.NewTemplate(gqReq)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -295,6 +316,26 @@ func (this *Pixcore) ListSchemas() ([]Schema, error) {
     result = gqResp.Data.Schemas
     return result, err
 }
+//
+// GetSchemaVersion() returns deployed schema version, false if schema not exists
+//
+func (this *Pixcore) GetSchemaVersion(schemaId pgschema.UUID) (string, bool, error) {
+    return this.GetSchemaVersionCtx(this.pixcoreCtx, schemaId)
+}
+
+func (this *Pixcore) GetSchemaVersionCtx(ctx context.Context, schemaId pgschema.UUID) (string, bool, error) {
+    var err error
+    schemas, err := this.ListSchemasCtx(ctx)
+    if err != nil {
+        return "", false, err
+    }
+    for _, schema := range schemas {
+        if schema.Id == schemaId {
+            return schema.MVersion, true, err
+        }
+    }
+    return "", false, err
+}
 
 //
 // ListSchemaProperties
@@ -328,7 +369,11 @@ func (this *SchemaProperty) GetJSON() string {
     json, _ := json.Marshal(this)
     return string(json)
 }
-func (this *Pixcore) ListSchemaProperties(schemaId pgschema.UUID, ) ([]SchemaProperty, error) {
+func (this *Pixcore) ListSchemaProperties(schemaId pgschema.UUID) ([]SchemaProperty, error) {
+    return this.ListSchemaPropertiesCtx(this.pixcoreCtx, schemaId)
+}
+
+func (this *Pixcore) ListSchemaPropertiesCtx(ctx context.Context, schemaId pgschema.UUID) ([]SchemaProperty, error) {
     var err error
     var result []SchemaProperty
 
@@ -361,7 +406,7 @@ func (this *Pixcore) ListSchemaProperties(schemaId pgschema.UUID, ) ([]SchemaPro
     tmpl.SetStrRepl("schemaId", schemaId)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -0,0 +1,127 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+
+    "app/pmlog"
+)
+
+const (
+    tokenMinLead            time.Duration   = 10 * time.Second
+    tokenMinBackoff         time.Duration   = 1 * time.Second
+    tokenMaxBackoff         time.Duration   = 60 * time.Second
+)
+//
+// TokenManager
+//
+// TokenManager refreshes jwt on timer before expiration. Failed refresh
+// is retried with backoff, Bind() is done only when core rejects
+// the refresh token. Token change renews active subscriptions.
+//
+type TokenManager struct {
+    pg          *Pixcore
+    changed     chan struct{}
+    wg          sync.WaitGroup
+}
+
+func NewTokenManager(pg *Pixcore) *TokenManager {
+    manager := &TokenManager{
+        pg:         pg,
+        changed:    make(chan struct{}, 1),
+    }
+    pg.OnTokenChange(func(string) {
+        select {
+            case manager.changed <- struct{}{}:
+            default:
+        }
+    })
+    return manager
+}
+//
+// Start() runs refresh loop until context is done
+//
+func (this *TokenManager) Start(ctx context.Context) {
+    this.wg.Add(1)
+    go this.loop(ctx)
+}
+
+func (this *TokenManager) Wait() {
+    this.wg.Wait()
+}
+
+func (this *TokenManager) loop(ctx context.Context) {
+    defer this.wg.Done()
+    pmlog.LogInfo("jwt manager started")
+    for {
+        remaining := time.Until(time.Unix(this.pg.GetJWTExpire(), 0))
+        timer := time.NewTimer(refreshDelay(remaining))
+        select {
+            case <- ctx.Done():
+                timer.Stop()
+                pmlog.LogInfo("jwt manager stopped")
+                return
+            case <- this.changed:
+                timer.Stop()
+                continue
+            case <- timer.C:
+        }
+        err := this.Refresh(ctx)
+        if err != nil {
+            pmlog.LogInfo("jwt manager stopped:", err)
+            return
+        }
+    }
+}
+//
+// Refresh() updates jwt, retries until success or context is done
+//
+func (this *TokenManager) Refresh(ctx context.Context) error {
+    var err error
+    backoff := tokenMinBackoff
+    for {
+        err = this.pg.UpdateJWTokenCtx(ctx)
+        if errors.Is(err, ErrTokenRejected) {
+            pmlog.LogWarning("jwt refresh rejected, bind again:", err)
+            err = this.pg.BindCtx(ctx)
+        }
+        if err == nil {
+            return err
+        }
+        pmlog.LogError("jwt refresh error:", err, "retry after", backoff)
+        select {
+            case <- ctx.Done():
+                return ctx.Err()
+            case <- time.After(backoff):
+        }
+        backoff = nextBackoff(backoff)
+    }
+}
+//
+// refreshDelay() schedules refresh at last quarter of token lifetime
+//
+func refreshDelay(remaining time.Duration) time.Duration {
+    lead := remaining / 4
+    if lead < tokenMinLead {
+        lead = tokenMinLead
+    }
+    if remaining <= lead {
+        return 0
+    }
+    return remaining - lead
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+    backoff *= 2
+    if backoff > tokenMaxBackoff {
+        backoff = tokenMaxBackoff
+    }
+    return backoff
+}
+//EOF
@@ -0,0 +1,67 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestRefreshDelay(t *testing.T) {
+    cases := map[time.Duration]time.Duration{
+        300 * time.Second:  225 * time.Second,
+        30 * time.Second:   20 * time.Second,
+        5 * time.Second:    0,
+        -time.Second:       0,
+    }
+    for remaining, expected := range cases {
+        delay := refreshDelay(remaining)
+        if delay != expected {
+            t.Error("wrong delay for", remaining, delay)
+        }
+    }
+}
+
+func TestNextBackoff(t *testing.T) {
+    backoff := tokenMinBackoff
+    for i := 0; i < 10; i++ {
+        backoff = nextBackoff(backoff)
+    }
+    if backoff != tokenMaxBackoff {
+        t.Error("backoff not limited", backoff)
+    }
+}
+
+func TestJwtTokenRejected(t *testing.T) {
+    cases := []struct {
+        status      int
+        body        string
+        rejected    bool
+    }{
+        { http.StatusOK,            `{"errors":[{"message":"invalid refresh token"}]}`,     true },
+        { http.StatusOK,            `{"data":{"authAccessToken":{"jwtToken":null}}}`,       true },
+        { http.StatusUnauthorized,  `unauthorized`,                                         true },
+        { http.StatusOK,            `{"errors":[{"message":"statement timeout"}]}`,         false },
+        { http.StatusBadGateway,    `bad gateway`,                                          false },
+        { http.StatusBadRequest,    `bad request`,                                          false },
+    }
+    for _, test := range cases {
+        pg, _ := newTestPixcore(t, func(w http.ResponseWriter, r *http.Request) {
+            w.WriteHeader(test.status)
+            w.Write([]byte(test.body))
+        })
+        _, err := pg.getJwtToken(context.Background(), "token", 60, nil)
+        if err == nil {
+            t.Fatal("error not reported:", test.body)
+        }
+        if errors.Is(err, ErrTokenRejected) != test.rejected {
+            t.Error("wrong rejection for", test.status, test.body, err)
+        }
+    }
+}
+//EOF
@@ -5,6 +5,7 @@
 package pgcore
 
 import (
+    "context"
     "encoding/json"
     "errors"
     
@@ -21,6 +22,10 @@ type GetUserProfileIdRespone struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) GetUserProfileId() (string, error) {
+    return this.GetUserProfileIdCtx(this.pixcoreCtx)
+}
+
+func (this *Pixcore) GetUserProfileIdCtx(ctx context.Context) (string, error) {
     var err error
     var result string
     gqReq := `{
@@ -32,7 +37,7 @@ func (this *Pixcore) GetUserProfileId() (string, error) {
     tmpl := pgtmpl.NewTemplate(gqReq)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
@@ -64,6 +69,10 @@ type GetUserIdByLoginRespone struct {
     Errors  pgerrors.Errors  `json:"errors"`
 }
 func (this *Pixcore) GetUserIdByLogin(login string) (string, error) {
+    return this.GetUserIdByLoginCtx(this.pixcoreCtx, login)
+}
+
+func (this *Pixcore) GetUserIdByLoginCtx(ctx context.Context, login string) (string, error) {
     var err error
     var result string
     //gqReq := `{
@@ -87,7 +96,7 @@ func (this *Pixcore) GetUserIdByLogin(login string) (string, error) {
     //tmpl.SetStrRepl("login", login)
     gqReq = tmpl.Pack()
 
-    httpRespBody, err := this.httpRequest(gqReq)
+    httpRespBody, err := this.httpRequestContext(ctx, gqReq)
     if err != nil {
         return result, err
     }
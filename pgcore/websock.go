@@ -7,6 +7,7 @@ package pgcore
 import (
     "context"
     "encoding/json"
+    "errors"
     "time"
 
     "net/http"
@@ -91,8 +92,74 @@ func (this *Pixcore) GetWsURL() (*url.URL, error) {
 }
 
 const wsReadTimeout time.Duration = 20
+
+var errSubscriptionRenew = errors.New("subscription renew")
 type SubscribeLoopFunc = func()
 //
+// subscription tracks websocket of active subscription, Renew() breaks
+// pending read, the loop terminates old socket and dials again with
+// current jwt
+//
+type subscription struct {
+    conn        *websocket.Conn
+    renew       bool
+    mutex       sync.Mutex
+}
+
+func (this *subscription) setConn(conn *websocket.Conn) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.conn = conn
+}
+
+func (this *subscription) Renew() {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.renew = true
+    if this.conn != nil {
+        this.conn.SetReadDeadline(time.Now())
+    }
+}
+
+func (this *subscription) renewing() bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.renew
+}
+
+func (this *subscription) takeRenew() bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    renew := this.renew
+    this.renew = false
+    return renew
+}
+//
+// RenewSubscriptions() reconnects active subscriptions with current jwt
+//
+func (this *Pixcore) RenewSubscriptions() {
+    this.tokenMutex.Lock()
+    defer this.tokenMutex.Unlock()
+    if len(this.subscrs) > 0 {
+        pmlog.LogInfo("renew", len(this.subscrs), "subscriptions with new jwt")
+    }
+    for subscr := range this.subscrs {
+        subscr.Renew()
+    }
+}
+
+func (this *Pixcore) addSubscription(subscr *subscription) {
+    this.tokenMutex.Lock()
+    defer this.tokenMutex.Unlock()
+    this.subscrs[subscr] = true
+}
+
+func (this *Pixcore) removeSubscription(subscr *subscription) {
+    this.tokenMutex.Lock()
+    defer this.tokenMutex.Unlock()
+    delete(this.subscrs, subscr)
+}
+//
 // Subscribe
 //
 func (this *Pixcore) Subscribe(externalParentCtx context.Context, wg *sync.WaitGroup, gQuery *pgquery.GQuery, dataHandler GwsDataHandlerFunc) (SubscribeLoopFunc, context.CancelFunc, error) {
@@ -106,26 +173,42 @@ func (this *Pixcore) Subscribe(externalParentCtx context.Context, wg *sync.WaitG
         return loopFunc, wsCancel, err
     }
 
-    headers := make(http.Header) 
-    headers.Add("Sec-Websocket-Protocol", "graphql-ws")
-    headers.Add("Authorization", "Bearer " + this.GetJWTToken())
+    dial := func() (*websocket.Conn, error) {
+        headers := make(http.Header)
+        headers.Add("Sec-Websocket-Protocol", "graphql-ws")
+        headers.Add("Authorization", "Bearer " + this.GetJWTToken())
 
-    // Start Level1
-    wsConn, _, err := websocket.DefaultDialer.DialContext(wsCtx, wsRef.String(), headers)
-    if err != nil {
-        return loopFunc, wsCancel, err
-    }
+        // Start Level1
+        wsConn, _, err := websocket.DefaultDialer.DialContext(wsCtx, wsRef.String(), headers)
+        if err != nil {
+            return wsConn, err
+        }
 
-    closeHandler := func(code int, text string) error {
-        var err error
-        pmlog.LogInfo("web socket closed on level1 handler, code:", code, "text:", text)
-        // Send terminate message on Level2, wo control response
-        gMessage := NewGMessage(gwsConnectionTerminate, nil)
+        closeHandler := func(code int, text string) error {
+            var err error
+            pmlog.LogInfo("web socket closed on level1 handler, code:", code, "text:", text)
+            // Send terminate message on Level2, wo control response
+            gMessage := NewGMessage(gwsConnectionTerminate, nil)
+            err = wsConn.WriteJSON(gMessage)
+            wsCancel()
+            return err
+        }
+        wsConn.SetCloseHandler(closeHandler)
+
+        // Start Level2
+        gMessage := NewGMessage(gwsConnectionInit, nil)
         err = wsConn.WriteJSON(gMessage)
-        wsCancel()
-        return err
+        if err != nil {
+            wsConn.Close()
+            return wsConn, err
+        }
+        return wsConn, err
     }
-    wsConn.SetCloseHandler(closeHandler)
+    wsConn, err := dial()
+    if err != nil {
+        return loopFunc, wsCancel, err
+    }
+    subscr := &subscription{ conn: wsConn }
 
     //wsConn.SetPongHandler(func(str string) error {
     //    var err error
@@ -138,17 +221,12 @@ func (this *Pixcore) Subscribe(externalParentCtx context.Context, wg *sync.WaitG
     //    pmlog.LogDebug("#### ping received:", str)
     //    return err
     //})
-    
-    // Start Level2
-    gMessage := NewGMessage(gwsConnectionInit, nil)
-    err = wsConn.WriteJSON(gMessage)
-    if err != nil {
-        return loopFunc, wsCancel, err
-    }
 
     wg.Add(1)
+    this.addSubscription(subscr)
     loopFunc = func() {
         defer wg.Done()
+        defer this.removeSubscription(subscr)
         defer pmlog.LogWarning("subsription done")
         
         for {
@@ -169,7 +247,7 @@ func (this *Pixcore) Subscribe(externalParentCtx context.Context, wg *sync.WaitG
             }
 
             err = wsConn.SetReadDeadline(time.Now().Add(wsReadTimeout * time.Second))
-            if err != nil {
+            if err != nil && !subscr.renewing() {
                 err = wsConn.Close()
                 pmlog.LogInfo("gws close websocket")
                 if err != nil {
@@ -181,10 +259,32 @@ func (this *Pixcore) Subscribe(externalParentCtx context.Context, wg *sync.WaitG
             }
             
             var gMessage GMessage
-            err = wsConn.ReadJSON(&gMessage)
+            if subscr.renewing() {
+                err = errSubscriptionRenew
+            } else {
+                err = wsConn.ReadJSON(&gMessage)
+            }
 
             //pmlog.LogDebug("raw gws message:", gMessage.GetJSON())
 
+            if err != nil && subscr.takeRenew() && wsCtx.Err() == nil {
+                pmlog.LogInfo("gws renew subscription with new jwt")
+                // Send terminate message on Level2, wo control response
+                gMessage := NewGMessage(gwsConnectionTerminate, nil)
+                err = wsConn.WriteJSON(gMessage)
+                if err != nil {
+                    pmlog.LogInfo("gws write connection terminate error:", err)
+                }
+                wsConn.Close()
+                wsConn, err = dial()
+                if err != nil {
+                    pmlog.LogError("gws renew subscription error:", err)
+                    wsCancel()
+                    return
+                }
+                subscr.setConn(wsConn)
+                continue
+            }
             if err != nil {
                 pmlog.LogInfo("gws inloop reading error:", err)
                 //subCancel()
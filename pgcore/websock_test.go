@@ -0,0 +1,83 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgcore
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+
+    "app/pgquery"
+
+    "github.com/gorilla/websocket"
+)
+
+func TestSubscriptionRenew(t *testing.T) {
+    messages := make(chan string, 20)
+    upgrader := websocket.Upgrader{ Subprotocols: []string{ "graphql-ws" } }
+    connection := 0
+    var mutex sync.Mutex
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        conn, err := upgrader.Upgrade(w, r, nil)
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        mutex.Lock()
+        connection += 1
+        current := connection
+        mutex.Unlock()
+        for {
+            var message GMessage
+            err = conn.ReadJSON(&message)
+            if err != nil {
+                return
+            }
+            messages <- string(rune('0' + current)) + message.Type
+            if message.Type == gwsConnectionInit {
+                conn.WriteJSON(NewGMessage(gwsConnectionAck, nil))
+            }
+        }
+    }))
+    defer server.Close()
+
+    pg := New(context.Background())
+    err := pg.Setup(server.URL + "/graphql", "user", "password", 1, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    var wg sync.WaitGroup
+    loop, _, err := pg.Subscribe(ctx, &wg, pgquery.NewGQuery(), func(string) error { return nil })
+    if err != nil {
+        t.Fatal(err)
+    }
+    go loop()
+
+    expect := func(expected string) {
+        select {
+            case message := <-messages:
+                if message != expected {
+                    t.Fatal("wrong message:", message, "expected:", expected)
+                }
+            case <-time.After(5 * time.Second):
+                t.Fatal("message not received:", expected)
+        }
+    }
+    expect("1" + gwsConnectionInit)
+    expect("1" + gwsStart)
+    pg.RenewSubscriptions()
+    expect("1" + gwsConnectionTerminate)
+    expect("2" + gwsConnectionInit)
+    expect("2" + gwsStart)
+
+    cancel()
+    pg.RenewSubscriptions()
+    wg.Wait()
+}
+//EOF
@@ -34,26 +34,34 @@ func (this *Errors) ToJsonIndent() string {
 }
 
 func (this *Errors) GetMessages() string {
-    var messages string
+    messages := make([]string, 0, len(*this))
     for _, gqError := range *this {
-        messages = ";" + gqError.Message
+        messages = append(messages, gqError.GetMessages())
     }
-    messages = strings.Replace(messages, ";", "", 1)
-    messages = strings.Replace(messages, `\\"`, `"`, -1)
-    return messages
+    return strings.Join(messages, "; ")
 }
 
 
 func (this *Errors) GetStack() string {
-    var messages string
+    stacks := make([]string, 0, len(*this))
     for _, gqError := range *this {
-        messages = "\n" + gqError.Stack
+        stacks = append(stacks, gqError.Stack)
     }
-    messages = strings.Replace(messages, "\n", "", 1)
-    //messages = strings.Replace(messages, `\\"`, `"`, -1)
-    return messages
+    return strings.Join(stacks, "\n")
 }
 
+//
+// Unauthorized() reports authorization error, sqlstate class 28
+// or insufficient privilege, or message about invalid or expired token
+//
+func (this *Errors) Unauthorized() bool {
+    for i := range *this {
+        if (*this)[i].Unauthorized() {
+            return true
+        }
+    }
+    return false
+}
 
 type Error struct {
     Code        string      `json:"code,omitempty"`
@@ -91,6 +99,20 @@ func (this *Error) ToJsonIndent() string {
     return string(json)
 }
 
+func (this *Error) Unauthorized() bool {
+    for _, code := range []string{ this.Code, this.Extensions.Exception.Code } {
+        if strings.HasPrefix(code, "28") || code == "42501" {
+            return true
+        }
+    }
+    message := strings.ToLower(this.Message)
+    if !strings.Contains(message, "token") && !strings.Contains(message, "jwt") {
+        return false
+    }
+    return strings.Contains(message, "invalid") || strings.Contains(message, "expired") ||
+        strings.Contains(message, "not found")
+}
+
 func (this *Error) GetMessages() string {
     return strings.Replace(this.Message, `\\"`, `"`, -1)
 }
@@ -0,0 +1,43 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgerrors
+
+import (
+    "encoding/json"
+    "testing"
+)
+
+func TestGetMessages(t *testing.T) {
+    errors := Errors{
+        Error{ Message: "first" },
+        Error{ Message: "second" },
+    }
+    messages := errors.GetMessages()
+    if messages != "first; second" {
+        t.Error("wrong messages:", messages)
+    }
+}
+
+func TestUnauthorized(t *testing.T) {
+    cases := map[string]bool{
+        `[{"message":"invalid refresh token"}]`:                                 true,
+        `[{"message":"jwt expired"}]`:                                           true,
+        `[{"message":"denied","extensions":{"exception":{"code":"28000"}}}]`:   true,
+        `[{"message":"permission denied","code":"42501"}]`:                     true,
+        `[{"message":"canceling statement due to statement timeout"}]`:         false,
+        `[{"message":"connection terminated","code":"57P01"}]`:                 false,
+    }
+    for data, expected := range cases {
+        var errors Errors
+        err := json.Unmarshal([]byte(data), &errors)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if errors.Unauthorized() != expected {
+            t.Error("wrong result for", data)
+        }
+    }
+}
+//EOF
@@ -0,0 +1,348 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgjwt
+
+import (
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/hmac"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "math/big"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    _ "crypto/sha256"
+    _ "crypto/sha512"
+)
+
+const (
+    jwksTimeout         time.Duration   = 10    // sec
+    jwksRefetchDelay    time.Duration   = 60    // sec
+)
+
+type JwtHeader struct {
+    Alg     string  `json:"alg"`
+    Kid     string  `json:"kid,omitempty"`
+    Typ     string  `json:"typ,omitempty"`
+}
+//
+// KeySet
+//
+// KeySet holds keys for signature verification by key id, key without
+// id is used for tokens without kid or with kid not in set. Keys are []byte for HS*, *rsa.PublicKey
+// for RS* and *ecdsa.PublicKey for ES* algorithms. With JWKS url unknown
+// key id causes refetch of key set, not often than once per minute.
+//
+type KeySet struct {
+    keys        map[string]interface{}
+    jwksURL     string
+    fetched     time.Time
+    mutex       sync.RWMutex
+}
+
+func NewKeySet() *KeySet {
+    return &KeySet{
+        keys:   make(map[string]interface{}),
+    }
+}
+
+func (this *KeySet) AddKey(kid string, key interface{}) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.keys[kid] = key
+}
+
+func (this *KeySet) AddSecret(secret string) {
+    this.AddKey("", []byte(secret))
+}
+//
+// AddPEM() adds PEM encoded RSA or EC public key or certificate
+//
+func (this *KeySet) AddPEM(data []byte) error {
+    var err error
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return errors.New("jwt key: no pem data")
+    }
+    var key interface{}
+    switch block.Type {
+        case "CERTIFICATE":
+            cert, err := x509.ParseCertificate(block.Bytes)
+            if err != nil {
+                return err
+            }
+            key = cert.PublicKey
+        case "RSA PUBLIC KEY":
+            key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+        default:
+            key, err = x509.ParsePKIXPublicKey(block.Bytes)
+    }
+    if err != nil {
+        return err
+    }
+    switch key.(type) {
+        case *rsa.PublicKey, *ecdsa.PublicKey:
+        default:
+            return errors.New("jwt key: unsupported public key type")
+    }
+    this.AddKey("", key)
+    return err
+}
+
+type jwkKey struct {
+    Kty     string  `json:"kty"`
+    Kid     string  `json:"kid"`
+    Use     string  `json:"use"`
+    N       string  `json:"n"`
+    E       string  `json:"e"`
+    Crv     string  `json:"crv"`
+    X       string  `json:"x"`
+    Y       string  `json:"y"`
+    K       string  `json:"k"`
+}
+
+type jwkSet struct {
+    Keys    []jwkKey    `json:"keys"`
+}
+//
+// AddJWKS() adds signing keys of JSON Web Key Set document
+//
+func (this *KeySet) AddJWKS(data []byte) error {
+    var err error
+    var set jwkSet
+    err = json.Unmarshal(data, &set)
+    if err != nil {
+        return err
+    }
+    for _, jwk := range set.Keys {
+        if len(jwk.Use) > 0 && jwk.Use != "sig" {
+            continue
+        }
+        key, err := jwk.publicKey()
+        if err != nil {
+            return fmt.Errorf("jwks key %s: %s", jwk.Kid, err)
+        }
+        this.AddKey(jwk.Kid, key)
+    }
+    return err
+}
+//
+// SetJWKS() sets key set url, keys are fetched on first use and
+// refetched on key rotation
+//
+func (this *KeySet) SetJWKS(url string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.jwksURL = url
+}
+
+func (this *KeySet) fetchJWKS() error {
+    var err error
+    this.mutex.Lock()
+    url := this.jwksURL
+    this.fetched = time.Now()
+    this.mutex.Unlock()
+
+    httpClient := &http.Client{ Timeout: jwksTimeout * time.Second }
+    httpResp, err := httpClient.Get(url)
+    if err != nil {
+        return err
+    }
+    defer httpResp.Body.Close()
+    if httpResp.StatusCode != http.StatusOK {
+        return fmt.Errorf("jwks: %s: %s", url, httpResp.Status)
+    }
+    data, err := ioutil.ReadAll(httpResp.Body)
+    if err != nil {
+        return err
+    }
+    return this.AddJWKS(data)
+}
+
+func (this *KeySet) getKey(kid string) (interface{}, error) {
+    var err error
+    this.mutex.RLock()
+    key, exists := this.keys[kid]
+    if !exists && len(this.jwksURL) == 0 {
+        key, exists = this.keys[""]
+    }
+    refetch := len(this.jwksURL) > 0 && time.Since(this.fetched) > jwksRefetchDelay * time.Second
+    this.mutex.RUnlock()
+    if exists {
+        return key, err
+    }
+    if refetch {
+        err = this.fetchJWKS()
+        if err != nil {
+            return nil, err
+        }
+        this.mutex.RLock()
+        key, exists = this.keys[kid]
+        this.mutex.RUnlock()
+        if exists {
+            return key, err
+        }
+    }
+    return nil, fmt.Errorf("jwt: unknown key id %q", kid)
+}
+
+func (this *jwkKey) publicKey() (interface{}, error) {
+    switch this.Kty {
+        case "RSA":
+            n, err := decodeSegment(this.N)
+            if err != nil {
+                return nil, err
+            }
+            e, err := decodeSegment(this.E)
+            if err != nil {
+                return nil, err
+            }
+            return &rsa.PublicKey{
+                N:  new(big.Int).SetBytes(n),
+                E:  int(new(big.Int).SetBytes(e).Int64()),
+            }, err
+        case "EC":
+            var curve elliptic.Curve
+            switch this.Crv {
+                case "P-256":
+                    curve = elliptic.P256()
+                case "P-384":
+                    curve = elliptic.P384()
+                case "P-521":
+                    curve = elliptic.P521()
+                default:
+                    return nil, fmt.Errorf("unsupported curve %s", this.Crv)
+            }
+            x, err := decodeSegment(this.X)
+            if err != nil {
+                return nil, err
+            }
+            y, err := decodeSegment(this.Y)
+            if err != nil {
+                return nil, err
+            }
+            return &ecdsa.PublicKey{
+                Curve:  curve,
+                X:      new(big.Int).SetBytes(x),
+                Y:      new(big.Int).SetBytes(y),
+            }, err
+        case "oct":
+            return decodeSegment(this.K)
+    }
+    return nil, fmt.Errorf("unsupported key type %s", this.Kty)
+}
+//
+// Verify() parses token and checks signature and expiration
+//
+func Verify(token string, keys *KeySet) (*JWT, error) {
+    var err error
+    jwt, err := Parse(token)
+    if err != nil {
+        return jwt, err
+    }
+    tokenParts := strings.SplitN(token, ".", 3)
+    headerBytes, err := decodeSegment(tokenParts[0])
+    if err != nil {
+        return jwt, err
+    }
+    var header JwtHeader
+    err = json.Unmarshal(headerBytes, &header)
+    if err != nil {
+        return jwt, err
+    }
+    signature, err := decodeSegment(tokenParts[2])
+    if err != nil {
+        return jwt, err
+    }
+    key, err := keys.getKey(header.Kid)
+    if err != nil {
+        return jwt, err
+    }
+    err = verifySignature(header.Alg, tokenParts[0] + "." + tokenParts[1], signature, key)
+    if err != nil {
+        return jwt, err
+    }
+    if jwt.Payload.Exp > 0 && jwt.Payload.Exp <= time.Now().Unix() {
+        return jwt, errors.New("jwt: token expired")
+    }
+    return jwt, err
+}
+
+var algHashes = map[string]crypto.Hash{
+    "256":  crypto.SHA256,
+    "384":  crypto.SHA384,
+    "512":  crypto.SHA512,
+}
+
+func verifySignature(alg string, signed string, signature []byte, key interface{}) error {
+    var err error
+    if len(alg) != 5 {
+        return fmt.Errorf("jwt: unsupported algorithm %q", alg)
+    }
+    hash, exists := algHashes[alg[2:]]
+    if !exists {
+        return fmt.Errorf("jwt: unsupported algorithm %q", alg)
+    }
+    wrongKey := fmt.Errorf("jwt: key does not match algorithm %s", alg)
+
+    switch alg[:2] {
+        case "HS":
+            secret, ok := key.([]byte)
+            if !ok {
+                return wrongKey
+            }
+            mac := hmac.New(hash.New, secret)
+            mac.Write([]byte(signed))
+            if !hmac.Equal(mac.Sum(nil), signature) {
+                return errors.New("jwt: wrong signature")
+            }
+            return err
+        case "RS":
+            publicKey, ok := key.(*rsa.PublicKey)
+            if !ok {
+                return wrongKey
+            }
+            hasher := hash.New()
+            hasher.Write([]byte(signed))
+            err = rsa.VerifyPKCS1v15(publicKey, hash, hasher.Sum(nil), signature)
+            if err != nil {
+                return errors.New("jwt: wrong signature")
+            }
+            return err
+        case "ES":
+            publicKey, ok := key.(*ecdsa.PublicKey)
+            if !ok {
+                return wrongKey
+            }
+            size := (publicKey.Curve.Params().BitSize + 7) / 8
+            if len(signature) != 2 * size {
+                return errors.New("jwt: wrong signature size")
+            }
+            r := new(big.Int).SetBytes(signature[:size])
+            s := new(big.Int).SetBytes(signature[size:])
+            hasher := hash.New()
+            hasher.Write([]byte(signed))
+            if !ecdsa.Verify(publicKey, hasher.Sum(nil), r, s) {
+                return errors.New("jwt: wrong signature")
+            }
+            return err
+    }
+    return fmt.Errorf("jwt: unsupported algorithm %q", alg)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+    return base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+}
+//EOF
@@ -0,0 +1,92 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgjwt
+
+import (
+    "crypto"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "fmt"
+    "math/big"
+    "strings"
+    "testing"
+    "time"
+)
+
+func encodeSegment(data string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(data))
+}
+
+func unsignedToken(alg string, kid string, exp int64) string {
+    header  := fmt.Sprintf(`{"alg":"%s","kid":"%s","typ":"JWT"}`, alg, kid)
+    payload := fmt.Sprintf(`{"user_id":"u1","exp":%d}`, exp)
+    return encodeSegment(header) + "." + encodeSegment(payload)
+}
+
+func TestVerifyHMAC(t *testing.T) {
+    keys := NewKeySet()
+    keys.AddSecret("core secret")
+
+    signed := unsignedToken("HS256", "", time.Now().Unix() + 60)
+    mac := hmac.New(sha256.New, []byte("core secret"))
+    mac.Write([]byte(signed))
+    token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+    jwt, err := Verify(token, keys)
+    if err != nil || jwt.Payload.UserID != "u1" {
+        t.Fatal("valid token rejected:", err)
+    }
+
+    other := NewKeySet()
+    other.AddSecret("other secret")
+    _, err = Verify(token, other)
+    if err == nil {
+        t.Error("token with wrong key accepted")
+    }
+}
+
+func TestVerifyJWKS(t *testing.T) {
+    privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatal(err)
+    }
+    jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"k1","use":"sig","n":"%s","e":"%s"}]}`,
+                base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+                base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()))
+    keys := NewKeySet()
+    err = keys.AddJWKS([]byte(jwks))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    sign := func(signed string) string {
+        digest := sha256.Sum256([]byte(signed))
+        signature, _ := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+        return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+    }
+
+    _, err = Verify(sign(unsignedToken("RS256", "k1", time.Now().Unix() + 60)), keys)
+    if err != nil {
+        t.Error("valid token rejected:", err)
+    }
+    _, err = Verify(sign(unsignedToken("RS256", "k1", time.Now().Unix() - 1)), keys)
+    if err == nil {
+        t.Error("expired token accepted")
+    }
+    _, err = Verify(sign(unsignedToken("RS256", "k2", time.Now().Unix() + 60)), keys)
+    if err == nil {
+        t.Error("token with unknown key id accepted")
+    }
+    valid := sign(unsignedToken("RS256", "k1", time.Now().Unix() + 60))
+    tampered := unsignedToken("RS256", "k1", time.Now().Unix() + 3600) + valid[strings.LastIndex(valid, "."):]
+    _, err = Verify(tampered, keys)
+    if err == nil {
+        t.Error("tampered token accepted")
+    }
+}
+//EOF
@@ -0,0 +1,193 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgschema
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strings"
+)
+
+var propertyTypes = map[PropertyType]bool{
+    HexType:        true,
+    BoolType:       true,
+    StringType:     true,
+    FloatType:      true,
+    IntType:        true,
+    DoubleType:     true,
+}
+
+var metadataTypes = map[string]bool{
+    MetadataTypeDevice: true,
+    MetadataTypeApp:    true,
+}
+//
+// Builder
+//
+// Builder declares schema in code. Control() adds rpc entry, Argument()
+// adds its arguments, both return the entry for optional fields, e.g.
+//
+//      builder.Argument("SetTopics", "qos", IntType, "QoS").DefaultValue = "0"
+//
+type Builder struct {
+    schema      *Schema
+}
+
+func NewBuilder(metadata *Metadata) *Builder {
+    schema := NewSchema()
+    schema.Metadata = metadata
+    return &Builder{
+        schema:     schema,
+    }
+}
+
+func (this *Builder) Property(name string, propertyType PropertyType, group string, description string) *Property {
+    property := NewProperty()
+    property.Property       = name
+    property.Type           = propertyType
+    property.GroupName      = group
+    property.Description    = description
+    this.AddProperty(property)
+    return property
+}
+
+func (this *Builder) Control(rpc string, description string) *Control {
+    return this.Argument(rpc, rpc, StringType, description)
+}
+
+func (this *Builder) Argument(rpc string, argument string, argumentType PropertyType, description string) *Control {
+    control := NewControl()
+    control.RPC             = rpc
+    control.Argument        = argument
+    control.Type            = argumentType
+    control.Description     = description
+    this.AddControl(control)
+    return control
+}
+//
+// AddProperty() and AddControl() add entries made by helper functions
+//
+func (this *Builder) AddProperty(properties ...*Property) *Builder {
+    this.schema.Properties = append(this.schema.Properties, properties...)
+    return this
+}
+
+func (this *Builder) AddControl(controls ...*Control) *Builder {
+    this.schema.Controls = append(this.schema.Controls, controls...)
+    return this
+}
+//
+// Build() validates and returns schema
+//
+func (this *Builder) Build() (*Schema, error) {
+    return this.schema, this.schema.Validate()
+}
+//
+// Validate() checks metadata, property names and types, rpc arguments
+//
+func (this *Schema) Validate() error {
+    var err error
+    problems := make([]string, 0)
+    report := func(format string, args ...interface{}) {
+        problems = append(problems, fmt.Sprintf(format, args...))
+    }
+
+    if this.Metadata == nil {
+        return errors.New("schema: metadata not defined")
+    }
+    if len(this.Metadata.Id) == 0 {
+        report("schema id empty")
+    }
+    if len(this.Metadata.Name) == 0 {
+        report("schema name empty")
+    }
+    if len(this.Metadata.MVersion) == 0 {
+        report("schema version empty")
+    }
+    if !metadataTypes[this.Metadata.Type] {
+        report("schema type %q invalid", this.Metadata.Type)
+    }
+
+    properties := make(map[string]bool)
+    for _, property := range this.Properties {
+        if len(property.Property) == 0 {
+            report("property with empty name")
+            continue
+        }
+        if properties[property.Property] {
+            report("property %s duplicated", property.Property)
+        }
+        properties[property.Property] = true
+        if !propertyTypes[property.Type] {
+            report("property %s type %q invalid", property.Property, property.Type)
+        }
+    }
+
+    rpcs := make(map[string]bool)
+    arguments := make(map[string]bool)
+    for _, control := range this.Controls {
+        if len(control.RPC) == 0 || len(control.Argument) == 0 {
+            report("control %q argument %q: empty rpc or argument", control.RPC, control.Argument)
+            continue
+        }
+        key := control.RPC + "." + control.Argument
+        if arguments[key] {
+            report("control %s argument %s duplicated", control.RPC, control.Argument)
+        }
+        arguments[key] = true
+        if control.Argument == control.RPC {
+            rpcs[control.RPC] = true
+        }
+        if !propertyTypes[control.Type] {
+            report("control %s argument %s type %q invalid", control.RPC, control.Argument, control.Type)
+        }
+    }
+    for _, control := range this.Controls {
+        if len(control.RPC) > 0 && !rpcs[control.RPC] {
+            report("control %s has arguments but no rpc entry", control.RPC)
+            rpcs[control.RPC] = true
+        }
+    }
+
+    if len(problems) > 0 {
+        return errors.New("schema " + this.Metadata.Name + ": " + strings.Join(problems, "; "))
+    }
+    return err
+}
+//
+// GetFileJSON() returns schema as written to schema files
+//
+func (this *Schema) GetFileJSON() []byte {
+    jsonBytes, _ := json.MarshalIndent(this, "", "    ")
+    return append(jsonBytes, '\n')
+}
+//
+// WriteFile() writes schema file, returns false if file was up to date
+//
+func (this *Schema) WriteFile(fileName string) (bool, error) {
+    upToDate, err := this.FileUpToDate(fileName)
+    if err != nil || upToDate {
+        return false, err
+    }
+    return true, ioutil.WriteFile(fileName, this.GetFileJSON(), 0644)
+}
+//
+// FileUpToDate() compares schema with schema file
+//
+func (this *Schema) FileUpToDate(fileName string) (bool, error) {
+    data, err := ioutil.ReadFile(fileName)
+    if os.IsNotExist(err) {
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+    return bytes.Equal(data, this.GetFileJSON()), err
+}
+//EOF
@@ -0,0 +1,47 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgschema
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestBuilder(t *testing.T) {
+    metadata := NewMetadata()
+    metadata.Id         = "6a34e442-cc3c-4586-853e-9058e1fd7739"
+    metadata.Name       = "Test Device"
+    metadata.Type       = MetadataTypeDevice
+    metadata.MVersion   = "1.0"
+
+    builder := NewBuilder(metadata)
+    builder.Property("TopicBase", StringType, "Credentials", "Topic base")
+    builder.Control("SendCommand", "Send command")
+    builder.Argument("SendCommand", "command", StringType, "Command")
+    schema, err := builder.Build()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(schema.Properties) != 1 || len(schema.Controls) != 2 || schema.Controls[0].Argument != "SendCommand" {
+        t.Error("wrong schema", schema.GetJSON())
+    }
+
+    builder.Property("TopicBase", "text", "Credentials", "Topic base")
+    builder.Argument("SendCommand", "command", StringType, "Command")
+    builder.Argument("Reboot", "delay", IntType, "Delay")
+    _, err = builder.Build()
+    if err == nil {
+        t.Fatal("wrong schema accepted")
+    }
+    for _, problem := range []string{
+            "property TopicBase duplicated",
+            `type "text" invalid`,
+            "control SendCommand argument command duplicated",
+            "control Reboot has arguments but no rpc entry" } {
+        if !strings.Contains(err.Error(), problem) {
+            t.Error("problem not reported:", problem)
+        }
+    }
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgschema
+
+import (
+    "sort"
+    "strings"
+)
+//
+// Rename
+//
+// Rename keeps object property value when schema property changes name.
+//
+type Rename struct {
+    From        string      `json:"from"`
+    To          string      `json:"to"`
+}
+//
+// Changes
+//
+// Changes lists difference between deployed and new schema. Controls
+// are named by rpc, control arguments as rpc.argument.
+//
+type Changes struct {
+    FromVersion         string      `json:"fromVersion"`
+    ToVersion           string      `json:"toVersion"`
+    AddedProperties     []string    `json:"addedProperties"`
+    RemovedProperties   []string    `json:"removedProperties"`
+    RenamedProperties   []Rename    `json:"renamedProperties"`
+    AddedControls       []string    `json:"addedControls"`
+    RemovedControls     []string    `json:"removedControls"`
+}
+
+func (this *Changes) Empty() bool {
+    return len(this.AddedProperties) == 0 && len(this.RemovedProperties) == 0 &&
+        len(this.RenamedProperties) == 0 &&
+        len(this.AddedControls) == 0 && len(this.RemovedControls) == 0
+}
+//
+// String() gives short report, e.g. "1.40 -> 1.41: +Foo -Bar Baz>Qux"
+//
+func (this *Changes) String() string {
+    items := make([]string, 0)
+    for _, name := range this.AddedProperties {
+        items = append(items, "+" + name)
+    }
+    for _, name := range this.RemovedProperties {
+        items = append(items, "-" + name)
+    }
+    for _, rename := range this.RenamedProperties {
+        items = append(items, rename.From + ">" + rename.To)
+    }
+    for _, name := range this.AddedControls {
+        items = append(items, "+" + name + "()")
+    }
+    for _, name := range this.RemovedControls {
+        items = append(items, "-" + name + "()")
+    }
+    report := this.FromVersion + " -> " + this.ToVersion
+    if len(items) > 0 {
+        report += ": " + strings.Join(items, " ")
+    }
+    return report
+}
+//
+// Diff() compares deployed schema with new one, renames which are
+// applicable to the pair are reported instead of add and remove
+//
+func Diff(deployed *Schema, current *Schema, renames []Rename) *Changes {
+    changes := &Changes{
+        AddedProperties:    make([]string, 0),
+        RemovedProperties:  make([]string, 0),
+        RenamedProperties:  make([]Rename, 0),
+        AddedControls:      make([]string, 0),
+        RemovedControls:    make([]string, 0),
+    }
+    if deployed.Metadata != nil {
+        changes.FromVersion = deployed.Metadata.MVersion
+    }
+    if current.Metadata != nil {
+        changes.ToVersion = current.Metadata.MVersion
+    }
+
+    oldProperties := propertyNames(deployed)
+    newProperties := propertyNames(current)
+    for _, rename := range renames {
+        if oldProperties[rename.From] && !newProperties[rename.From] &&
+                newProperties[rename.To] && !oldProperties[rename.To] {
+            changes.RenamedProperties = append(changes.RenamedProperties, rename)
+            delete(oldProperties, rename.From)
+            delete(newProperties, rename.To)
+        }
+    }
+    changes.AddedProperties     = missing(newProperties, oldProperties)
+    changes.RemovedProperties   = missing(oldProperties, newProperties)
+
+    oldControls := controlNames(deployed)
+    newControls := controlNames(current)
+    changes.AddedControls       = missing(newControls, oldControls)
+    changes.RemovedControls     = missing(oldControls, newControls)
+    return changes
+}
+
+func propertyNames(schema *Schema) map[string]bool {
+    names := make(map[string]bool)
+    for _, property := range schema.Properties {
+        names[property.Property] = true
+    }
+    return names
+}
+
+func controlNames(schema *Schema) map[string]bool {
+    names := make(map[string]bool)
+    for _, control := range schema.Controls {
+        name := control.RPC
+        if len(control.Argument) > 0 {
+            name += "." + control.Argument
+        }
+        names[name] = true
+    }
+    return names
+}
+//
+// missing() returns sorted names of source absent in target
+//
+func missing(source map[string]bool, target map[string]bool) []string {
+    result := make([]string, 0)
+    for name := range source {
+        if !target[name] {
+            result = append(result, name)
+        }
+    }
+    sort.Strings(result)
+    return result
+}
+//EOF
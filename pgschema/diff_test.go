@@ -0,0 +1,45 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pgschema
+
+import (
+    "testing"
+)
+
+func newTestSchema(version string, properties []string, controls [][2]string) *Schema {
+    schema := NewSchema()
+    schema.Metadata.MVersion = version
+    for _, name := range properties {
+        property := NewProperty()
+        property.Property = name
+        schema.Properties = append(schema.Properties, property)
+    }
+    for _, item := range controls {
+        control := NewControl()
+        control.RPC         = item[0]
+        control.Argument    = item[1]
+        schema.Controls = append(schema.Controls, control)
+    }
+    return schema
+}
+
+func TestDiff(t *testing.T) {
+    deployed := newTestSchema("1.40",
+        []string{ "Status", "TopicBase", "Legacy" },
+        [][2]string{ { "Reload", "" }, { "SetTopics", "topics" } })
+    current := newTestSchema("1.41",
+        []string{ "Status", "BaseTopic", "Timeout" },
+        [][2]string{ { "Reload", "" }, { "SetTopics", "topics" }, { "SetTopics", "qos" } })
+    renames := []Rename{ { From: "TopicBase", To: "BaseTopic" }, { From: "Missing", To: "Timeout" } }
+
+    changes := Diff(deployed, current, renames)
+    report := "1.40 -> 1.41: +Timeout -Legacy TopicBase>BaseTopic +SetTopics.qos()"
+    if changes.String() != report {
+        t.Error("wrong changes", changes.String())
+    }
+    if Diff(current, current, renames).Empty() != true {
+        t.Error("same schema has changes")
+    }
+}
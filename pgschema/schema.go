@@ -95,6 +95,10 @@ const (
     IntType     PropertyType = "int"
     DoubleType  PropertyType = "double"
 )
+//
+// PasswordMask marks property or argument value as secret for UI
+//
+const PasswordMask string = "password"
 
 type Property struct {
     Property         string     `json:"property,omitempty"`
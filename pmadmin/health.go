@@ -0,0 +1,89 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmadmin
+
+import (
+    "fmt"
+    "net/http"
+    "sync/atomic"
+    "time"
+)
+
+const (
+    DefaultLiveWindow   int     = 60 // sec
+)
+//
+// Health
+//
+// Health answers probes. Liveness fails when main loop has not beat
+// within window, readiness asks the bridge by ready function.
+//
+type Health struct {
+    window      int64
+    lastBeat    int64
+    ready       func() error
+}
+
+func NewHealth(window int, ready func() error) *Health {
+    if window <= 0 {
+        window = DefaultLiveWindow
+    }
+    return &Health{
+        window:     int64(window),
+        lastBeat:   time.Now().Unix(),
+        ready:      ready,
+    }
+}
+//
+// Beat() marks main loop progress
+//
+func (this *Health) Beat() {
+    atomic.StoreInt64(&this.lastBeat, time.Now().Unix())
+}
+
+func (this *Health) Live() error {
+    var err error
+    silence := time.Now().Unix() - atomic.LoadInt64(&this.lastBeat)
+    if silence > this.window {
+        return fmt.Errorf("main loop stalled for %d sec", silence)
+    }
+    return err
+}
+
+func (this *Health) Ready() error {
+    var err error
+    err = this.Live()
+    if err != nil {
+        return err
+    }
+    if this.ready == nil {
+        return err
+    }
+    return this.ready()
+}
+
+func (this *Health) LiveHandler(writer http.ResponseWriter, request *http.Request) {
+    probeResponse(writer, this.Live())
+}
+
+func (this *Health) ReadyHandler(writer http.ResponseWriter, request *http.Request) {
+    probeResponse(writer, this.Ready())
+}
+
+func probeResponse(writer http.ResponseWriter, err error) {
+    if err != nil {
+        writeError(writer, http.StatusServiceUnavailable, err)
+        return
+    }
+    writeJSON(writer, http.StatusOK, map[string]string{ "status": "ok" })
+}
+//
+// Register() adds probe endpoints to server
+//
+func (this *Health) Register(server *Server) {
+    server.Handle("/healthz", this.LiveHandler)
+    server.Handle("/readyz",  this.ReadyHandler)
+}
+//EOF
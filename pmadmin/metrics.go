@@ -0,0 +1,70 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmadmin
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+)
+
+const (
+    DefaultMetricsPath  string  = "/metrics"
+    metricsPrefix       string  = "pmbri_"
+)
+//
+// Metrics
+//
+// Metrics exposes bridge counters in prometheus text format,
+// counter names come from source function on each scrape.
+//
+type Metrics struct {
+    source      func() map[string]int64
+}
+
+func NewMetrics(source func() map[string]int64) *Metrics {
+    return &Metrics{
+        source:     source,
+    }
+}
+
+func (this *Metrics) Handler(writer http.ResponseWriter, request *http.Request) {
+    if !allowMethod(writer, request, http.MethodGet) {
+        return
+    }
+    values := this.source()
+    names := make([]string, 0, len(values))
+    for name := range values {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var builder strings.Builder
+    for _, name := range names {
+        fmt.Fprintf(&builder, "%s%s %d\n", metricsPrefix, name, values[name])
+    }
+    writer.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    writer.WriteHeader(http.StatusOK)
+    writer.Write([]byte(builder.String()))
+}
+//
+// Register() adds metrics endpoint to server
+//
+func (this *Metrics) Register(server *Server, path string) {
+    if len(path) == 0 {
+        path = DefaultMetricsPath
+    }
+    server.Handle(path, this.Handler)
+}
+//
+// NewMetricsServer() serves metrics endpoint only
+//
+func NewMetricsServer(listen string, path string, metrics *Metrics) *Server {
+    server := newServer(listen)
+    metrics.Register(server, path)
+    return server
+}
+//EOF
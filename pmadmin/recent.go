@@ -0,0 +1,83 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmadmin
+
+import (
+    "sync"
+    "time"
+)
+
+const (
+    DefaultRecentSize   int     = 50
+)
+//
+// Message
+//
+// Message describes handled uplink, payload itself is not kept.
+//
+type Message struct {
+    Time        string      `json:"time"`
+    Topic       string      `json:"topic"`
+    TopicBase   string      `json:"topicBase,omitempty"`
+    Size        int         `json:"size"`
+    Result      string      `json:"result"`
+}
+//
+// Recent
+//
+// Recent is ring of last handled messages.
+//
+type Recent struct {
+    messages    []Message
+    next        int
+    full        bool
+    mutex       sync.Mutex
+}
+
+func NewRecent(size int) *Recent {
+    if size <= 0 {
+        size = DefaultRecentSize
+    }
+    return &Recent{
+        messages:   make([]Message, size),
+    }
+}
+
+func (this *Recent) Add(topic string, topicBase string, size int, result string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    this.messages[this.next] = Message{
+        Time:       time.Now().UTC().Format(time.RFC3339),
+        Topic:      topic,
+        TopicBase:  topicBase,
+        Size:       size,
+        Result:     result,
+    }
+    this.next += 1
+    if this.next == len(this.messages) {
+        this.next = 0
+        this.full = true
+    }
+}
+//
+// List() returns messages from newest to oldest
+//
+func (this *Recent) List() []Message {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    count := this.next
+    if this.full {
+        count = len(this.messages)
+    }
+    result := make([]Message, 0, count)
+    for i := 1; i <= count; i++ {
+        index := (this.next - i + len(this.messages)) % len(this.messages)
+        result = append(result, this.messages[index])
+    }
+    return result
+}
+//EOF
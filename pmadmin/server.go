@@ -0,0 +1,261 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmadmin
+
+import (
+    "context"
+    "crypto/subtle"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net"
+    "net/http"
+    "strings"
+    "time"
+
+    "app/pmlog"
+)
+
+const (
+    readTimeout     time.Duration   = 10 // sec
+    writeTimeout    time.Duration   = 60 // sec
+    maxBodySize     int64           = 1024 * 1024
+)
+//
+// Status
+//
+type Status struct {
+    ObjectId        string      `json:"objectId"`
+    CoreBound       bool        `json:"coreBound"`
+    JWTExpire       int64       `json:"jwtExpire"`         // epoch
+    Transport       bool        `json:"transportConnected"`
+    BrokerURL       string      `json:"brokerUrl"`
+    Subscription    bool        `json:"controlSubscription"`
+}
+//
+// Backend
+//
+// Backend is the bridge side of admin API. Control() runs the same
+// controller code as core control execution with params json.
+//
+type Backend interface {
+    Status()            Status
+    Topics()            []string
+    Messages()          []Message
+    Queues()            map[string]int64
+    Config()            interface{}
+    Control(name string, params string) error
+}
+
+const (
+    ControlPublish      string  = "publish"
+    ControlReload       string  = "reload"
+    ControlSetTopics    string  = "topics"
+)
+//
+// Server
+//
+// Server with backend serves admin API, API endpoints require bearer
+// token when it is set. Admin server without token starts on loopback
+// address only.
+//
+type Server struct {
+    listen      string
+    token       string
+    backend     Backend
+    mux         *http.ServeMux
+    server      *http.Server
+}
+
+func newServer(listen string) *Server {
+    return &Server{
+        listen:     listen,
+        mux:        http.NewServeMux(),
+    }
+}
+
+func NewServer(listen string, token string, backend Backend) *Server {
+    server := newServer(listen)
+    server.token    = token
+    server.backend  = backend
+    server.mux.HandleFunc("/api/status",    server.authorized(server.statusHandler))
+    server.mux.HandleFunc("/api/topics",    server.authorized(server.topicsHandler))
+    server.mux.HandleFunc("/api/messages",  server.authorized(server.messagesHandler))
+    server.mux.HandleFunc("/api/queues",    server.authorized(server.queuesHandler))
+    server.mux.HandleFunc("/api/config",    server.authorized(server.configHandler))
+    server.mux.HandleFunc("/api/publish",   server.authorized(server.controlHandler(ControlPublish)))
+    server.mux.HandleFunc("/api/reload",    server.authorized(server.controlHandler(ControlReload)))
+    return server
+}
+//
+// IsLoopback() reports listen address bound to loopback interface only
+//
+func IsLoopback(listen string) bool {
+    host, _, err := net.SplitHostPort(listen)
+    if err != nil {
+        return false
+    }
+    if host == "localhost" {
+        return true
+    }
+    ip := net.ParseIP(host)
+    return ip != nil && ip.IsLoopback()
+}
+//
+// authorized() checks bearer token of API request
+//
+func (this *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        if len(this.token) > 0 {
+            authorization := request.Header.Get("Authorization")
+            token := strings.TrimPrefix(authorization, "Bearer ")
+            if token == authorization || subtle.ConstantTimeCompare([]byte(token), []byte(this.token)) != 1 {
+                writer.Header().Set("WWW-Authenticate", "Bearer")
+                writeError(writer, http.StatusUnauthorized, errors.New("unauthorized"))
+                return
+            }
+        }
+        handler(writer, request)
+    }
+}
+//
+// NewHealthServer() serves probe endpoints only
+//
+func NewHealthServer(listen string, health *Health) *Server {
+    server := newServer(listen)
+    health.Register(server)
+    return server
+}
+//
+// Handle() registers additional endpoint
+//
+func (this *Server) Handle(pattern string, handler http.HandlerFunc) {
+    this.mux.HandleFunc(pattern, handler)
+}
+//
+// Start() binds listen address and serves in background
+//
+func (this *Server) Start() error {
+    var err error
+    if this.backend != nil && len(this.token) == 0 && !IsLoopback(this.listen) {
+        return fmt.Errorf("admin api on non-loopback address %s requires token", this.listen)
+    }
+    listener, err := net.Listen("tcp", this.listen)
+    if err != nil {
+        return err
+    }
+    this.server = &http.Server{
+        Handler:        this.mux,
+        ReadTimeout:    readTimeout * time.Second,
+        WriteTimeout:   writeTimeout * time.Second,
+    }
+    go func() {
+        err := this.server.Serve(listener)
+        if err != nil && err != http.ErrServerClosed {
+            pmlog.LogError("admin server error:", err)
+        }
+    }()
+    pmlog.LogInfo("admin server listen on", this.listen)
+    return err
+}
+
+func (this *Server) Stop(ctx context.Context) error {
+    var err error
+    if this.server == nil {
+        return err
+    }
+    return this.server.Shutdown(ctx)
+}
+
+func (this *Server) statusHandler(writer http.ResponseWriter, request *http.Request) {
+    if !allowMethod(writer, request, http.MethodGet) {
+        return
+    }
+    writeJSON(writer, http.StatusOK, this.backend.Status())
+}
+
+func (this *Server) topicsHandler(writer http.ResponseWriter, request *http.Request) {
+    switch request.Method {
+        case http.MethodGet:
+            writeJSON(writer, http.StatusOK, this.backend.Topics())
+        case http.MethodPost:
+            this.controlHandler(ControlSetTopics)(writer, request)
+        default:
+            writeError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+    }
+}
+
+func (this *Server) messagesHandler(writer http.ResponseWriter, request *http.Request) {
+    if !allowMethod(writer, request, http.MethodGet) {
+        return
+    }
+    writeJSON(writer, http.StatusOK, this.backend.Messages())
+}
+
+func (this *Server) queuesHandler(writer http.ResponseWriter, request *http.Request) {
+    if !allowMethod(writer, request, http.MethodGet) {
+        return
+    }
+    writeJSON(writer, http.StatusOK, this.backend.Queues())
+}
+
+func (this *Server) configHandler(writer http.ResponseWriter, request *http.Request) {
+    if !allowMethod(writer, request, http.MethodGet) {
+        return
+    }
+    writeJSON(writer, http.StatusOK, this.backend.Config())
+}
+
+func (this *Server) controlHandler(name string) http.HandlerFunc {
+    return func(writer http.ResponseWriter, request *http.Request) {
+        if !allowMethod(writer, request, http.MethodPost) {
+            return
+        }
+        body, err := ioutil.ReadAll(http.MaxBytesReader(writer, request.Body, maxBodySize))
+        if err != nil {
+            writeError(writer, http.StatusBadRequest, err)
+            return
+        }
+        params := string(body)
+        if len(body) == 0 {
+            params = "{}"
+        }
+        if !json.Valid([]byte(params)) {
+            writeError(writer, http.StatusBadRequest, errors.New("body is not json"))
+            return
+        }
+        err = this.backend.Control(name, params)
+        if err != nil {
+            writeError(writer, http.StatusUnprocessableEntity, err)
+            return
+        }
+        writeJSON(writer, http.StatusOK, map[string]bool{ "done": true })
+    }
+}
+
+func allowMethod(writer http.ResponseWriter, request *http.Request, method string) bool {
+    if request.Method != method {
+        writeError(writer, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+        return false
+    }
+    return true
+}
+
+func writeError(writer http.ResponseWriter, status int, err error) {
+    writeJSON(writer, status, map[string]string{ "error": err.Error() })
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+    jsonBytes, err := json.Marshal(value)
+    if err != nil {
+        status      = http.StatusInternalServerError
+        jsonBytes   = []byte(`{"error":"json encoding error"}`)
+    }
+    writer.Header().Set("Content-Type", "application/json")
+    writer.WriteHeader(status)
+    writer.Write(jsonBytes)
+}
+//EOF
@@ -0,0 +1,169 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmadmin
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+type testBackend struct {
+    name        string
+    params      string
+}
+
+func (this *testBackend) Status() Status                { return Status{ CoreBound: true } }
+func (this *testBackend) Topics() []string              { return []string{ "gw/#" } }
+func (this *testBackend) Messages() []Message           { return []Message{} }
+func (this *testBackend) Queues() map[string]int64      { return map[string]int64{ "ingest": 0 } }
+func (this *testBackend) Config() interface{}           { return map[string]string{ "password": "******" } }
+
+func (this *testBackend) Control(name string, params string) error {
+    this.name   = name
+    this.params = params
+    if name == ControlReload {
+        return errors.New("reload failed")
+    }
+    return nil
+}
+
+func TestServer(t *testing.T) {
+    backend := &testBackend{}
+    server := NewServer("", "", backend)
+
+    recorder := httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+    if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"coreBound":true`) {
+        t.Error("wrong status response", recorder.Code, recorder.Body.String())
+    }
+
+    recorder = httptest.NewRecorder()
+    body := strings.NewReader(`{"topics": "gw/#,dev/#"}`)
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/topics", body))
+    if recorder.Code != http.StatusOK || backend.name != ControlSetTopics || backend.params != `{"topics": "gw/#,dev/#"}` {
+        t.Error("wrong topics control", recorder.Code, backend.name, backend.params)
+    }
+
+    recorder = httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/reload", nil))
+    if recorder.Code != http.StatusUnprocessableEntity {
+        t.Error("control error not reported", recorder.Code)
+    }
+
+    recorder = httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/publish", nil))
+    if recorder.Code != http.StatusMethodNotAllowed {
+        t.Error("wrong method accepted", recorder.Code)
+    }
+}
+
+func TestServerToken(t *testing.T) {
+    backend := &testBackend{}
+    server := NewServer("", "secret", backend)
+
+    for _, authorization := range []string{ "", "Bearer wrong", "secret" } {
+        recorder := httptest.NewRecorder()
+        request := httptest.NewRequest(http.MethodPost, "/api/publish", strings.NewReader(`{}`))
+        request.Header.Set("Authorization", authorization)
+        server.mux.ServeHTTP(recorder, request)
+        if recorder.Code != http.StatusUnauthorized || len(backend.name) > 0 {
+            t.Error("request with authorization", authorization, "accepted", recorder.Code)
+        }
+    }
+    recorder := httptest.NewRecorder()
+    request := httptest.NewRequest(http.MethodPost, "/api/publish", strings.NewReader(`{}`))
+    request.Header.Set("Authorization", "Bearer secret")
+    server.mux.ServeHTTP(recorder, request)
+    if recorder.Code != http.StatusOK || backend.name != ControlPublish {
+        t.Error("authorized request rejected", recorder.Code)
+    }
+}
+
+func TestServerLoopback(t *testing.T) {
+    tests := map[string]bool{
+        "127.0.0.1:8081":   true,
+        "[::1]:8081":       true,
+        "localhost:8081":   true,
+        ":8081":            false,
+        "0.0.0.0:8081":     false,
+        "10.0.0.5:8081":    false,
+        "wrong":            false,
+    }
+    for listen, expected := range tests {
+        if IsLoopback(listen) != expected {
+            t.Error("wrong loopback check of", listen)
+        }
+    }
+    err := NewServer(":0", "", &testBackend{}).Start()
+    if err == nil || !strings.Contains(err.Error(), "requires token") {
+        t.Error("admin api started off loopback without token:", err)
+    }
+    server := NewServer("127.0.0.1:0", "", &testBackend{})
+    err = server.Start()
+    if err != nil {
+        t.Error("loopback admin api not started:", err)
+    }
+    server.Stop(context.Background())
+}
+
+func TestRecent(t *testing.T) {
+    recent := NewRecent(2)
+    recent.Add("a", "", 1, "ok")
+    recent.Add("b", "", 1, "ok")
+    recent.Add("c", "", 1, "ok")
+    list := recent.List()
+    if len(list) != 2 || list[0].Topic != "c" || list[1].Topic != "b" {
+        t.Error("wrong recent list", list)
+    }
+}
+
+func TestHealth(t *testing.T) {
+    var readyErr error
+    health := NewHealth(1, func() error { return readyErr })
+    server := NewHealthServer("", health)
+
+    recorder := httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+    if recorder.Code != http.StatusOK {
+        t.Error("ready probe failed", recorder.Code)
+    }
+
+    readyErr = errors.New("mqtt transport disconnected")
+    recorder = httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+    if recorder.Code != http.StatusServiceUnavailable {
+        t.Error("not ready state not reported", recorder.Code)
+    }
+
+    health.lastBeat -= 2
+    recorder = httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if recorder.Code != http.StatusServiceUnavailable {
+        t.Error("stalled loop not reported", recorder.Code)
+    }
+    health.Beat()
+    recorder = httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if recorder.Code != http.StatusOK {
+        t.Error("live probe failed", recorder.Code)
+    }
+}
+
+func TestMetrics(t *testing.T) {
+    metrics := NewMetrics(func() map[string]int64 {
+        return map[string]int64{ "queue_ingest": 2, "dead_letters": 5 }
+    })
+    server := NewMetricsServer("", "", metrics)
+
+    recorder := httptest.NewRecorder()
+    server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DefaultMetricsPath, nil))
+    if recorder.Body.String() != "pmbri_dead_letters 5\npmbri_queue_ingest 2\n" {
+        t.Error("wrong metrics output", recorder.Body.String())
+    }
+}
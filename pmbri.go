@@ -15,7 +15,9 @@ import (
     "regexp"
     "time"
     "sync"
+    "sync/atomic"
     "strconv"
+    "unicode/utf8"
 
     "app/pmlog"
     "app/pmconfig"
@@ -23,6 +25,18 @@ import (
     "app/pgcore"
     "app/pmtools"
     "app/pmtopics"
+    "app/pmprov"
+    "app/pmdevs"
+    "app/pmdown"
+    "app/pmfirm"
+    "app/pmrpc"
+    "app/pmfwd"
+    "app/pmrules"
+    "app/pmvalid"
+    "app/pmadmin"
+    "app/pmcli"
+    "app/pmqueue"
+    "app/pmsecret"
     "app/mqtrans"
 
     mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -32,11 +46,37 @@ import (
 //
 func main() {
     app := NewApplication()
-    err := app.StartApplication()
+    err := app.GetConfig()
+    if err != nil {
+        pmlog.LogError("app config error:", err)
+        os.Exit(1)
+    }
+    command := flag.Arg(0)
+    if len(command) > 0 && command != pmcli.CommandRun {
+        schemas, err := app.GeneratedSchemas()
+        if err != nil {
+            pmlog.LogError("app schema error:", err)
+            os.Exit(1)
+        }
+        app.cli.SetGenerated(schemas)
+        err = app.cli.Run(flag.Args())
+        if err != nil {
+            pmlog.LogError("command error:", err)
+            os.Exit(1)
+        }
+        return
+    }
+    go app.WaitSignals()
+    err = app.StartApplication()
+    if errors.Is(err, errStopping) {
+        app.WaitShutdown()
+        return
+    }
     if err != nil {
         pmlog.LogError("app error:", err)
         os.Exit(1)
     }
+    app.WaitShutdown()
 }
 //
 //
@@ -44,6 +84,9 @@ type Application struct {
     schema              *pgschema.Schema
     config              *pmconfig.Config
     pg                  *pgcore.Pixcore
+    tokens              *pgcore.TokenManager
+    backlog             *pmqueue.Backlog
+    buffering           int32
     tr                  *mqtrans.Transport
 
     brokerUrl           string
@@ -74,6 +117,69 @@ type Application struct {
 
     autoProvision       bool
     autoProvisionMutex  sync.Mutex
+
+    provisionRule       *pmprov.Rule
+    provisionLimiter    *pmprov.Limiter
+    provisionRuleMutex  sync.Mutex
+
+    devices             *pmdevs.Registry
+    lifecyclePolicy     pmdevs.Policy
+    lifecycleMutex      sync.Mutex
+
+    presence            *pmdevs.Presence
+    statusTimeout       int64
+    statusTimeoutMutex  sync.Mutex
+
+    downlinkRoutes      *pmdown.Routes
+    downlinkRoutesMutex sync.Mutex
+
+    payloadEncoding     string
+    payloadEncodingMutex sync.Mutex
+
+    maxPayloadSize      int64
+    maxPayloadSizeMutex sync.Mutex
+
+    binaryAsMedia       bool
+    binaryAsMediaMutex  sync.Mutex
+
+    firmwareLayout      *pmfirm.Layout
+    firmwareTransfers   map[string]bool
+    firmwareMutex       sync.Mutex
+
+    taps                map[string]mqtrans.Handler
+    tapsMutex           sync.Mutex
+
+    requester           *pmrpc.Requester
+    forwarder           *pmfwd.Forwarder
+
+    topicRules          *pmrules.Rules
+    topicRulesMutex     sync.Mutex
+
+    payloadValidator    *pmvalid.Validator
+    deadLetterTopic     string
+    deadLetters         *pmvalid.Outbox
+    deadLetterCount     int64
+    deadLetterWritten   int64
+    validatorMutex      sync.Mutex
+
+    unmatched           *pmdevs.Unmatched
+    unmatchedWritten    int64
+    suggestionLimiter   *pmprov.Limiter
+
+    admin               *pmadmin.Server
+    probes              *pmadmin.Server
+    metrics             *pmadmin.Server
+    cli                 *pmcli.CLI
+    secrets             *pmsecret.Store
+    schemaChanges       []*pgschema.Changes
+    schemaMigration     map[pgschema.UUID]map[string]string
+    migrationFile       string
+    health              *pmadmin.Health
+    recent              *pmadmin.Recent
+    inflight            int64
+    controlActive       int32
+    stopping            int32
+    shutdownDone        chan struct{}
 }
 //
 //
@@ -112,10 +218,34 @@ func NewApplication() *Application {
 
     app.config  = pmconfig.New()
     app.pg      = pgcore.New(app.appCtx)
+    app.tokens  = pgcore.NewTokenManager(app.pg)
+    app.pg.Breaker().OnChange(app.CoreCircuitChanged)
     app.schema  = pgschema.NewSchema()
     app.tr      = mqtrans.NewTransport()
+    app.deadLetters = pmvalid.NewOutbox(pmvalid.DefaultDeadLetterSize, app.tr.Publish)
     app.topics  = pmtopics.NewTopics()
 
+    app.provisionRule       = pmprov.NewRule()
+    app.provisionLimiter    = pmprov.NewLimiter(app.provisionRule.RateLimit)
+
+    app.devices             = pmdevs.NewRegistry()
+    app.presence            = pmdevs.NewPresence(pmdevs.DefaultPresenceSize)
+    app.downlinkRoutes      = pmdown.NewRoutes()
+    app.firmwareLayout      = pmfirm.NewLayout()
+    app.firmwareTransfers   = make(map[string]bool)
+    app.taps                = make(map[string]mqtrans.Handler)
+    app.requester           = pmrpc.NewRequester()
+    app.forwarder           = pmfwd.NewForwarder()
+    app.topicRules, _       = pmrules.RulesFromString(propertyTopicRulesDefaultValue)
+    app.payloadValidator    = pmvalid.NewValidator()
+    app.unmatched           = pmdevs.NewUnmatched(pmdevs.DefaultUnmatchedSize)
+    app.suggestionLimiter   = pmprov.NewLimiter(suggestionRateLimit)
+    app.recent              = pmadmin.NewRecent(pmadmin.DefaultRecentSize)
+    app.shutdownDone        = make(chan struct{})
+    app.secrets, _          = pmsecret.NewStore("", false)
+    app.cli                 = pmcli.New(app.config, []string{ applicationTag, mqttBridgeAppSchemaTag, appProfileTag })
+    app.health              = pmadmin.NewHealth(pmadmin.DefaultLiveWindow, app.Ready)
+
     return &app
 }
 //
@@ -123,8 +253,12 @@ func NewApplication() *Application {
 func (this *Application) GetConfig() error {
     var err error
     exeName := filepath.Base(os.Args[0])
-    this.config.Read(exeName + ".yml")
+    err = this.config.Read(exeName + ".yml")
+    if err != nil {
+        return err
+    }
 
+    printConfig := flag.Bool("print-config", false, "print effective config with masked secrets and exit")
     flag.Usage = func() {
         fmt.Println(exeName + " version " + this.config.Version)
         fmt.Println("")
@@ -132,17 +266,55 @@ func (this *Application) GetConfig() error {
         fmt.Println("")
         flag.PrintDefaults()
         fmt.Println("")
+        this.cli.Usage(os.Stdout)
+        fmt.Println("")
+        fmt.Println("every config key may be set by environment, e.g.")
+        fmt.Println("core.password as CONFIG_CORE_PASSWORD, queues.recentSize as CONFIG_QUEUES_RECENTSIZE")
+        fmt.Println("")
     }
     flag.Parse()
-    if len(os.Getenv("CONFIG_API_USERNAME")) > 0 {
-        this.config.Core.Username = os.Getenv("CONFIG_API_USERNAME")
+
+    err = this.config.ApplyEnv()
+    if err != nil {
+        return err
+    }
+    err = this.config.ResolveSecrets()
+    if err != nil {
+        return err
+    }
+    if *printConfig {
+        fmt.Print(this.config.Masked().GetYaml())
+        os.Exit(0)
+    }
+    err = this.config.Validate()
+    if err != nil {
+        return err
+    }
+    pmlog.SetLevel(this.config.LogLevel())
+
+    dataDir, err := this.DataDir()
+    if err != nil {
+        return err
     }
-    if len(os.Getenv("CONFIG_API_PASSWORD")) > 0 {
-        this.config.Core.Password = os.Getenv("CONFIG_API_PASSWORD")
+    this.migrationFile = filepath.Join(dataDir, exeName + ".migration.json")
+
+    this.secrets, err = pmsecret.NewStore(this.config.Secrets.Key, this.config.Secrets.EncryptBroker)
+    if err != nil {
+        return err
     }
-    if len(os.Getenv("CONFIG_API_URL")) > 0 {
-        this.config.Core.URL = os.Getenv("CONFIG_API_URL")
+    this.forwarder.SetResolver(this.secrets.Decode)
+
+    tlsConfig, err := this.config.TLS.TLSConfig()
+    if err != nil {
+        return err
     }
+    this.tr.SetTLSConfig(tlsConfig)
+    this.forwarder.SetLocalTLS(tlsConfig)
+
+    this.recent     = pmadmin.NewRecent(this.config.Queues.RecentSize)
+    this.unmatched  = pmdevs.NewUnmatched(this.config.Queues.UnmatchedSize)
+    this.presence   = pmdevs.NewPresence(this.config.Queues.PresenceSize)
+    this.backlog    = pmqueue.NewBacklog(this.config.Queues.BacklogSize)
     return err
 }
 //
@@ -150,7 +322,8 @@ func (this *Application) GetConfig() error {
 func (this *Application) StartApplication() error {
     var err error
     pmlog.LogInfo("trying to start application")
-    err = this.GetConfig()
+    this.deadLetters.Start()
+    err = this.StartAdmin()
     if err != nil {
         return err
     }
@@ -168,6 +341,7 @@ func (this *Application) StartApplication() error {
     if err != nil {
         return err
     }
+    this.tokens.Start(this.appCtx)
 
     err = this.GetUserId()
     if err != nil {
@@ -188,10 +362,15 @@ func (this *Application) StartApplication() error {
     if err != nil {
         return err
     }
+    this.MigrateAppSchema()
     err = this.GetAppProperties()
     if err != nil {
         return err
     }
+    err = this.LoadManagedDevices()
+    if err != nil {
+        pmlog.LogError("application managed devices error:", err)
+    }
 
     err = this.GetTransProperties()
     if err != nil {
@@ -218,6 +397,11 @@ func (this *Application) StartApplication() error {
     //}
     //go this.StartPropertySubsrWatcher()
 
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyStatusName, "true")
+    if err != nil {
+        pmlog.LogError("error update status property:", err)
+    }
+
     err = this.StartLoop()
     if err != nil {
         return err
@@ -262,10 +446,15 @@ func (this *Application) ReStartApplication() error {
     if err != nil {
         return err
     }
+    this.MigrateAppSchema()
     err = this.GetAppProperties()
     if err != nil {
         return err
     }
+    err = this.LoadManagedDevices()
+    if err != nil {
+        pmlog.LogError("application managed devices error:", err)
+    }
     err = this.GetTransProperties()
     if err != nil {
         return err
@@ -300,36 +489,55 @@ func (this *Application) ConnectionSetup() error {
     err := this.pg.Setup(this.config.Core.URL, this.config.Core.Username,
                     this.config.Core.Password, this.config.Core.JwtTTL,
                     this.schema.Metadata.MTags)
+    if err != nil {
+        return err
+    }
+    policy := pgcore.DefaultRequestPolicy()
+    policy.Timeout          = time.Duration(this.config.Core.Timeout) * time.Second
+    policy.Attempts         = this.config.Core.Attempts
+    policy.BreakerThreshold = this.config.Core.BreakerThreshold
+    policy.BreakerCooldown  = time.Duration(this.config.Core.BreakerCooldown) * time.Second
+    this.pg.SetRequestPolicy(policy)
+
+    jwtKeys, err := this.config.Core.JwtKeys()
+    if err != nil {
+        return err
+    }
+    if jwtKeys != nil {
+        this.pg.SetVerifyKeys(jwtKeys)
+        pmlog.LogInfo("jwt signature verification enabled")
+    }
+    err = this.pg.SetMediaURL(this.config.Media.URL)
     return err
 }
 //
+// errStopping aborts startup or restart when shutdown began
+//
+var errStopping = errors.New("application is stopping")
 //
-func (this *Application) BindCore() error { 
+// BindCore() binds core with retries until success or shutdown
+//
+func (this *Application) BindCore() error {
     var err error
     timer := time.NewTicker(bindReconnectInterval * time.Second)
-    for _ = range timer.C {
-        err := this.pg.Bind()
+    defer timer.Stop()
+    for {
+        select {
+            case <- this.appCtx.Done():
+                return errStopping
+            case <- timer.C:
+        }
+        if atomic.LoadInt32(&this.stopping) == 1 {
+            return errStopping
+        }
+        err = this.pg.Bind()
         if err != nil {
             pmlog.LogInfo("application wainting connection to core, error:", err)
             continue
         }
         pmlog.LogInfo("application connected to core")
-        break
-    }
-    return err
-}
-//
-//
-func (this *Application) SetupAppSchema() error {
-    var err error
-    pmlog.LogInfo("trying to import application schema")
-    schemaJson := this.schema.GetJSON()
-    schemaId, err := this.pg.ImportSchema(schemaJson)
-    if err != nil {
         return err
     }
-    pmlog.LogInfo("done import application schema", schemaId)
-    return err
 }
 //
 //
@@ -361,12 +569,83 @@ func (this *Application) GetAppProperties() error {
     }
     this.autoProvision = autoProvisionBool
 
+    err = this.GetProvisionProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetLifecycleProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetPresenceProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetDownlinkProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetForwardProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetTopicRulesProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetDeadLetterProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetEncodingProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetPayloadSizeProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetMediaProperties()
+    if err != nil {
+        return err
+    }
+
+    err = this.GetFirmwareProperties()
+    if err != nil {
+        return err
+    }
+
     pmlog.LogInfo("application got own property")
     return err
 }
 
-
-
+//
+//
+func (this *Application) getInt64Property(propertyName string, defaultValue string) (int64, error) {
+    var err error
+    var result int64
+    valueStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyName)
+    if err != nil {
+        return result, err
+    }
+    if len(valueStr) == 0 {
+        valueStr = defaultValue
+    }
+    result, err = strconv.ParseInt(valueStr, 10, 64)
+    if err != nil {
+        return result, err
+    }
+    return result, err
+}
 func (this *Application) GetTransProperties() error {
     var err error
     pmlog.LogInfo("application trying to get own property")
@@ -375,15 +654,21 @@ func (this *Application) GetTransProperties() error {
     if err != nil {
         return err
     }
-    this.username, err = this.pg.GetObjectPropertyValue(this.objectId, mqttPropertyUsernameName)
+    this.username, err = this.GetCredentialProperty(mqttPropertyUsernameName)
     if err != nil {
         return err
     }
-    this.password, err = this.pg.GetObjectPropertyValue(this.objectId, mqttPropertyPasswordName)
+    this.password, err = this.GetCredentialProperty(mqttPropertyPasswordName)
     if err != nil {
         return err
     }
 
+    if len(this.brokerUrl) == 0 {
+        this.brokerUrl  = this.config.Broker.URL
+        this.username   = this.config.Broker.Username
+        this.password   = this.config.Broker.Password
+    }
+
     topicsString, err := this.pg.GetObjectPropertyValue(this.objectId, mqttPropertyTopicsName)
     if err != nil {
         return err
@@ -391,6 +676,8 @@ func (this *Application) GetTransProperties() error {
     pmlog.LogInfo("application got topics", topicsString)
     this.topics = pmtopics.TopicsFromString(topicsString)
 
+    this.forwarder.SetLocal(this.brokerUrl, this.username, this.password)
+
     pmlog.LogInfo("application got own property")
     return err
 }
@@ -513,10 +800,14 @@ func (this *Application) SubscribeToTopics() error {
 //
 const (
     aliveMessage            string          = "Alive"
-    jwtExpireTh             int64           = 15
     bindReconnectInterval   time.Duration   = 1   // sec
     loopInterval            time.Duration   = 1   // sec
     aliveInterval           time.Duration   = 20  // sec
+    lifecycleInterval       time.Duration   = 60  // sec
+    presenceInterval        time.Duration   = 5   // sec
+    forwardInterval         time.Duration   = 10  // sec
+    forwardStatsInterval    time.Duration   = 60  // sec
+    deadLetterInterval      time.Duration   = 30  // sec
 )
 
 func (this *Application) StartLoop() error {
@@ -526,6 +817,12 @@ func (this *Application) StartLoop() error {
     for {
         needRestart := false
         time.Sleep(loopInterval * time.Second)
+        this.health.Beat()
+
+        if atomic.LoadInt32(&this.stopping) == 1 {
+            pmlog.LogInfo("application loop stopped")
+            return err
+        }
 
         if !this.tr.IsConnected() {
             pmlog.LogInfo("mqtt transport will reconnect")
@@ -535,23 +832,44 @@ func (this *Application) StartLoop() error {
         if (time.Now().Unix() % int64(aliveInterval)) == 0 {
             pmlog.LogInfo("application is still alive")
             _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyMessageName, aliveMessage)
-            if err != nil {
-                pmlog.LogError("error update message property:", err)
-                needRestart = true
+            switch {
+                case err == nil:
+                case errors.Is(err, pgcore.ErrCircuitOpen) || atomic.LoadInt32(&this.buffering) == 1:
+                    // keep transport bound, ingest goes to backlog until circuit closes
+                    pmlog.LogWarning("core unavailable, skip update message property:", err)
+                default:
+                    pmlog.LogError("error update message property:", err)
+                    needRestart = true
             }
         }
 
-        expirePeriod := this.pg.GetJWTExpire() - time.Now().Unix()
-        if expirePeriod < jwtExpireTh {
+        if (time.Now().Unix() % int64(lifecycleInterval)) == 0 {
+            this.CheckDeviceLifecycle()
+        }
 
-            err = this.pg.UpdateJWToken()
-            if err != nil {
-                pmlog.LogError("error update jwt:", err)
-                needRestart = true
-            }
+        if (time.Now().Unix() % int64(presenceInterval)) == 0 {
+            this.CheckDevicePresence()
+        }
+
+        if (time.Now().Unix() % int64(forwardInterval)) == 0 {
+            this.forwarder.Connect()
+        }
+
+        if (time.Now().Unix() % int64(forwardStatsInterval)) == 0 {
+            this.WriteForwardStats()
+        }
+
+        if (time.Now().Unix() % int64(deadLetterInterval)) == 0 {
+            this.WriteDeadLetterCount()
+            this.WriteUnmatchedCount()
         }
+
         if needRestart {
                 for {
+                    if atomic.LoadInt32(&this.stopping) == 1 {
+                        pmlog.LogInfo("application loop stopped")
+                        return err
+                    }
                     pmlog.LogInfo("restart application loop")
                     err = this.ReStartApplication()
                     if err == nil {
@@ -590,6 +908,7 @@ func (this *Application) StartControlSubsription() error {
     this.subscrControlCancel = cancel
 
     go loopFunc()
+    atomic.StoreInt32(&this.controlActive, 1)
     pmlog.LogInfo("application started control subscription")
     return err
 }
@@ -615,6 +934,7 @@ func (this *Application) StartControlSubsrWatcher() error {
     
     for {
         this.subscrControlWG.Wait()
+        atomic.StoreInt32(&this.controlActive, 0)
         select {
             case <- this.controlWatcherCtx.Done():
                 pmlog.LogInfo("control subscription watcher canceled")
@@ -666,13 +986,19 @@ func (this *Application) RoutePropertyMessage(propertyMessage pgcore.ObjectPrope
                     pmlog.LogError("transport reconnect error", err)
                 }
             case mqttPropertyUsernameName:
-                this.username = propertyMessage.Value
+                this.username, err = this.secrets.Decode(propertyMessage.Value)
+                if err != nil {
+                    pmlog.LogError("username property error:", err)
+                }
                 err := this.TransportReconnect()
                 if err != nil {
                     pmlog.LogError("transport reconnect error", err)
                 }
             case mqttPropertyPasswordName:
-                this.password = propertyMessage.Value
+                this.password, err = this.secrets.Decode(propertyMessage.Value)
+                if err != nil {
+                    pmlog.LogError("password property error:", err)
+                }
                 err := this.TransportReconnect()
                 if err != nil {
                     pmlog.LogError("transport reconnect error", err)
@@ -775,19 +1101,27 @@ func (this *Application) WaitSPropertySubsrWatcher() error {
 //*********************************************************************//
 //
 const (
-    appSchemaVersion                    string  = "1.41"
+    appSchemaVersion                    string  = "1.54"
 
     // Property groups
     propertyGroupMeasurement            string = "Measurements"
     propertyGroupCredential             string = "Credentials"
     propertyGroupTopics                 string = "Topics"
     propertyGroupHealthCheck            string = "HealthCheck"
+    propertyGroupProvisioning           string = "Provisioning"
+    propertyGroupLifecycle              string = "Lifecycle"
+    propertyGroupDownlink               string = "Downlink"
+    propertyGroupPayload                string = "Payload"
+    propertyGroupForwarding             string = "Forwarding"
+    propertyGroupRouting                string = "Routing"
+    propertyGroupSettings               string = "Settings"
 
     // Common properties
     propertyStatusName                  string = "Status"
     propertyMessageName                 string = "Message"
     propertyTimeoutName                 string = "Timeout"
     propertyAutoProvisionName           string = "AUTO_PROVISION"
+    propertyProvisionRuleName           string = "ProvisionRule"
 
     propertyStatusDefaultValue          string = "true"
     propertyTimeoutDefaultValue         string = "120"
@@ -798,6 +1132,16 @@ const (
     controlReloadName                   string  = "Reload"
     controlTestModuleName               string  = "TestModule"
     controlSetAutoProvisionName         string  = "SetAutoProvision"
+    controlSetProvisionRuleName         string  = "SetProvisionRule"
+    controlSetDeviceLifecycleName       string  = "SetDeviceLifecycle"
+    controlSetDownlinkRoutesName        string  = "SetDownlinkRoutes"
+    controlSetForwardRulesName          string  = "SetForwardRules"
+    controlSetTopicRulesName            string  = "SetTopicRules"
+    controlSetPayloadSchemasName        string  = "SetPayloadSchemas"
+    controlListUnmatchedName            string  = "ListUnmatched"
+    controlSendFileName                 string  = "SendFile"
+    controlSendFirmwareName             string  = "SendFirmware"
+    controlRequestName                  string  = "Request"
 
     controlSetTopicsName                string  = "SetTopics"
     controlSetBrokerURLName              string  = "SetBrokerURL"
@@ -827,7 +1171,7 @@ const (
     // MQTT specific defaults
     mqttPropertyBrokerUrlDefaultValue   string  = "tcp://v7.unix7.org:1883"
     mqttPropertyUsernameDefaultValue    string  = "device"
-    mqttPropertyPasswordDefaultValue    string  = "qwerty"
+    mqttPropertyPasswordDefaultValue    string  = ""
     mqttPropertyTopicsDefaultValue      string  = "/gw/#,SENSO8/#"
 
     controlDecodePayloadName            string = "DecodePayload"
@@ -840,9 +1184,76 @@ const (
     appProfileTag                       string  = "app profile"
 )
 
+//
+// Generic MQTT Device schema, imported from pmdata by start script
+//
+const (
+    deviceSchemaVersion                 string = "1.29"
+    deviceSchemaExternalId              pgschema.UUID = "5b282915-64cb-4fcf-b5d5-5ddc6a41c679"
+    deviceSchemaOwner                   pgschema.UUID = "4febcecb-5bf6-4a94-9bfb-ebd4b4598755"
+    deviceSchemaPicture                 string = "352ddd71-2bc1-f4f8-1549-6fa2b6a87f24"
+    deviceControlSendCommandName        string = "SendCommand"
+)
+
+func (this *Application) DefineDeviceSchema() (*pgschema.Schema, error) {
+    metadata := pgschema.NewMetadata()
+    metadata.Id                   = genericDriverSchemaId
+    metadata.MExternalId          = deviceSchemaExternalId
+    metadata.ApplicationOwner     = deviceSchemaOwner
+    metadata.MTags                = append(metadata.MTags, mqttDriverSchemaTag)
+    metadata.MVersion             = deviceSchemaVersion
+    metadata.MPicture             = deviceSchemaPicture
+    metadata.Name                 = "Generic MQTT Device"
+    metadata.Description          = "Generic MQTT Device"
+    metadata.Type                 = pgschema.MetadataTypeDevice
+    builder := pgschema.NewBuilder(metadata)
+
+    builder.Control(controlDecodePayloadName, "Decode payload")
+    builder.Argument(controlDecodePayloadName, "payload", pgschema.StringType, "Topic payload")
+    builder.Argument(controlDecodePayloadName, "topicName", pgschema.StringType, "Topic name")
+
+    builder.Control(deviceControlSendCommandName, "Send command to device")
+    builder.Argument(deviceControlSendCommandName, "command", pgschema.StringType, "Command")
+
+    builder.Control(controlSendFirmwareName, "Send firmware to device")
+    builder.Argument(controlSendFirmwareName, "mediaId", pgschema.StringType, "Firmware media object id")
+    builder.Argument(controlSendFirmwareName, "chunkSize", pgschema.IntType, "Chunk size").DefaultValue = "1024"
+
+    builder.Property(mqttPropertyBridgeObjectIdName, pgschema.StringType, propertyGroupCredential, "MQTT bridge")
+    builder.Property(mqttPropertyTopicBaseName, pgschema.StringType, propertyGroupCredential, "Topic base")
+    builder.Property(devicePropertyDownlinkTopicName, pgschema.StringType, propertyGroupSettings,
+                                    "Downlink topic template, overrides bridge route")
+    builder.Property("BATTERY_LEVEL", pgschema.DoubleType, propertyGroupMeasurement, "Battery level").Units = "%"
+    builder.Property("BATTERY_LOW", pgschema.BoolType, propertyGroupMeasurement, "Battery low")
+    builder.Property(propertyMessageName, pgschema.StringType, propertyGroupMeasurement, "Message")
+    builder.Property("RESPONSE_STATUS", pgschema.BoolType, propertyGroupMeasurement, "Response status").DefaultValue = "true"
+    builder.Property("RESPONSE_TIMEOUT", pgschema.IntType, propertyGroupSettings, "Response timeout").DefaultValue = "120"
+    builder.Property(propertyStatusName, pgschema.BoolType, propertyGroupHealthCheck, "Device online").DefaultValue = deviceStatusOfflineValue
+    builder.Property(devicePropertyLastSeenName, pgschema.StringType, propertyGroupHealthCheck,
+                                    "Last message time").DefaultValue = "1970-01-01T00:00:00Z"
+    return builder.Build()
+}
+//
+// GeneratedSchemas() maps pmdata schema file names to schemas
+//
+func (this *Application) GeneratedSchemas() (map[string]*pgschema.Schema, error) {
+    var err error
+    result := make(map[string]*pgschema.Schema)
+    err = this.DefineAppSchema()
+    if err != nil {
+        return result, err
+    }
+    result["mqtt-bridge.json"] = this.schema
+    deviceSchema, err := this.DefineDeviceSchema()
+    if err != nil {
+        return result, err
+    }
+    result["mqtt-generic-tmp.json"] = deviceSchema
+    return result, err
+}
+
 func (this *Application) DefineAppSchema() error {
     var err error
-    schema := pgschema.NewSchema()
     metadata := pgschema.NewMetadata()
     metadata.Id                   = this.config.AppSchemaId
     metadata.MExternalId          = this.config.AppSchemaId
@@ -855,39 +1266,102 @@ func (this *Application) DefineAppSchema() error {
     metadata.Name                 = "MQTT Bridge"
     metadata.Description          = "MQTT Bridge"
     metadata.Type                 = pgschema.MetadataTypeApp
-    schema.Metadata = metadata
-
-    schema.Controls     = append(schema.Controls, this.newPublishControl())
-    schema.Controls     = append(schema.Controls, this.newPublishControlArgTopicName())
-    schema.Controls     = append(schema.Controls, this.newPublishControlArgPayload())
-
-    schema.Controls     = append(schema.Controls, this.newReloadControl())
-    schema.Controls     = append(schema.Controls, this.newTestModuleControl())
-
-    schema.Controls     = append(schema.Controls, this.newSetAutoProvisionControl())
-    schema.Controls     = append(schema.Controls, this.newSetAutoProvisionControlArgEnable())
-
-    schema.Properties   = append(schema.Properties, this.newStatusProperty())
-    schema.Properties   = append(schema.Properties, this.newMessageProperty())
-    schema.Properties   = append(schema.Properties, this.newTimeoutProperty())
-
-    schema.Properties   = append(schema.Properties, this.newMqttBrokerUrlProperty())
-    schema.Properties   = append(schema.Properties, this.newMqttUsernameProperty())
-    schema.Properties   = append(schema.Properties, this.newMqttPasswordProperty())
-    schema.Properties   = append(schema.Properties, this.newMqttTopicsProperty())
-
-    schema.Properties   = append(schema.Properties, this.newAutoProvisionProperty())
-    schema.Properties   = append(schema.Properties, this.newConnectionStateProperty())
-
-    schema.Controls     = append(schema.Controls, this.newSetTopicsControl())
-    schema.Controls     = append(schema.Controls, this.newSetTopicsControlArgTopicName())
-    schema.Controls     = append(schema.Controls, this.newSetBrokerURLControl())
-    schema.Controls     = append(schema.Controls, this.newSetBrokerURLControlArgTopicName())
-    schema.Controls     = append(schema.Controls, this.newSetUsernameControl())
-    schema.Controls     = append(schema.Controls, this.newSetUsernameControlArgTopicName())
-    schema.Controls     = append(schema.Controls, this.newSetPasswordControl())
-    schema.Controls     = append(schema.Controls, this.newSetPasswordControlArgTopicName())
-
+    builder := pgschema.NewBuilder(metadata)
+
+    builder.AddControl(this.newPublishControl())
+    builder.AddControl(this.newPublishControlArgTopicName())
+    builder.AddControl(this.newPublishControlArgPayload())
+    builder.AddControl(this.newPublishControlArgEncoding())
+
+    builder.AddControl(this.newRequestControl())
+    builder.AddControl(this.newRequestControlArgTopicName())
+    builder.AddControl(this.newRequestControlArgPayload())
+    builder.AddControl(this.newRequestControlArgEncoding())
+    builder.AddControl(this.newRequestControlArgReplyTopic())
+    builder.AddControl(this.newRequestControlArgCorrelationField())
+    builder.AddControl(this.newRequestControlArgTimeout())
+
+    builder.AddControl(this.newSendFileControl())
+    builder.AddControl(this.newSendFileControlArgMediaId())
+    builder.AddControl(this.newSendFileControlArgTopicName())
+    builder.AddControl(this.newSendFileControlArgChunkSize())
+
+    builder.AddControl(this.newSendFirmwareControl())
+    builder.AddControl(this.newSendFirmwareControlArgMediaId())
+    builder.AddControl(this.newSendFirmwareControlArgTopicBase())
+    builder.AddControl(this.newSendFirmwareControlArgChunkSize())
+    builder.AddControl(this.newSendFirmwareControlArgTimeout())
+    builder.AddControl(this.newSendFirmwareControlArgRetries())
+
+    builder.AddControl(this.newReloadControl())
+    builder.AddControl(this.newTestModuleControl())
+
+    builder.AddControl(this.newSetAutoProvisionControl())
+    builder.AddControl(this.newSetAutoProvisionControlArgEnable())
+
+    builder.AddControl(this.newSetProvisionRuleControl())
+    builder.AddControl(this.newSetProvisionRuleControlArgRule())
+
+    builder.AddControl(this.newSetDeviceLifecycleControl())
+    builder.AddControl(this.newSetDeviceLifecycleControlArgOffline())
+    builder.AddControl(this.newSetDeviceLifecycleControlArgDisable())
+    builder.AddControl(this.newSetDeviceLifecycleControlArgDelete())
+
+    builder.AddControl(this.newSetDownlinkRoutesControl())
+    builder.AddControl(this.newSetDownlinkRoutesControlArgRoutes())
+    builder.AddControl(this.newSetForwardRulesControl())
+    builder.AddControl(this.newSetForwardRulesControlArgRules())
+    builder.AddControl(this.newSetTopicRulesControl())
+    builder.AddControl(this.newSetTopicRulesControlArgRules())
+    builder.AddControl(this.newSetPayloadSchemasControl())
+    builder.AddControl(this.newSetPayloadSchemasControlArgSchemas())
+    builder.AddControl(this.newListUnmatchedControl())
+
+    builder.AddProperty(this.newStatusProperty())
+    builder.AddProperty(this.newMessageProperty())
+    builder.AddProperty(this.newTimeoutProperty())
+
+    builder.AddProperty(this.newMqttBrokerUrlProperty())
+    builder.AddProperty(this.newMqttUsernameProperty())
+    builder.AddProperty(this.newMqttPasswordProperty())
+    builder.AddProperty(this.newMqttTopicsProperty())
+
+    builder.AddProperty(this.newAutoProvisionProperty())
+    builder.AddProperty(this.newConnectionStateProperty())
+    builder.AddProperty(this.newProvisionRuleProperty())
+
+    builder.AddProperty(this.newDeviceOfflineAfterProperty())
+    builder.AddProperty(this.newDeviceDisableAfterProperty())
+    builder.AddProperty(this.newDeviceDeleteAfterProperty())
+
+    builder.AddProperty(this.newDownlinkRoutesProperty())
+    builder.AddProperty(this.newPayloadEncodingProperty())
+    builder.AddProperty(this.newMaxPayloadSizeProperty())
+    builder.AddProperty(this.newBinaryAsMediaProperty())
+    builder.AddProperty(this.newFirmwareLayoutProperty())
+    builder.AddProperty(this.newTopicRulesProperty())
+    builder.AddProperty(this.newPayloadSchemasProperty())
+    builder.AddProperty(this.newDeadLetterTopicProperty())
+    builder.AddProperty(this.newDeadLetterCountProperty())
+    builder.AddProperty(this.newUnmatchedCountProperty())
+    builder.AddProperty(this.newProvisionSuggestionProperty())
+    builder.AddProperty(this.newForwardRulesProperty())
+    builder.AddProperty(this.newForwardStatsProperty())
+    builder.AddProperty(this.newForwardedProperty())
+
+    builder.AddControl(this.newSetTopicsControl())
+    builder.AddControl(this.newSetTopicsControlArgTopicName())
+    builder.AddControl(this.newSetBrokerURLControl())
+    builder.AddControl(this.newSetBrokerURLControlArgTopicName())
+    builder.AddControl(this.newSetUsernameControl())
+    builder.AddControl(this.newSetUsernameControlArgTopicName())
+    builder.AddControl(this.newSetPasswordControl())
+    builder.AddControl(this.newSetPasswordControlArgTopicName())
+
+    schema, err := builder.Build()
+    if err != nil {
+        return err
+    }
     this.schema = schema
     return err
 }
@@ -958,7 +1432,6 @@ func (this *Application) newSetAutoProvisionControlArgEnable() *pgschema.Control
     control.DefaultValue    = "true"
     return control
 }
-
 //
 //*********************************************************************//
 //
@@ -1034,6 +1507,7 @@ func (this *Application) newSetPasswordControlArgTopicName() *pgschema.Control {
     control.Description     = "Password"
     control.Type            = pgschema.StringType
     control.Argument        = "password"
+    control.Mask            = pgschema.PasswordMask
     return control
 }
 
@@ -1113,6 +1587,7 @@ func (this *Application) newMqttPasswordProperty() *pgschema.Property {
     property.Description    = "MQTT broker password"
     property.GroupName      = propertyGroupCredential
     property.DefaultValue   = mqttPropertyPasswordDefaultValue
+    property.Mask           = pgschema.PasswordMask
     return property
 }
 //
@@ -1149,7 +1624,7 @@ func (this *Application) CreateDummyTopicHandler() mqtrans.Handler {
 //
 //
 const (
-    mqttPropertyTopicBaseName       string  = "TopicBase"
+    mqttPropertyTopicBaseName       string  = "TOPIC_BASE"
     mqttPropertyBridgeObjectIdName  string  = "BRIDGE"
 
     genericDriverSchemaId       pgschema.UUID   = "6a34e442-cc3c-4586-853e-9058e1fd7739"
@@ -1157,11 +1632,18 @@ const (
 
     minewG1Pattern      string  = "^(/gw/)(.*)/status$"
     senso8DataPattern   string  = "^(SENSO8/nbiot/data/)(.*)$"
-    senso8SysPattern    string  = "^(SENSO8/nbiot/sys/)(.*)$"
 )
 //
 //
-const maxPayloadSize    int = 64 * 1024
+const (
+    messageResultDropped    string      = "dropped"
+    messageResultDelivered  string      = "delivered"
+
+    suggestionRateLimit int             = 6     // per minute
+    deliveryRetryDelay  time.Duration   = 1 // sec
+    presenceForgetAfter int64           = 86400 // sec, without lifecycle delete period
+)
+//
 //
 func (this *Application) CreateRealTopicHandler() mqtrans.Handler {
 
@@ -1171,56 +1653,71 @@ func (this *Application) CreateRealTopicHandler() mqtrans.Handler {
         mqttTopic := message.Topic()
 
         //pmlog.LogDetail("receive mqtt message topic:", message.Topic(), "with payload", string(message.Payload()))
+        this.DispatchTaps(client, message)
 
-        argument := pgcore.NewTopicArguments()
-        argument.TopicName  = mqttTopic
-        argument.Payload    = message.Payload()
+        if atomic.LoadInt32(&this.stopping) == 1 {
+            return
+        }
+        atomic.AddInt64(&this.inflight, 1)
+        defer atomic.AddInt64(&this.inflight, -1)
 
-        if len(argument.Payload) > maxPayloadSize {
-            pmlog.LogWarning("payload size is more 64k")
+        ctx, cancel := this.MessageContext()
+        defer cancel()
+
+        rules := this.GetTopicRules().Apply(mqttTopic, message.Payload())
+        if rules.Dropped {
+            pmlog.LogDetail("message of topic", mqttTopic, "dropped by rules", rules.Applied)
+            this.recent.Add(mqttTopic, "", len(message.Payload()), messageResultDropped)
             return
         }
+        mqttTopic = rules.Topic
 
-        controlExecution := func() {
+        deadLetterTopic := this.GetDeadLetterTopic()
+        if len(deadLetterTopic) > 0 && message.Topic() == deadLetterTopic {
+            return
+        }
+        payload := message.Payload()
+        err = this.GetPayloadValidator().Validate(mqttTopic, payload)
+        if err != nil {
+            this.DeadLetterMessage(mqttTopic, payload, pmvalid.ReasonInvalid, err)
+            this.recent.Add(mqttTopic, "", len(payload), pmvalid.ReasonInvalid)
+            return
+        }
 
-            controlName := controlDecodePayloadName
+        argument := pgcore.NewTopicArguments()
+        argument.TopicName  = mqttTopic
+        argument.Payload    = message.Payload()
+        argument.Encoding   = this.GetPayloadEncoding()
+        if len(rules.Tags) > 0 {
+            argument.Tags   = rules.Tags
+        }
 
-            patterns := make([]string, 0)
-            patterns = append(patterns, senso8SysPattern) 
-            patterns = append(patterns, senso8DataPattern) 
-            patterns = append(patterns, minewG1Pattern)
-            //var patternDefined bool
-            var foundTemplate string
-            topicBase := mqttTopic
-
-            for _, pattern := range patterns {
-                patternDefined, _ := regexp.MatchString(pattern, mqttTopic)
-                if patternDefined  {
-                    foundTemplate = pattern
-                    break
-                }
+        maxPayloadSize := this.GetMaxPayloadSize()
+        if maxPayloadSize > 0 && int64(len(argument.Payload)) > maxPayloadSize {
+            err = this.StorePayloadMedia(ctx, argument)
+            if err != nil {
+                pmlog.LogWarning("payload of topic", mqttTopic, "size", len(argument.Payload),
+                                    "is more", maxPayloadSize, "and not stored:", err)
+                return
             }
+        } else if this.GetBinaryAsMedia() && !utf8.Valid(argument.Payload) {
+            err = this.StorePayloadMedia(ctx, argument)
+            if err != nil {
+                pmlog.LogWarning("binary payload of topic", mqttTopic, "not stored:", err)
+                return
+            }
+        }
 
-            re := regexp.MustCompile(foundTemplate)
-            res := re.FindStringSubmatch(mqttTopic)
-
-            var gwCode string
-            switch foundTemplate {
-                case senso8SysPattern, senso8DataPattern:
-                    if len(res) == 3 {
-                        gwCode = res[2]
-                        topicBase = "SENSO8/nbiot/data/" + gwCode
-                    }
-                case minewG1Pattern:
-                    if len(res) == 3 {
-                        gwCode = res[2]
-                        topicBase = "gw/" + gwCode
-                    }
-                default:
-                    topicBase = mqttTopic
+        var controlExecution func(ctx context.Context)
+        controlExecution = func(ctx context.Context) {
+            if atomic.LoadInt32(&this.buffering) == 1 {
+                this.BacklogMessage(mqttTopic, payload, controlExecution)
+                return
             }
 
-            
+            controlName := controlDecodePayloadName
+            topicBase := rules.TopicBase
+
             autoProvisionBool := this.GetAutoProvision()
             pmlog.LogDebug("autoProvision:", autoProvisionBool)
 
@@ -1228,7 +1725,21 @@ func (this *Application) CreateRealTopicHandler() mqtrans.Handler {
                 patternDefined  := false
                 var autoTopicBase string
                 if !patternDefined  {
-                    autoTopicBase, patternDefined, err = this.CheckOrCreateGenericDevice(argument.TopicName, argument.Payload)
+                    autoTopicBase, patternDefined, err = this.CheckOrCreateGenericDevice(ctx, argument.TopicName, argument.Payload)
+                    if errors.Is(err, pgcore.ErrCircuitOpen) {
+                        this.BacklogMessage(mqttTopic, payload, controlExecution)
+                        return
+                    }
+                    if err != nil {
+                        return
+                    }
+                }
+                if !patternDefined  {
+                    autoTopicBase, patternDefined, err = this.CheckOrCreateRuleDevice(ctx, argument.TopicName)
+                    if errors.Is(err, pgcore.ErrCircuitOpen) {
+                        this.BacklogMessage(mqttTopic, payload, controlExecution)
+                        return
+                    }
                     if err != nil {
                         return
                     }
@@ -1238,22 +1749,42 @@ func (this *Application) CreateRealTopicHandler() mqtrans.Handler {
                 }
             }
     
+            this.TouchDevice(ctx, topicBase)
+            this.UpdateDevicePresence(ctx, topicBase)
+
             pmlog.LogInfo("make control message for mqtt topic:",  mqttTopic, "with topicBase:", topicBase, )
 
-            err = this.pg.CreateControlExecutionStealthByPropertyValue(controlName, argument.Pack(), propertyGroupCredential, mqttPropertyTopicBaseName, topicBase)
-            if err != nil {
-                pmlog.LogError("real topic handler error: unable control call ", controlName, "with error:", err.Error())
+            var deliver func(ctx context.Context, attempt int)
+            deliver = func(ctx context.Context, attempt int) {
+                claimed, err := this.pg.CreateControlExecutionStealthByPropertyValueCtx(ctx, controlName, argument.Pack(), propertyGroupCredential, mqttPropertyTopicBaseName, topicBase)
+                if errors.Is(err, pgcore.ErrCircuitOpen) {
+                    this.BacklogMessage(mqttTopic, payload, func(ctx context.Context) {
+                        deliver(ctx, attempt)
+                    })
+                    return
+                }
+                if err != nil {
+                    pmlog.LogError("real topic handler error: unable control call ", controlName, "attempt", attempt, "with error:", err.Error())
+                }
+                if err != nil && attempt < this.config.Queues.DeliveryAttempts && this.appCtx.Err() == nil {
+                    this.RetryMessage(deliveryRetryDelay * time.Second, func(ctx context.Context) {
+                        deliver(ctx, attempt + 1)
+                    })
+                    return
+                }
+                this.DeliveryResult(mqttTopic, topicBase, payload, claimed, err)
             }
+            deliver(ctx, 1)
         }
 
-        controlExecution()
+        controlExecution(ctx)
         //pmlog.LogDebug("do real topic handler rpc call:", controlDecodePayloadName)
         return
     }
 }
 //
 //
-func (this *Application) CheckOrCreateGenericDevice(topicName string, payload []byte) (string, bool, error) {
+func (this *Application) CheckOrCreateGenericDevice(ctx context.Context, topicName string, payload []byte) (string, bool, error) {
     var err             error
     var topicBase       string
     var patternDefined  bool
@@ -1302,30 +1833,10 @@ func (this *Application) CheckOrCreateGenericDevice(topicName string, payload []
         return topicBase, patternDefined , errors.New("zero application code")
     }
 
-    // Checking for Generic
-    objects, err := this.pg.ListObjectsBySchemaId(genericDriverSchemaId)
-    if err != nil {
-        return topicBase, patternDefined , err
-    }
-    
-    //for i := range objects {
-    //    storedTopicBase, _ := this.pg.GetObjectPropertyValue(objects[i].Id, mqttPropertyTopicBaseName)
-    //    if topicBase == storedTopicBase {
-    //        return topicBase, patternDefined , err
-    //    }
-    //}
-
-    // Checink for SENSO8
-    sensoObjects, err := this.pg.ListObjectsBySchemaId(senso8BLEGWDriverSchemaId)
-    if err != nil {
-        return topicBase, patternDefined , err
-    }
-    objects = append(objects, sensoObjects...)
-
-    
-    for i := range objects {
-        storedTopicBase, _ := this.pg.GetObjectPropertyValue(objects[i].Id, mqttPropertyTopicBaseName)
-        if topicBase == storedTopicBase {
+    // Checking for Generic and SENSO8
+    for _, schemaId := range []pgschema.UUID{ genericDriverSchemaId, senso8BLEGWDriverSchemaId } {
+        exists, err := this.deviceExists(ctx, schemaId, topicBase)
+        if err != nil || exists {
             return topicBase, patternDefined , err
         }
     }
@@ -1338,25 +1849,40 @@ func (this *Application) CheckOrCreateGenericDevice(topicName string, payload []
     object.Name             = "Generic MQTT Device "+ nameHint  + " #" + gwCode
     object.Description      = "Generic MQTT Device "+ nameHint  + " #" + gwCode
 
-    objectId, err := this.pg.CreateObject(object)
+    objectId, err := this.pg.CreateObjectCtx(ctx, object)
     if err != nil {
         pmlog.LogError("error create new generic device:", err)
         return topicBase, patternDefined , err
     }
 
-    _, err = this.pg.UpdateObjectPropertyByName(objectId, mqttPropertyBridgeObjectIdName, this.objectId)
+    _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, mqttPropertyBridgeObjectIdName, this.objectId)
     if err != nil {
         pmlog.LogInfo("error update message property:", err)
     }
 
-    _, err = this.pg.UpdateObjectPropertyByName(objectId, mqttPropertyTopicBaseName, topicBase)
+    _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, mqttPropertyTopicBaseName, topicBase)
     if err != nil {
         pmlog.LogInfo("error update message property:", err)
     }
+    this.devices.Register(topicBase, objectId, true, 0)
+    this.presence.Forget(topicBase)
     pmlog.LogInfo("application created new generic device", objectId)
     return topicBase, patternDefined , err
 }
 //
+// deviceExists() looks up device of schema with given topic base on core side
+//
+func (this *Application) deviceExists(ctx context.Context, schemaId pgschema.UUID, topicBase string) (bool, error) {
+    page, err := this.pg.ListObjectsPageCtx(ctx, pgcore.ObjectFilter{
+        SchemaId:       schemaId,
+        GroupName:      propertyGroupCredential,
+        PropertyName:   mqttPropertyTopicBaseName,
+        Value:          topicBase,
+        PageSize:       1,
+    }, "")
+    return len(page.Objects) > 0, err
+}
+//
 // BridgeApp: Piblish()
 //
 func (this *Application) Publish(topic string, payload string) error {
@@ -1379,43 +1905,92 @@ func (this *Application) RouteControlMessage(controlMessage pgcore.ControlExecut
     if err != nil {
         return err
     }
+    if controlMessage.Name == controlSendFirmwareName {
+        return this.FirmwareController(controlMessage)
+    }
+    if controlMessage.Name == controlListUnmatchedName {
+        return this.pg.CreateControlExecutionReport(controlMessage.Id, false, true, this.unmatched.GetJSON())
+    }
+    if controlMessage.Name == controlRequestName {
+        go this.RequestController(controlMessage)
+        return err
+    }
+    if len(controlMessage.ObjectId) > 0 && controlMessage.ObjectId != this.objectId {
+        var report string
+        err = this.DownlinkController(controlMessage)
+        if err != nil {
+            pmlog.LogError("downlink error:", err)
+            report = err.Error()
+        }
+        return this.pg.CreateControlExecutionReport(controlMessage.Id, err != nil, true, report)
+    }
+    err = this.DispatchControl(controlMessage)
+    if err != nil {
+        pmlog.LogError("*** control error:", err)
+    }
+    err = this.pg.CreateControlExecutionEmptyReport(controlMessage.Id, false, true)
+    if err != nil {
+        return err
+    }
+    
+    return err
+}
+//
+// DispatchControl() runs controller of bridge object control,
+// shared by core control executions and admin api
+//
+func (this *Application) DispatchControl(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
     switch  controlMessage.Name {
         case controlPublishName:
-            this.PublishController(controlMessage)
+            err = this.PublishController(controlMessage)
+
+        case controlSendFileName:
+            err = this.SendFileController(controlMessage)
 
         case controlReloadName:
-            this.ReloadController(controlMessage)
+            err = this.ReloadController(controlMessage)
 
         case controlTestModuleName:
-            this.LogController(controlMessage)
+            err = this.LogController(controlMessage)
 
         case controlSetTopicsName:
-            this.SetTopicsController(controlMessage)
+            err = this.SetTopicsController(controlMessage)
 
         case controlSetBrokerURLName:
-            this.SetBrokerURLController(controlMessage)
+            err = this.SetBrokerURLController(controlMessage)
 
         case controlSetUsernameName:
-            this.SetUsernameController(controlMessage)
+            err = this.SetUsernameController(controlMessage)
 
         case controlSetPasswordName:
-            this.SetPasswordController(controlMessage)
+            err = this.SetPasswordController(controlMessage)
 
         case controlSetAutoProvisionName:
-            this.SetAutoProvisionController(controlMessage)
+            err = this.SetAutoProvisionController(controlMessage)
+
+        case controlSetProvisionRuleName:
+            err = this.SetProvisionRuleController(controlMessage)
+
+        case controlSetDeviceLifecycleName:
+            err = this.SetDeviceLifecycleController(controlMessage)
+
+        case controlSetDownlinkRoutesName:
+            err = this.SetDownlinkRoutesController(controlMessage)
+
+        case controlSetForwardRulesName:
+            err = this.SetForwardRulesController(controlMessage)
+
+        case controlSetTopicRulesName:
+            err = this.SetTopicRulesController(controlMessage)
+
+        case controlSetPayloadSchemasName:
+            err = this.SetPayloadSchemasController(controlMessage)
 
         default:
             pmlog.LogError("*** unknown control message:", controlMessage.GetJSON())
             err = errors.New(fmt.Sprintf("unknown control message name %s", controlMessage.Name))
     }
-    //if err != nil {
-    //    pmlog.LogError("*** control error:", err)
-    //}
-    err = this.pg.CreateControlExecutionEmptyReport(controlMessage.Id, false, true)
-    if err != nil {
-        return err
-    }
-    
     return err
 }
 func (this *Application) SetTopicsController(controlMessage pgcore.ControlExecutionMessage) error {
@@ -1450,7 +2025,7 @@ func (this *Application) SetUsernameController(controlMessage pgcore.ControlExec
     var err error
     arguments, _ := UnpackUsernameArguments(controlMessage.Params)
     pmlog.LogInfo("set username:", arguments.Username)
-    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, mqttPropertyUsernameName, arguments.Username)
+    err = this.SetCredentialProperty(mqttPropertyUsernameName, arguments.Username)
     if err != nil {
         return err
     }
@@ -1463,8 +2038,8 @@ func (this *Application) SetUsernameController(controlMessage pgcore.ControlExec
 func (this *Application) SetPasswordController(controlMessage pgcore.ControlExecutionMessage) error {
     var err error
     arguments, _ := UnpackPasswordArguments(controlMessage.Params)
-    pmlog.LogInfo("set password:", arguments.Password)
-    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, mqttPropertyPasswordName, arguments.Password)
+    pmlog.LogInfo("set password:", pmsecret.Mask(arguments.Password))
+    err = this.SetCredentialProperty(mqttPropertyPasswordName, arguments.Password)
     if err != nil {
         return err
     }
@@ -1494,6 +2069,13 @@ func (this *Application) SetAutoProvisionController(controlMessage pgcore.Contro
     return err
 }
 //
+// MessageContext() limits core calls of one ingest message by
+// message timeout, application shutdown cancels it too
+//
+func (this *Application) MessageContext() (context.Context, context.CancelFunc) {
+    return context.WithTimeout(this.appCtx, time.Duration(this.config.Queues.MessageTimeout) * time.Second)
+}
+//
 //
 func (this *Application) LogController(controlMessage pgcore.ControlExecutionMessage) error {
     var err error
@@ -1518,19 +2100,26 @@ func (this *Application) PublishController(controlMessage pgcore.ControlExecutio
     pmlog.LogDetail("*** publish controller full control message:", controlMessage.GetJSON())
     pmlog.LogDetail("*** publish controller message params:", controlMessage.Params)
 
-    arguments, _ := pgcore.UnpackPublishArguments(controlMessage.Params)
+    arguments, err := pgcore.UnpackPublishArguments(controlMessage.Params)
     if err != nil {
         return err
     }
-    pmlog.LogDetail("*** publish controller sent message with topic:", arguments.TopicName, "payload:", string(arguments.Payload))
+    pmlog.LogDetail("*** publish controller sent message with topic:", arguments.TopicName, "payload size:", len(arguments.Payload), "encoding:", arguments.Encoding)
 
-    err = this.tr.Publish(arguments.TopicName, string(arguments.Payload))
+    err = this.tr.PublishBytes(arguments.TopicName, arguments.Payload)
     if err != nil {
         return err
     }
     return err
 }
-
+//
+//
+func parseIntArgument(value string, defaultValue int) (int, error) {
+    if len(value) == 0 {
+        return defaultValue, nil
+    }
+    return strconv.Atoi(value)
+}
 //
 //*********************************************************************//
 //
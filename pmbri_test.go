@@ -0,0 +1,134 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "app/pmcli"
+    "app/pmtopics"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+//
+// TestSchemaFiles fails when pmdata schema files differ from code,
+// run "pmbri schema generate" to update them
+//
+func TestSchemaFiles(t *testing.T) {
+    app := NewApplication()
+    schemas, err := app.GeneratedSchemas()
+    if err != nil {
+        t.Fatal(err)
+    }
+    outdated, err := pmcli.GenerateSchemas(schemas, pmcli.DefaultSchemaDir, true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(outdated) > 0 {
+        t.Error("schema files not up to date:", outdated)
+    }
+}
+
+type testMessage struct {
+    mqtt.Message
+    topic   string
+    payload []byte
+}
+
+func (this testMessage) Topic() string {
+    return this.topic
+}
+
+func (this testMessage) Payload() []byte {
+    return this.payload
+}
+//
+// TestTemporaryTaps checks temporary topic covered by bridge topics
+// is served from topic handler without own subscription
+//
+func TestTemporaryTaps(t *testing.T) {
+    app := NewApplication()
+    app.topics = pmtopics.TopicsFromString("dev/+/status,dev/#")
+
+    calls := 0
+    handler := func(client mqtt.Client, message mqtt.Message) {
+        calls += 1
+    }
+    err := app.SubscribeTemporary("dev/1/ack", handler)
+    if err != nil {
+        t.Fatal("covered topic subscribed:", err)
+    }
+    app.DispatchTaps(nil, testMessage{ topic: "dev/1/ack" })
+    app.DispatchTaps(nil, testMessage{ topic: "dev/2/ack" })
+    if calls != 1 {
+        t.Error("wrong tap calls:", calls)
+    }
+    err = app.UnsubscribeTemporary("dev/1/ack")
+    if err != nil {
+        t.Error("covered topic unsubscribed:", err)
+    }
+    app.DispatchTaps(nil, testMessage{ topic: "dev/1/ack" })
+    if calls != 1 {
+        t.Error("released tap called")
+    }
+    if app.SubscribeTemporary("other/1/ack", handler) == nil {
+        t.Error("uncovered topic not subscribed")
+    }
+}
+//
+// TestRetryMessage checks delayed delivery does not block caller and
+// counts in flight until done
+//
+func TestRetryMessage(t *testing.T) {
+    app := NewApplication()
+    done := make(chan struct{})
+    proceed := make(chan struct{})
+    start := time.Now()
+    app.RetryMessage(10 * time.Millisecond, func(ctx context.Context) {
+        if ctx.Err() != nil {
+            t.Error("retry context done")
+        }
+        <-proceed
+        close(done)
+    })
+    if time.Since(start) >= 10 * time.Millisecond {
+        t.Error("retry blocked caller")
+    }
+    if atomic.LoadInt64(&app.inflight) != 1 {
+        t.Error("retry not counted in flight")
+    }
+    close(proceed)
+    <-done
+    for i := 0; i < 100 && atomic.LoadInt64(&app.inflight) != 0; i++ {
+        time.Sleep(time.Millisecond)
+    }
+    if atomic.LoadInt64(&app.inflight) != 0 {
+        t.Error("retry still in flight")
+    }
+}
+//
+// TestBindCoreStopping checks core bind retries end on shutdown
+//
+func TestBindCoreStopping(t *testing.T) {
+    app := NewApplication()
+    app.appCancel()
+    done := make(chan error, 1)
+    go func() {
+        done <- app.BindCore()
+    }()
+    select {
+        case err := <-done:
+            if !errors.Is(err, errStopping) {
+                t.Error("wrong bind error:", err)
+            }
+        case <-time.After(5 * time.Second):
+            t.Fatal("bind not stopped")
+    }
+}
+//EOF
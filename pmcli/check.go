@@ -0,0 +1,33 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+//
+// Check() tests core and broker connectivity
+//
+func (this *CLI) Check(args []string) error {
+    var err error
+    err = this.connectCore(0)
+    if err != nil {
+        this.printf("core %s: %s\n", this.config.Core.URL, err)
+        return err
+    }
+    bridgeId, err := this.bridgeId()
+    if err != nil {
+        this.printf("core %s: %s\n", this.config.Core.URL, err)
+        return err
+    }
+    this.printf("core %s: ok, bridge object %s\n", this.config.Core.URL, bridgeId)
+
+    transport, brokerURL, err := this.connectBroker()
+    if err != nil {
+        this.printf("broker %s: %s\n", brokerURL, err)
+        return err
+    }
+    transport.Disconnect()
+    this.printf("broker %s: ok\n", brokerURL)
+    return err
+}
+//EOF
@@ -0,0 +1,229 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "app/mqtrans"
+    "app/pgcore"
+    "app/pgschema"
+    "app/pmconfig"
+    "app/pmlog"
+    "app/pmsecret"
+)
+
+const (
+    CommandRun              string  = "run"
+
+    bindRetryInterval       time.Duration   = 3  // sec
+
+    propertyGroupCredential string  = "Credentials"
+    propertyTopicBaseName   string  = "TOPIC_BASE"
+    propertyBridgeName      string  = "BRIDGE"
+    propertyLastSeenName    string  = "LastSeen"
+    propertyBrokerURLName   string  = "BrokerURL"
+    propertyUsernameName    string  = "Username"
+    propertyPasswordName    string  = "Password"
+
+    genericDriverSchemaId   pgschema.UUID   = "6a34e442-cc3c-4586-853e-9058e1fd7739"
+)
+//
+// Command
+//
+// Command is offline operation, run handler gets arguments after
+// command and subcommand names.
+//
+type Command struct {
+    Name        string
+    Sub         string
+    Usage       string
+    Run         func(args []string) error
+}
+//
+// CLI
+//
+// CLI runs commands with bridge config and core session of bridge user.
+//
+type CLI struct {
+    config      *pmconfig.Config
+    profileTags []string
+    commands    []Command
+    out         io.Writer
+    pg          *pgcore.Pixcore
+    generated   map[string]*pgschema.Schema
+}
+
+func New(config *pmconfig.Config, profileTags []string) *CLI {
+    cli := &CLI{
+        config:         config,
+        profileTags:    profileTags,
+        out:            os.Stdout,
+    }
+    cli.commands = []Command{
+        { CommandRun,   "",          "run (default)", nil },
+        { "schema",     "export",    "schema export [schemaId] [file]", cli.SchemaExport },
+        { "schema",     "import",    "schema import [--wait sec] [--force] file|dir ...", cli.SchemaImport },
+        { "schema",     "diff",      "schema diff file", cli.SchemaDiff },
+        { "schema",     "generate",  "schema generate [--check] [dir]", cli.SchemaGenerate },
+        { "devices",    "list",      "devices list", cli.DevicesList },
+        { "devices",    "provision", "devices provision [--schema id] [--name name] topicBase", cli.DevicesProvision },
+        { "devices",    "prune",     "devices prune [--older sec] [--include-never-seen] [--force]", cli.DevicesPrune },
+        { "publish",    "",          "publish [--qos n] topic payload", cli.Publish },
+        { "check",      "",          "check", cli.Check },
+    }
+    return cli
+}
+//
+// SetGenerated() sets schemas defined in code by file name
+//
+func (this *CLI) SetGenerated(schemas map[string]*pgschema.Schema) {
+    this.generated = schemas
+}
+//
+// Usage() writes command list
+//
+func (this *CLI) Usage(writer io.Writer) {
+    fmt.Fprintln(writer, "commands:")
+    for _, command := range this.commands {
+        fmt.Fprintln(writer, "  " + command.Usage)
+    }
+}
+//
+// Run() finds command by arguments and runs it
+//
+func (this *CLI) Run(args []string) error {
+    if len(args) == 0 {
+        return errors.New("command required")
+    }
+    names := make([]string, 0)
+    for _, command := range this.commands {
+        if command.Name != args[0] {
+            continue
+        }
+        if command.Run == nil {
+            return fmt.Errorf("%s: not offline command", args[0])
+        }
+        if len(command.Sub) == 0 {
+            return command.Run(args[1:])
+        }
+        names = append(names, command.Sub)
+        if len(args) > 1 && command.Sub == args[1] {
+            return command.Run(args[2:])
+        }
+    }
+    if len(names) > 0 {
+        sort.Strings(names)
+        return fmt.Errorf("%s: subcommand required: %s", args[0], strings.Join(names, ", "))
+    }
+    return fmt.Errorf("unknown command: %s", args[0])
+}
+
+func (this *CLI) printf(format string, args ...interface{}) {
+    fmt.Fprintf(this.out, format, args...)
+}
+//
+// connectCore() binds core as bridge user, waits up to timeout if given
+//
+func (this *CLI) connectCore(wait int) error {
+    var err error
+    if this.pg != nil {
+        return err
+    }
+    pg := pgcore.New(context.Background())
+    err = pg.Setup(this.config.Core.URL, this.config.Core.Username,
+                    this.config.Core.Password, this.config.Core.JwtTTL, this.profileTags)
+    if err != nil {
+        return err
+    }
+    err = pg.SetMediaURL(this.config.Media.URL)
+    if err != nil {
+        return err
+    }
+    deadline := time.Now().Add(time.Duration(wait) * time.Second)
+    for {
+        err = pg.Bind()
+        if err == nil || time.Now().After(deadline) {
+            break
+        }
+        pmlog.LogInfo("waiting connection to core, error:", err)
+        time.Sleep(bindRetryInterval * time.Second)
+    }
+    if err != nil {
+        return err
+    }
+    this.pg = pg
+    return err
+}
+//
+// bridgeId() returns object id of bridge user profile
+//
+func (this *CLI) bridgeId() (pgschema.UUID, error) {
+    return this.pg.GetUserProfileId()
+}
+//
+// connectBroker() uses config broker or bridge object broker properties
+//
+func (this *CLI) connectBroker() (*mqtrans.Transport, string, error) {
+    var err error
+    brokerURL   := this.config.Broker.URL
+    username    := this.config.Broker.Username
+    password    := this.config.Broker.Password
+    if len(brokerURL) == 0 {
+        err = this.connectCore(0)
+        if err != nil {
+            return nil, brokerURL, err
+        }
+        bridgeId, err := this.bridgeId()
+        if err != nil {
+            return nil, brokerURL, err
+        }
+        brokerURL, _    = this.pg.GetObjectPropertyValue(bridgeId, propertyBrokerURLName)
+        username, _     = this.pg.GetObjectPropertyValue(bridgeId, propertyUsernameName)
+        password, _     = this.pg.GetObjectPropertyValue(bridgeId, propertyPasswordName)
+    }
+    if len(brokerURL) == 0 {
+        return nil, brokerURL, errors.New("broker url not configured")
+    }
+    secrets, err := pmsecret.NewStore(this.config.Secrets.Key, false)
+    if err != nil {
+        return nil, brokerURL, err
+    }
+    username, err = secrets.Decode(username)
+    if err != nil {
+        return nil, brokerURL, err
+    }
+    password, err = secrets.Decode(password)
+    if err != nil {
+        return nil, brokerURL, err
+    }
+    tlsConfig, err := this.config.TLS.TLSConfig()
+    if err != nil {
+        return nil, brokerURL, err
+    }
+    transport := mqtrans.NewTransport()
+    transport.SetTLSConfig(tlsConfig)
+    err = transport.Bind(brokerURL, username, password)
+    if err != nil {
+        return nil, brokerURL, err
+    }
+    return transport, brokerURL, err
+}
+//
+// newFlags() makes command flag set which returns errors
+//
+func newFlags(name string) *flag.FlagSet {
+    flags := flag.NewFlagSet(name, flag.ContinueOnError)
+    return flags
+}
+//EOF
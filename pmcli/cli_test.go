@@ -0,0 +1,72 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "app/pgcore"
+    "app/pgschema"
+    "app/pmconfig"
+)
+
+func TestRun(t *testing.T) {
+    cli := New(pmconfig.New(), nil)
+    err := cli.Run([]string{ "schema" })
+    if err == nil || !strings.Contains(err.Error(), "diff, export, generate, import") {
+        t.Error("missing subcommand not reported", err)
+    }
+    err = cli.Run([]string{ "unknown" })
+    if err == nil {
+        t.Error("unknown command accepted")
+    }
+    err = cli.Run([]string{ CommandRun })
+    if err == nil {
+        t.Error("run command accepted as offline command")
+    }
+    err = cli.Run([]string{ "publish", "--qos", "3", "a/b", "x" })
+    if err == nil || !strings.Contains(err.Error(), "qos") {
+        t.Error("wrong qos accepted", err)
+    }
+}
+
+func TestDiffProperties(t *testing.T) {
+    local := []*pgschema.Property{
+        { Property: "Status",   Type: "bool",   GroupName: "HealthCheck" },
+        { Property: "Topics",   Type: "string", GroupName: "Settings", DefaultValue: "gw/#" },
+        { Property: "Timeout",  Type: "int",    GroupName: "Settings" },
+    }
+    remote := []pgcore.SchemaProperty{
+        { Property: "Status",   Type: "bool",   GroupName: "HealthCheck" },
+        { Property: "Topics",   Type: "string", GroupName: "Settings", DefaultValue: "#" },
+        { Property: "Legacy",   Type: "string", GroupName: "Settings" },
+    }
+    changes := DiffProperties(local, remote)
+    expected := []string{
+        "- Legacy",
+        "+ Timeout",
+        `~ Topics: default "#" -> "gw/#"`,
+    }
+    if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+        t.Error("wrong diff", changes)
+    }
+}
+
+func TestDeviceStale(t *testing.T) {
+    now := time.Now()
+    device := Device{ LastSeen: now.Add(-time.Hour).UTC().Format(time.RFC3339) }
+    if device.Stale(now.Add(-2 * time.Hour)) || !device.Stale(now) {
+        t.Error("wrong stale state", device.LastSeen)
+    }
+    if device.NeverSeen() {
+        t.Error("seen device reported never seen")
+    }
+    device.LastSeen = ""
+    if device.Stale(now) || !device.NeverSeen() {
+        t.Error("never seen device reported stale")
+    }
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "app/pgcore"
+    "app/pgschema"
+    "app/pmtools"
+)
+
+const (
+    defaultPruneAge     int     = 30 * 24 * 3600 // sec
+)
+//
+// Device
+//
+// Device is object created or adopted by bridge, e.g. it has
+// bridge object id in BRIDGE property.
+//
+type Device struct {
+    Object      pgschema.Object
+    TopicBase   string
+    LastSeen    string
+}
+//
+// Stale() reports device not seen since time, never seen device is not
+// stale, e.g. it may be just provisioned
+//
+func (this *Device) Stale(since time.Time) bool {
+    lastSeen, err := time.Parse(time.RFC3339, this.LastSeen)
+    if err != nil {
+        return false
+    }
+    return lastSeen.Before(since)
+}
+
+func (this *Device) NeverSeen() bool {
+    _, err := time.Parse(time.RFC3339, this.LastSeen)
+    return err != nil
+}
+
+func (this *CLI) listDevices() ([]Device, error) {
+    var err error
+    result := make([]Device, 0)
+    err = this.connectCore(0)
+    if err != nil {
+        return result, err
+    }
+    bridgeId, err := this.bridgeId()
+    if err != nil {
+        return result, err
+    }
+    items, err := this.pg.ListObjectItems(pgcore.ObjectFilter{
+        GroupName:      propertyGroupCredential,
+        PropertyName:   propertyBridgeName,
+        Value:          bridgeId,
+        Properties:     []string{ propertyTopicBaseName, propertyLastSeenName },
+    })
+    if err != nil {
+        return result, err
+    }
+    for _, item := range items {
+        var device Device
+        device.Object       = item.Object
+        device.TopicBase    = item.Properties[propertyTopicBaseName]
+        device.LastSeen     = item.Properties[propertyLastSeenName]
+        result = append(result, device)
+    }
+    return result, err
+}
+//
+// DevicesList() prints bridge managed devices
+//
+func (this *CLI) DevicesList(args []string) error {
+    var err error
+    devices, err := this.listDevices()
+    if err != nil {
+        return err
+    }
+    for _, device := range devices {
+        lastSeen := device.LastSeen
+        if len(lastSeen) == 0 {
+            lastSeen = "never"
+        }
+        this.printf("%s\t%t\t%s\t%s\t%s\n", device.Object.Id, device.Object.Enabled,
+                        device.TopicBase, lastSeen, device.Object.Name)
+    }
+    return err
+}
+//
+// DevicesProvision() creates device object for topic base
+//
+func (this *CLI) DevicesProvision(args []string) error {
+    var err error
+    flags := newFlags("devices provision")
+    schemaId    := flags.String("schema", genericDriverSchemaId, "device schema id")
+    name        := flags.String("name", "", "device name, topic base by default")
+    err = flags.Parse(args)
+    if err != nil {
+        return err
+    }
+    if flags.NArg() != 1 {
+        return errors.New("devices provision: topic base required")
+    }
+    topicBase := flags.Arg(0)
+
+    devices, err := this.listDevices()
+    if err != nil {
+        return err
+    }
+    for _, device := range devices {
+        if device.TopicBase == topicBase {
+            return fmt.Errorf("devices provision: topic base %s used by %s", topicBase, device.Object.Id)
+        }
+    }
+    bridgeId, err := this.bridgeId()
+    if err != nil {
+        return err
+    }
+
+    object := pgschema.NewObject()
+    object.Id               = pmtools.GetNewUUID()
+    object.SchemaId         = *schemaId
+    object.Name             = *name
+    if len(object.Name) == 0 {
+        object.Name = topicBase
+    }
+    object.Description      = object.Name
+
+    objectId, err := this.pg.CreateObject(object)
+    if err != nil {
+        return err
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(objectId, propertyBridgeName, bridgeId)
+    if err != nil {
+        return err
+    }
+    _, err = this.pg.UpdateObjectPropertyByName(objectId, propertyTopicBaseName, topicBase)
+    if err != nil {
+        return err
+    }
+    this.printf("created %s for %s\n", objectId, topicBase)
+    return err
+}
+//
+// DevicesPrune() deletes managed devices not seen within age, without
+// force flag it prints devices to delete only
+//
+func (this *CLI) DevicesPrune(args []string) error {
+    var err error
+    flags := newFlags("devices prune")
+    older       := flags.Int("older", defaultPruneAge, "delete devices not seen for sec")
+    neverSeen   := flags.Bool("include-never-seen", false, "delete also devices never seen")
+    force       := flags.Bool("force", false, "delete devices, without it only print them")
+    err = flags.Parse(args)
+    if err != nil {
+        return err
+    }
+    if *older <= 0 {
+        return errors.New("devices prune: age must be positive")
+    }
+    devices, err := this.listDevices()
+    if err != nil {
+        return err
+    }
+    since := time.Now().Add(-time.Duration(*older) * time.Second)
+    for i := range devices {
+        device := devices[i]
+        if !device.Stale(since) && !(*neverSeen && device.NeverSeen()) {
+            continue
+        }
+        if !*force {
+            this.printf("would delete %s %s\n", device.Object.Id, device.TopicBase)
+            continue
+        }
+        err = this.pg.DeleteObject(device.Object.Id)
+        if err != nil {
+            return err
+        }
+        this.printf("deleted %s %s\n", device.Object.Id, device.TopicBase)
+    }
+    return err
+}
+//EOF
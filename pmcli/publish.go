@@ -0,0 +1,46 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+import (
+    "errors"
+    "strconv"
+    "time"
+)
+
+const (
+    publishQuiesce      time.Duration   = 1 // sec
+)
+//
+// Publish() sends one message to bridge broker
+//
+func (this *CLI) Publish(args []string) error {
+    var err error
+    flags := newFlags("publish")
+    qos := flags.Int("qos", 0, "message qos 0..2")
+    err = flags.Parse(args)
+    if err != nil {
+        return err
+    }
+    if flags.NArg() != 2 {
+        return errors.New("publish: topic and payload required")
+    }
+    if *qos < 0 || *qos > 2 {
+        return errors.New("publish: wrong qos " + strconv.Itoa(*qos))
+    }
+    transport, brokerURL, err := this.connectBroker()
+    if err != nil {
+        return err
+    }
+    defer transport.Close(publishQuiesce * time.Second)
+
+    err = transport.PublishQos(flags.Arg(0), byte(*qos), []byte(flags.Arg(1)))
+    if err != nil {
+        return err
+    }
+    this.printf("published to %s %s\n", brokerURL, flags.Arg(0))
+    return err
+}
+//EOF
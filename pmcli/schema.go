@@ -0,0 +1,275 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmcli
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "app/pgcore"
+    "app/pgschema"
+)
+
+const DefaultSchemaDir string = "pmdata/schemas"
+
+var ErrSchemaDiffers = errors.New("schema differs")
+var ErrSchemaOutdated = errors.New("schema files not up to date")
+//
+// SchemaExport() writes core schema to file or stdout
+//
+func (this *CLI) SchemaExport(args []string) error {
+    var err error
+    schemaId := this.config.AppSchemaId
+    if len(args) > 0 {
+        schemaId = args[0]
+    }
+    err = this.connectCore(0)
+    if err != nil {
+        return err
+    }
+    schema, err := this.pg.ExportSchema(schemaId)
+    if err != nil {
+        return err
+    }
+    if len(args) > 1 {
+        return ioutil.WriteFile(args[1], []byte(schema), 0644)
+    }
+    this.printf("%s\n", schema)
+    return err
+}
+//
+// SchemaImport() imports schema files, directories are walked for json files
+//
+func (this *CLI) SchemaImport(args []string) error {
+    var err error
+    flags := newFlags("schema import")
+    wait    := flags.Int("wait", 0, "wait for core up to sec")
+    force   := flags.Bool("force", false, "import even if deployed version is the same")
+    err = flags.Parse(args)
+    if err != nil {
+        return err
+    }
+    if flags.NArg() == 0 {
+        return errors.New("schema import: file or directory required")
+    }
+    fileNames, err := schemaFiles(flags.Args())
+    if err != nil {
+        return err
+    }
+    err = this.connectCore(*wait)
+    if err != nil {
+        return err
+    }
+    for _, fileName := range fileNames {
+        data, err := ioutil.ReadFile(fileName)
+        if err != nil {
+            return err
+        }
+        var schema pgschema.Schema
+        err = json.Unmarshal(data, &schema)
+        if err != nil {
+            return fmt.Errorf("%s: %s", fileName, err)
+        }
+        if schema.Metadata != nil && !*force {
+            version, exists, err := this.pg.GetSchemaVersion(schema.Metadata.Id)
+            if err != nil {
+                return err
+            }
+            if exists && version == schema.Metadata.MVersion {
+                this.printf("skipped %s, version %s deployed\n", fileName, version)
+                continue
+            }
+        }
+        schemaId, err := this.pg.ImportSchema(string(data))
+        if err != nil {
+            return fmt.Errorf("%s: %s", fileName, err)
+        }
+        this.printf("imported %s as %s\n", fileName, schemaId)
+    }
+    return err
+}
+
+//
+// SchemaGenerate() writes schema files from code definitions, with
+// --check only reports outdated files
+//
+func (this *CLI) SchemaGenerate(args []string) error {
+    var err error
+    flags := newFlags("schema generate")
+    check := flags.Bool("check", false, "only check files are up to date")
+    err = flags.Parse(args)
+    if err != nil {
+        return err
+    }
+    dir := DefaultSchemaDir
+    if flags.NArg() > 0 {
+        dir = flags.Arg(0)
+    }
+    outdated, err := GenerateSchemas(this.generated, dir, *check)
+    for _, fileName := range outdated {
+        if *check {
+            this.printf("outdated %s\n", fileName)
+        } else {
+            this.printf("generated %s\n", fileName)
+        }
+    }
+    if err != nil {
+        return err
+    }
+    if *check && len(outdated) > 0 {
+        return ErrSchemaOutdated
+    }
+    return err
+}
+//
+// GenerateSchemas() writes or checks schema files, returns changed files
+//
+func GenerateSchemas(schemas map[string]*pgschema.Schema, dir string, check bool) ([]string, error) {
+    var err error
+    result := make([]string, 0)
+    names := make([]string, 0, len(schemas))
+    for name := range schemas {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        fileName := filepath.Join(dir, name)
+        if check {
+            upToDate, err := schemas[name].FileUpToDate(fileName)
+            if err != nil {
+                return result, err
+            }
+            if !upToDate {
+                result = append(result, fileName)
+            }
+            continue
+        }
+        written, err := schemas[name].WriteFile(fileName)
+        if err != nil {
+            return result, err
+        }
+        if written {
+            result = append(result, fileName)
+        }
+    }
+    return result, err
+}
+
+func schemaFiles(paths []string) ([]string, error) {
+    var err error
+    result := make([]string, 0)
+    for _, path := range paths {
+        info, err := os.Stat(path)
+        if err != nil {
+            return result, err
+        }
+        if !info.IsDir() {
+            result = append(result, path)
+            continue
+        }
+        err = filepath.Walk(path, func(fileName string, info os.FileInfo, err error) error {
+            if err != nil {
+                return err
+            }
+            if !info.IsDir() && strings.HasSuffix(fileName, ".json") {
+                result = append(result, fileName)
+            }
+            return err
+        })
+        if err != nil {
+            return result, err
+        }
+    }
+    return result, err
+}
+//
+// SchemaDiff() compares schema file properties with core schema
+//
+func (this *CLI) SchemaDiff(args []string) error {
+    var err error
+    if len(args) == 0 {
+        return errors.New("schema diff: file required")
+    }
+    data, err := ioutil.ReadFile(args[0])
+    if err != nil {
+        return err
+    }
+    var schema pgschema.Schema
+    err = json.Unmarshal(data, &schema)
+    if err != nil {
+        return err
+    }
+    if schema.Metadata == nil || len(schema.Metadata.Id) == 0 {
+        return errors.New("schema diff: schema id not defined in file")
+    }
+    err = this.connectCore(0)
+    if err != nil {
+        return err
+    }
+    remote, err := this.pg.ListSchemaProperties(schema.Metadata.Id)
+    if err != nil {
+        return err
+    }
+    changes := DiffProperties(schema.Properties, remote)
+    for _, change := range changes {
+        this.printf("%s\n", change)
+    }
+    if len(changes) > 0 {
+        return ErrSchemaDiffers
+    }
+    return err
+}
+//
+// DiffProperties() lists added (+), removed (-) and changed (~) properties
+// of local schema against core schema properties
+//
+func DiffProperties(local []*pgschema.Property, remote []pgcore.SchemaProperty) []string {
+    result := make([]string, 0)
+    remoteMap := make(map[string]pgcore.SchemaProperty)
+    for _, property := range remote {
+        remoteMap[property.Property] = property
+    }
+    localMap := make(map[string]bool)
+    for _, property := range local {
+        localMap[property.Property] = true
+        stored, exists := remoteMap[property.Property]
+        if !exists {
+            result = append(result, "+ " + property.Property)
+            continue
+        }
+        fields := make([]string, 0)
+        if property.Type != stored.Type {
+            fields = append(fields, fmt.Sprintf("type %s -> %s", stored.Type, property.Type))
+        }
+        if property.GroupName != stored.GroupName {
+            fields = append(fields, fmt.Sprintf("group %s -> %s", stored.GroupName, property.GroupName))
+        }
+        if property.DefaultValue != stored.DefaultValue {
+            fields = append(fields, fmt.Sprintf("default %q -> %q", stored.DefaultValue, property.DefaultValue))
+        }
+        if property.Hidden != stored.Hidden {
+            fields = append(fields, fmt.Sprintf("hidden %t -> %t", stored.Hidden, property.Hidden))
+        }
+        if len(fields) > 0 {
+            result = append(result, "~ " + property.Property + ": " + strings.Join(fields, ", "))
+        }
+    }
+    for name := range remoteMap {
+        if !localMap[name] {
+            result = append(result, "- " + name)
+        }
+    }
+    sort.SliceStable(result, func(i, j int) bool {
+        return result[i][2:] < result[j][2:]
+    })
+    return result
+}
+//EOF
@@ -7,6 +7,7 @@ package pmconfig
 import (
     "io/ioutil"
     "encoding/json"
+    "fmt"
     "os"
 
     "app/pgschema"
@@ -14,17 +15,84 @@ import (
     "github.com/go-yaml/yaml"
 )
 
+//
+// Config
+//
+// Config is bridge startup configuration. Values come from defaults,
+// yaml file next to executable and CONFIG_* environment variables,
+// in this order. Broker section is used only when bridge object has
+// no broker properties set.
+//
 type Config struct {
     Version             string          `yaml:"-"           json:"-"`
 
+    Debug               bool            `yaml:"debug"       json:"debug"`
     AppSchemaId         pgschema.UUID   `yaml:"schemaId"    json:"schemaId"`
+    ShutdownTimeout     int             `yaml:"shutdownTimeout" json:"shutdownTimeout"`   // sec
+    DataDir             string          `yaml:"dataDir"     json:"dataDir"`     // state files, executable dir if empty
 
     Core                Core            `yaml:"core"        json:"core"`
     Media               Media           `yaml:"media"       json:"media"`
+    Broker              Broker          `yaml:"broker"      json:"broker"`
+    TLS                 TLS             `yaml:"tls"         json:"tls"`
+    Log                 Log             `yaml:"log"         json:"log"`
+    Queues              Queues          `yaml:"queues"      json:"queues"`
+    Metrics             Metrics         `yaml:"metrics"     json:"metrics"`
+    Secrets             Secrets         `yaml:"secrets"     json:"secrets"`
+    Admin               Admin           `yaml:"admin"       json:"admin"`
+    Health              Health          `yaml:"health"      json:"health"`
+}
+
+//
+// Secrets
+//
+// Key encrypts broker credentials stored in bridge object properties
+// when EncryptBroker is set. Any string key of config may be given as
+// ${env:NAME} or ${file:/path} reference.
+//
+type Secrets struct {
+    Key             string  `yaml:"key"             json:"key"`
+    EncryptBroker   bool    `yaml:"encryptBroker"   json:"encryptBroker"`
+}
+
+type Health struct {
+    Listen      string          `yaml:"listen"      json:"listen"`      // empty disables probes
+    LiveWindow  int             `yaml:"liveWindow"  json:"liveWindow"`  // sec
+}
+
+type Admin struct {
+    Listen      string          `yaml:"listen"      json:"listen"`      // empty disables admin api
+    Token       string          `yaml:"token"       json:"token"`       // bearer token, required off loopback
+}
+
+type Metrics struct {
+    Listen      string          `yaml:"listen"      json:"listen"`      // empty disables metrics
+    Path        string          `yaml:"path"        json:"path"`
+}
+
+type Queues struct {
+    RecentSize          int     `yaml:"recentSize"          json:"recentSize"`
+    UnmatchedSize       int     `yaml:"unmatchedSize"       json:"unmatchedSize"`
+    PresenceSize        int     `yaml:"presenceSize"        json:"presenceSize"`    // tracked topic bases
+    DeliveryAttempts    int     `yaml:"deliveryAttempts"    json:"deliveryAttempts"`
+    BacklogSize         int     `yaml:"backlogSize"         json:"backlogSize"`     // buffered while core is down
+    MessageTimeout      int     `yaml:"messageTimeout"      json:"messageTimeout"`  // sec, core calls of one message
+}
+
+type Log struct {
+    Level       string          `yaml:"level"       json:"level"`       // debug, info, warning, error
+}
+
+type TLS struct {
+    CAFile      string          `yaml:"caFile"      json:"caFile"`
+    CertFile    string          `yaml:"certFile"    json:"certFile"`
+    KeyFile     string          `yaml:"keyFile"     json:"keyFile"`
+    Insecure    bool            `yaml:"insecure"    json:"insecure"`    // skip server verification
 }
 
 type Media struct {
-    URL     string                      `yaml:"url"         json:"url"`
+    URL         string          `yaml:"url"         json:"url"`
+    SchemaId    pgschema.UUID   `yaml:"schemaId"    json:"schemaId"`
 }
 
 type Core struct {
@@ -32,13 +100,44 @@ type Core struct {
     Username    string  `yaml:"username"    json:"username"`
     Password    string  `yaml:"password"    json:"password"`
     JwtTTL      int     `yaml:"tokenttl"    json:"tokenttl"`
+    JwtSecret   string  `yaml:"jwtSecret"   json:"jwtSecret"`
+    JwtKeyFile  string  `yaml:"jwtKeyFile"  json:"jwtKeyFile"`
+    JwksURL     string  `yaml:"jwksURL"     json:"jwksURL"`
+
+    Timeout             int `yaml:"timeout"             json:"timeout"`             // sec
+    Attempts            int `yaml:"attempts"            json:"attempts"`            // of idempotent query
+    BreakerThreshold    int `yaml:"breakerThreshold"    json:"breakerThreshold"`    // 0 disables breaker
+    BreakerCooldown     int `yaml:"breakerCooldown"     json:"breakerCooldown"`     // sec
 }
 
 type Broker struct {
-    Hostname    string  `yaml:"hostname"    json:"hostname"`
+    URL         string  `yaml:"url"         json:"url"`
     Username    string  `yaml:"username"    json:"username"`
     Password    string  `yaml:"password"    json:"password"`
-    Port        int     `yaml:"port"        json:"port"`
+}
+
+const maskedSecret string = "******"
+//
+// Masked() returns config copy with hidden secrets
+//
+func (this *Config) Masked() *Config {
+    masked := *this
+    if len(masked.Core.Password) > 0 {
+        masked.Core.Password = maskedSecret
+    }
+    if len(masked.Core.JwtSecret) > 0 {
+        masked.Core.JwtSecret = maskedSecret
+    }
+    if len(masked.Broker.Password) > 0 {
+        masked.Broker.Password = maskedSecret
+    }
+    if len(masked.Secrets.Key) > 0 {
+        masked.Secrets.Key = maskedSecret
+    }
+    if len(masked.Admin.Token) > 0 {
+        masked.Admin.Token = maskedSecret
+    }
+    return &masked
 }
 
 func (this *Config) GetJSON() string {
@@ -64,15 +163,25 @@ func (this *Config) Write(fileName string) error {
     return ioutil.WriteFile(fileName, data, 0640)
 }
 
+//
+// Read() reads yaml file, missing file is not error
+//
 func (this *Config) Read(fileName string) error {
     var data []byte
     var err error
 
     data, err = ioutil.ReadFile(fileName)
+    if os.IsNotExist(err) {
+        return nil
+    }
     if  err != nil {
         return err
     }
-    return yaml.Unmarshal(data, &this)
+    err = yaml.Unmarshal(data, this)
+    if err != nil {
+        return fmt.Errorf("config file %s: %s", fileName, err)
+    }
+    return err
 }
 
 func New() *Config {
@@ -80,19 +189,45 @@ func New() *Config {
         URL:            "http://127.0.0.1:5000/graphql",
 
         Username:       "mqttbridge",
-        Password:       "weEfTgIeR8tn6Fx61WOc3nKiJEPfqieE",
         JwtTTL:         5, // min
+
+        Timeout:            30,
+        Attempts:           3,
+        BreakerThreshold:   5,
+        BreakerCooldown:    30,
     }
     media := Media{
         URL:            "http://127.0.0.1:5001",
     }
+    health := Health{
+        LiveWindow:     60,
+    }
+    log := Log{
+        Level:          LogLevelInfo,
+    }
+    queues := Queues{
+        RecentSize:         50,
+        UnmatchedSize:      100,
+        PresenceSize:       10000,
+        DeliveryAttempts:   3,
+        BacklogSize:        1000,
+        MessageTimeout:     60,
+    }
+    metrics := Metrics{
+        Path:           "/metrics",
+    }
 
     return &Config{
         Version:            "1.0",
 
         AppSchemaId:        "220fcefa-46d3-4f6b-8081-28e5b4b2824b",     // may overlap from file config
+        ShutdownTimeout:    20,
 
         Core:               core,
         Media:              media,
+        Log:                log,
+        Queues:             queues,
+        Metrics:            metrics,
+        Health:             health,
     }
 }
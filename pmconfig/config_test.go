@@ -0,0 +1,94 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmconfig
+
+import (
+    "os"
+    "strings"
+    "testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+    env := map[string]string{
+        "CONFIG_API_PASSWORD":          "legacy",
+        "CONFIG_CORE_URL":              "https://core.example.com/graphql",
+        "CONFIG_QUEUES_RECENTSIZE":     "10",
+        "CONFIG_TLS_INSECURE":          "true",
+        "CONFIG_DEBUG":                 "1",
+    }
+    lookup := func(name string) (string, bool) {
+        value, exists := env[name]
+        return value, exists
+    }
+    config := New()
+    err := config.applyEnv(lookup)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if config.Core.Password != "legacy" || config.Core.URL != env["CONFIG_CORE_URL"] {
+        t.Error("core keys not overridden", config.Core)
+    }
+    if config.Queues.RecentSize != 10 || !config.TLS.Insecure || config.LogLevel() != LogLevelDebug {
+        t.Error("typed keys not overridden", config.Queues, config.TLS, config.Debug)
+    }
+
+    env["CONFIG_HEALTH_LIVEWINDOW"] = "soon"
+    err = New().applyEnv(lookup)
+    if err == nil || !strings.Contains(err.Error(), "CONFIG_HEALTH_LIVEWINDOW") {
+        t.Error("wrong value not reported", err)
+    }
+}
+
+func TestValidate(t *testing.T) {
+    config := New()
+    config.Core.Password = "secret"
+    err := config.Validate()
+    if err != nil {
+        t.Fatal("default config invalid:", err)
+    }
+    config.Core.URL         = "127.0.0.1:5000"
+    config.Log.Level        = "trace"
+    config.TLS.CertFile     = "/nonexistent/cert.pem"
+    config.Core.Password    = ""
+    config.Admin.Listen     = ":8081"
+    err = config.Validate()
+    if err == nil {
+        t.Fatal("wrong config accepted")
+    }
+    for _, key := range []string{ "core.URL", "log.level", "tls.certFile", "tls: certFile", "core.password", "admin.token" } {
+        if !strings.Contains(err.Error(), key) {
+            t.Error("problem not reported:", key)
+        }
+    }
+}
+
+func TestMasked(t *testing.T) {
+    config := New()
+    config.Core.Password    = "core-secret"
+    config.Broker.Password  = "broker-secret"
+    config.Secrets.Key      = "key-secret"
+    config.Admin.Token      = "token-secret"
+    masked := config.Masked().GetYaml()
+    if strings.Contains(masked, "-secret") {
+        t.Error("secret in masked config")
+    }
+}
+
+func TestResolveSecrets(t *testing.T) {
+    os.Setenv("PMCONFIG_TEST_PASSWORD", "from-env")
+    defer os.Unsetenv("PMCONFIG_TEST_PASSWORD")
+
+    config := New()
+    config.Core.Password = "${env:PMCONFIG_TEST_PASSWORD}"
+    err := config.ResolveSecrets()
+    if err != nil || config.Core.Password != "from-env" {
+        t.Error("reference not resolved", config.Core.Password, err)
+    }
+    config.Broker.Password = "${file:/nonexistent/password}"
+    err = config.ResolveSecrets()
+    if err == nil || !strings.Contains(err.Error(), "CONFIG_BROKER_PASSWORD") {
+        t.Error("wrong reference not reported", err)
+    }
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmconfig
+
+import (
+    "fmt"
+    "os"
+    "reflect"
+    "strconv"
+    "strings"
+
+    "app/pmsecret"
+)
+
+const EnvPrefix string = "CONFIG"
+//
+// Every config key has variable named by prefix and yaml key path,
+// e.g. core.password is CONFIG_CORE_PASSWORD, queues.recentSize
+// is CONFIG_QUEUES_RECENTSIZE. Old CONFIG_API_* names still work.
+//
+var legacyEnv = map[string]string{
+    "CONFIG_API_URL":       "CONFIG_CORE_URL",
+    "CONFIG_API_USERNAME":  "CONFIG_CORE_USERNAME",
+    "CONFIG_API_PASSWORD":  "CONFIG_CORE_PASSWORD",
+}
+//
+// ApplyEnv() overrides config keys by process environment
+//
+func (this *Config) ApplyEnv() error {
+    return this.applyEnv(os.LookupEnv)
+}
+
+func (this *Config) applyEnv(lookup func(string) (string, bool)) error {
+    var err error
+    for legacy, name := range legacyEnv {
+        value, exists := lookup(legacy)
+        if !exists || len(value) == 0 {
+            continue
+        }
+        if _, current := lookup(name); current {
+            continue
+        }
+        err = this.setEnvValue(name, value)
+        if err != nil {
+            return err
+        }
+    }
+    return walkKeys(reflect.ValueOf(this).Elem(), EnvPrefix, func(name string, field reflect.Value) error {
+        value, exists := lookup(name)
+        if !exists {
+            return nil
+        }
+        return setValue(name, field, value)
+    })
+}
+
+func (this *Config) setEnvValue(name string, value string) error {
+    return walkKeys(reflect.ValueOf(this).Elem(), EnvPrefix, func(key string, field reflect.Value) error {
+        if key != name {
+            return nil
+        }
+        return setValue(key, field, value)
+    })
+}
+//
+// ResolveSecrets() replaces ${env:...} and ${file:...} references
+//
+func (this *Config) ResolveSecrets() error {
+    return walkKeys(reflect.ValueOf(this).Elem(), EnvPrefix, func(name string, field reflect.Value) error {
+        if field.Kind() != reflect.String || !pmsecret.IsReference(field.String()) {
+            return nil
+        }
+        value, err := pmsecret.Resolve(field.String())
+        if err != nil {
+            return fmt.Errorf("%s: %s", name, err)
+        }
+        field.SetString(value)
+        return nil
+    })
+}
+//
+// EnvNames() lists all variable names
+//
+func (this *Config) EnvNames() []string {
+    names := make([]string, 0)
+    walkKeys(reflect.ValueOf(this).Elem(), EnvPrefix, func(name string, field reflect.Value) error {
+        names = append(names, name)
+        return nil
+    })
+    return names
+}
+
+func walkKeys(value reflect.Value, prefix string, handler func(string, reflect.Value) error) error {
+    var err error
+    valueType := value.Type()
+    for i := 0; i < value.NumField(); i++ {
+        key := strings.Split(valueType.Field(i).Tag.Get("yaml"), ",")[0]
+        if len(key) == 0 || key == "-" {
+            continue
+        }
+        name  := prefix + "_" + strings.ToUpper(key)
+        field := value.Field(i)
+        if field.Kind() == reflect.Struct {
+            err = walkKeys(field, name, handler)
+        } else {
+            err = handler(name, field)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+
+func setValue(name string, field reflect.Value, value string) error {
+    var err error
+    switch field.Kind() {
+        case reflect.String:
+            field.SetString(value)
+        case reflect.Int, reflect.Int64:
+            number, err := strconv.ParseInt(value, 10, 64)
+            if err != nil {
+                return fmt.Errorf("env %s: not integer: %s", name, value)
+            }
+            field.SetInt(number)
+        case reflect.Bool:
+            flag, err := strconv.ParseBool(value)
+            if err != nil {
+                return fmt.Errorf("env %s: not boolean: %s", name, value)
+            }
+            field.SetBool(flag)
+        default:
+            return fmt.Errorf("env %s: unsupported type %s", name, field.Kind())
+    }
+    return err
+}
+//EOF
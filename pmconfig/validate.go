@@ -0,0 +1,236 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmconfig
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net"
+    "net/url"
+    "os"
+    "strings"
+
+    "app/pgjwt"
+    "app/pmadmin"
+)
+
+const (
+    LogLevelDebug       string  = "debug"
+    LogLevelInfo        string  = "info"
+    LogLevelWarning     string  = "warning"
+    LogLevelError       string  = "error"
+)
+//
+// Validate() checks all keys and reports every problem at once
+//
+func (this *Config) Validate() error {
+    var err error
+    problems := make([]string, 0)
+    report := func(format string, args ...interface{}) {
+        problems = append(problems, fmt.Sprintf(format, args...))
+    }
+
+    checkURL := func(key string, value string, schemes ...string) {
+        parsed, err := url.Parse(value)
+        if err != nil || len(parsed.Host) == 0 {
+            report("%s: wrong url: %s", key, value)
+            return
+        }
+        for _, scheme := range schemes {
+            if parsed.Scheme == scheme {
+                return
+            }
+        }
+        report("%s: scheme must be one of %s", key, strings.Join(schemes, ", "))
+    }
+    checkListen := func(key string, value string) {
+        if len(value) == 0 {
+            return
+        }
+        _, _, err := net.SplitHostPort(value)
+        if err != nil {
+            report("%s: wrong listen address: %s", key, value)
+        }
+    }
+    checkFile := func(key string, value string) {
+        if len(value) == 0 {
+            return
+        }
+        if _, err := os.Stat(value); err != nil {
+            report("%s: %s", key, err)
+        }
+    }
+
+    if len(this.AppSchemaId) == 0 {
+        report("schemaId: empty")
+    }
+    if this.ShutdownTimeout < 0 {
+        report("shutdownTimeout: negative value")
+    }
+    if len(this.DataDir) > 0 {
+        info, err := os.Stat(this.DataDir)
+        if err != nil {
+            report("dataDir: %s", err)
+        } else if !info.IsDir() {
+            report("dataDir: not a directory: %s", this.DataDir)
+        }
+    }
+
+    checkURL("core.URL", this.Core.URL, "http", "https")
+    if len(this.Core.Username) == 0 {
+        report("core.username: empty")
+    }
+    if len(this.Core.Password) == 0 {
+        report("core.password: empty")
+    }
+    if this.Core.JwtTTL <= 0 {
+        report("core.tokenttl: must be positive")
+    }
+    if this.Core.Timeout <= 0 {
+        report("core.timeout: must be positive")
+    }
+    if this.Core.Attempts <= 0 {
+        report("core.attempts: must be positive")
+    }
+    if this.Core.BreakerThreshold < 0 {
+        report("core.breakerThreshold: negative value")
+    }
+    if this.Core.BreakerCooldown <= 0 {
+        report("core.breakerCooldown: must be positive")
+    }
+    checkFile("core.jwtKeyFile", this.Core.JwtKeyFile)
+    if len(this.Core.JwksURL) > 0 {
+        checkURL("core.jwksURL", this.Core.JwksURL, "http", "https")
+    }
+    checkURL("media.url", this.Media.URL, "http", "https")
+    if len(this.Broker.URL) > 0 {
+        checkURL("broker.url", this.Broker.URL, "tcp", "ssl", "tls", "ws", "wss", "mqtt", "mqtts")
+    }
+
+    checkFile("tls.caFile",   this.TLS.CAFile)
+    checkFile("tls.certFile", this.TLS.CertFile)
+    checkFile("tls.keyFile",  this.TLS.KeyFile)
+    if (len(this.TLS.CertFile) == 0) != (len(this.TLS.KeyFile) == 0) {
+        report("tls: certFile and keyFile must be set together")
+    }
+
+    if this.Secrets.EncryptBroker && len(this.Secrets.Key) == 0 {
+        report("secrets.key: required to encrypt broker credentials")
+    }
+
+    switch this.Log.Level {
+        case LogLevelDebug, LogLevelInfo, LogLevelWarning, LogLevelError:
+        default:
+            report("log.level: unknown level: %s", this.Log.Level)
+    }
+
+    if this.Queues.RecentSize <= 0 {
+        report("queues.recentSize: must be positive")
+    }
+    if this.Queues.UnmatchedSize <= 0 {
+        report("queues.unmatchedSize: must be positive")
+    }
+    if this.Queues.PresenceSize <= 0 {
+        report("queues.presenceSize: must be positive")
+    }
+    if this.Queues.DeliveryAttempts <= 0 {
+        report("queues.deliveryAttempts: must be positive")
+    }
+    if this.Queues.MessageTimeout <= 0 {
+        report("queues.messageTimeout: must be positive")
+    }
+    if this.Queues.BacklogSize < 0 {
+        report("queues.backlogSize: negative value")
+    }
+
+    checkListen("metrics.listen", this.Metrics.Listen)
+    if !strings.HasPrefix(this.Metrics.Path, "/") {
+        report("metrics.path: must start with /")
+    }
+    checkListen("admin.listen",  this.Admin.Listen)
+    if len(this.Admin.Listen) > 0 && len(this.Admin.Token) == 0 && !pmadmin.IsLoopback(this.Admin.Listen) {
+        report("admin.token: required for non-loopback listen address %s", this.Admin.Listen)
+    }
+    checkListen("health.listen", this.Health.Listen)
+    if this.Health.LiveWindow <= 0 {
+        report("health.liveWindow: must be positive")
+    }
+
+    if len(problems) > 0 {
+        return errors.New("config: " + strings.Join(problems, "; "))
+    }
+    return err
+}
+//
+// LogLevel() returns effective level, debug key forces debug level
+//
+func (this *Config) LogLevel() string {
+    if this.Debug {
+        return LogLevelDebug
+    }
+    return this.Log.Level
+}
+//
+// JwtKeys() builds jwt verification keys, nil when no key is set
+//
+func (this *Core) JwtKeys() (*pgjwt.KeySet, error) {
+    var err error
+    if len(this.JwtSecret) == 0 && len(this.JwtKeyFile) == 0 && len(this.JwksURL) == 0 {
+        return nil, err
+    }
+    keys := pgjwt.NewKeySet()
+    if len(this.JwtSecret) > 0 {
+        keys.AddSecret(this.JwtSecret)
+    }
+    if len(this.JwtKeyFile) > 0 {
+        data, err := ioutil.ReadFile(this.JwtKeyFile)
+        if err != nil {
+            return nil, err
+        }
+        err = keys.AddPEM(data)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %s", this.JwtKeyFile, err)
+        }
+    }
+    if len(this.JwksURL) > 0 {
+        keys.SetJWKS(this.JwksURL)
+    }
+    return keys, err
+}
+//
+// TLSConfig() builds broker tls config, nil when tls section is empty
+//
+func (this *TLS) TLSConfig() (*tls.Config, error) {
+    var err error
+    if len(this.CAFile) == 0 && len(this.CertFile) == 0 && !this.Insecure {
+        return nil, err
+    }
+    config := &tls.Config{
+        InsecureSkipVerify: this.Insecure,
+    }
+    if len(this.CAFile) > 0 {
+        caBytes, err := ioutil.ReadFile(this.CAFile)
+        if err != nil {
+            return nil, err
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(caBytes) {
+            return nil, fmt.Errorf("no certificates in %s", this.CAFile)
+        }
+        config.RootCAs = pool
+    }
+    if len(this.CertFile) > 0 {
+        cert, err := tls.LoadX509KeyPair(this.CertFile, this.KeyFile)
+        if err != nil {
+            return nil, err
+        }
+        config.Certificates = []tls.Certificate{ cert }
+    }
+    return config, err
+}
+//EOF
@@ -0,0 +1,164 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "sync"
+    "time"
+
+    "app/pgschema"
+)
+
+const (
+    DefaultPresenceSize     int     = 10000
+)
+//
+// Presence
+//
+// Presence keeps last message time for every routed topic base
+// and reports online/offline state changes only. Offline entries
+// are forgotten after forget period, when the map is full the
+// longest silent entry is dropped.
+//
+type Presence struct {
+    size        int
+    bases       map[string]*presenceEntry
+    mutex       sync.Mutex
+}
+
+type presenceEntry struct {
+    lastSeen    int64
+    online      bool
+    objectIds   []pgschema.UUID
+    resolved    bool
+}
+//
+// Change
+//
+type Change struct {
+    TopicBase   string
+    ObjectIds   []pgschema.UUID
+    LastSeen    int64
+    Online      bool
+    Resolved    bool
+}
+
+func NewPresence(size int) *Presence {
+    if size <= 0 {
+        size = DefaultPresenceSize
+    }
+    return &Presence{
+        size:       size,
+        bases:      make(map[string]*presenceEntry),
+    }
+}
+//
+// Seen() registers message and returns change if topic base came online
+//
+func (this *Presence) Seen(topicBase string) (Change, bool) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    entry, exists := this.bases[topicBase]
+    if !exists {
+        if len(this.bases) >= this.size {
+            this.evict()
+        }
+        entry = &presenceEntry{}
+        this.bases[topicBase] = entry
+    }
+    entry.lastSeen = time.Now().Unix()
+    if entry.online {
+        return Change{}, false
+    }
+    entry.online = true
+    return entry.change(topicBase), true
+}
+//
+// evict() drops the longest silent entry
+//
+func (this *Presence) evict() {
+    oldest := ""
+    var oldestSeen int64
+    for topicBase, entry := range this.bases {
+        if len(oldest) == 0 || entry.lastSeen < oldestSeen {
+            oldest      = topicBase
+            oldestSeen  = entry.lastSeen
+        }
+    }
+    delete(this.bases, oldest)
+}
+//
+// Expire() returns changes for topic bases silent longer than timeout
+// and forgets offline topic bases silent longer than forgetAfter, sec.
+// Zero value skips the step.
+//
+func (this *Presence) Expire(timeout int64, forgetAfter int64) []Change {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    changes := make([]Change, 0)
+    now := time.Now().Unix()
+    for topicBase, entry := range this.bases {
+        silence := now - entry.lastSeen
+        if !entry.online {
+            if forgetAfter > 0 && silence > forgetAfter {
+                delete(this.bases, topicBase)
+            }
+            continue
+        }
+        if timeout <= 0 || silence <= timeout {
+            continue
+        }
+        entry.online = false
+        changes = append(changes, entry.change(topicBase))
+    }
+    return changes
+}
+//
+// Resolve() stores device object ids for topic base
+//
+func (this *Presence) Resolve(topicBase string, objectIds []pgschema.UUID) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    entry, exists := this.bases[topicBase]
+    if !exists {
+        return
+    }
+    entry.objectIds = objectIds
+    entry.resolved  = true
+}
+//
+// Forget() drops resolved ids, e.g. after device was created or deleted
+//
+func (this *Presence) Forget(topicBase string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    entry, exists := this.bases[topicBase]
+    if !exists {
+        return
+    }
+    entry.objectIds = nil
+    entry.resolved  = false
+}
+
+func (this *Presence) Len() int {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return len(this.bases)
+}
+
+func (this *presenceEntry) change(topicBase string) Change {
+    return Change{
+        TopicBase:  topicBase,
+        ObjectIds:  this.objectIds,
+        LastSeen:   this.lastSeen,
+        Online:     this.online,
+        Resolved:   this.resolved,
+    }
+}
+//EOF
@@ -0,0 +1,100 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "testing"
+    "time"
+
+    "app/pgschema"
+)
+
+func (this *Presence) silence(topicBase string, period int64) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.bases[topicBase].lastSeen = time.Now().Unix() - period
+}
+
+func TestPresenceTransitions(t *testing.T) {
+    presence := NewPresence(10)
+
+    change, changed := presence.Seen("dev/a")
+    if !changed || !change.Online || change.TopicBase != "dev/a" {
+        t.Fatal("new topic base not reported online:", change)
+    }
+    if _, changed = presence.Seen("dev/a"); changed {
+        t.Error("online topic base reported again")
+    }
+
+    presence.silence("dev/a", 30)
+    if changes := presence.Expire(60, 600); len(changes) != 0 {
+        t.Error("topic base expired before timeout:", changes)
+    }
+    presence.silence("dev/a", 100)
+    changes := presence.Expire(60, 600)
+    if len(changes) != 1 || changes[0].Online {
+        t.Fatal("topic base not reported offline:", changes)
+    }
+    if changes = presence.Expire(60, 600); len(changes) != 0 {
+        t.Error("offline topic base reported again:", changes)
+    }
+
+    change, changed = presence.Seen("dev/a")
+    if !changed || !change.Online {
+        t.Error("topic base not reported back online:", change)
+    }
+    if changes = presence.Expire(0, 600); len(changes) != 0 {
+        t.Error("zero timeout expired topic base:", changes)
+    }
+}
+
+func TestPresenceForget(t *testing.T) {
+    presence := NewPresence(10)
+    presence.Seen("dev/a")
+    presence.Seen("dev/b")
+
+    presence.silence("dev/a", 1000)
+    presence.silence("dev/b", 1000)
+    presence.Expire(60, 600)
+    if presence.Len() != 2 {
+        t.Error("topic bases forgotten at offline transition")
+    }
+    presence.Seen("dev/b")
+    presence.Expire(60, 600)
+    if presence.Len() != 1 {
+        t.Error("offline topic base not forgotten, entries:", presence.Len())
+    }
+
+    presence.Resolve("dev/b", []pgschema.UUID{ "object" })
+    presence.silence("dev/b", 100)
+    changes := presence.Expire(60, 0)
+    if len(changes) != 1 || !changes[0].Resolved || len(changes[0].ObjectIds) != 1 {
+        t.Error("resolved ids lost:", changes)
+    }
+    presence.silence("dev/b", 100000)
+    presence.Expire(60, 0)
+    if presence.Len() != 1 {
+        t.Error("zero forget period removed topic base")
+    }
+}
+
+func TestPresenceSize(t *testing.T) {
+    presence := NewPresence(2)
+    presence.Seen("dev/a")
+    presence.Seen("dev/b")
+    presence.silence("dev/a", 100)
+
+    presence.Seen("dev/c")
+    if presence.Len() != 2 {
+        t.Fatal("presence size not bounded:", presence.Len())
+    }
+    if _, changed := presence.Seen("dev/b"); changed {
+        t.Error("recent topic base evicted")
+    }
+    if _, changed := presence.Seen("dev/a"); !changed {
+        t.Error("longest silent topic base not evicted")
+    }
+}
+//EOF
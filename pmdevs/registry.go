@@ -0,0 +1,177 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "encoding/json"
+    "sync"
+    "time"
+
+    "app/pgschema"
+)
+
+const (
+    StateOnline     string = "online"
+    StateOffline    string = "offline"
+    StateDisabled   string = "disabled"
+    StateDeleted    string = "deleted"
+)
+//
+// Device
+//
+type Device struct {
+    ObjectId    pgschema.UUID   `json:"objectId"`
+    TopicBase   string          `json:"topicBase"`
+    LastSeen    int64           `json:"lastSeen"`      // epoch
+    State       string          `json:"state"`
+}
+//
+// Transition
+//
+type Transition struct {
+    Device      Device
+    From        string
+    To          string
+}
+//
+// Policy
+//
+// Policy holds inactivity periods in seconds, zero skips the step.
+// Periods are independent: device silent longer than delete period
+// is deleted even with offline and disable steps skipped.
+//
+type Policy struct {
+    OfflineAfter    int64   `json:"offlineAfter"`
+    DisableAfter    int64   `json:"disableAfter"`
+    DeleteAfter     int64   `json:"deleteAfter"`
+}
+var stateRanks = map[string]int{
+    StateOnline:    0,
+    StateOffline:   1,
+    StateDisabled:  2,
+    StateDeleted:   3,
+}
+//
+// state() returns the furthest state reached after silence, sec
+//
+func (this Policy) state(silence int64) string {
+    switch {
+        case this.DeleteAfter > 0 && silence > this.DeleteAfter:
+            return StateDeleted
+        case this.DisableAfter > 0 && silence > this.DisableAfter:
+            return StateDisabled
+        case this.OfflineAfter > 0 && silence > this.OfflineAfter:
+            return StateOffline
+    }
+    return StateOnline
+}
+//
+// Registry
+//
+// Registry keeps bridge managed devices by topic base.
+//
+type Registry struct {
+    devices     map[string]*Device
+    mutex       sync.Mutex
+}
+
+func NewRegistry() *Registry {
+    return &Registry{
+        devices:    make(map[string]*Device),
+    }
+}
+
+//
+// Register() adds device, lastSeen is stored epoch or zero for now
+//
+func (this *Registry) Register(topicBase string, objectId pgschema.UUID, enabled bool, lastSeen int64) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    if lastSeen <= 0 {
+        lastSeen = time.Now().Unix()
+    }
+    state := StateOnline
+    if !enabled {
+        state = StateDisabled
+    }
+    device, exists := this.devices[topicBase]
+    if exists {
+        device.ObjectId = objectId
+        return
+    }
+    this.devices[topicBase] = &Device{
+        ObjectId:   objectId,
+        TopicBase:  topicBase,
+        LastSeen:   lastSeen,
+        State:      state,
+    }
+}
+
+func (this *Registry) Remove(topicBase string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    delete(this.devices, topicBase)
+}
+//
+// Touch() updates last seen time and returns previous device state
+//
+func (this *Registry) Touch(topicBase string) (Device, string, bool) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    device, exists := this.devices[topicBase]
+    if !exists {
+        return Device{}, "", false
+    }
+    prevState := device.State
+    device.LastSeen = time.Now().Unix()
+    device.State    = StateOnline
+    return *device, prevState, true
+}
+//
+// Expire() moves inactive devices forward to state reached by policy
+//
+func (this *Registry) Expire(policy Policy) []Transition {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    transitions := make([]Transition, 0)
+    now := time.Now().Unix()
+
+    for topicBase, device := range this.devices {
+        nextState := policy.state(now - device.LastSeen)
+        if stateRanks[nextState] <= stateRanks[device.State] {
+            continue
+        }
+        transitions = append(transitions, Transition{
+            Device:     *device,
+            From:       device.State,
+            To:         nextState,
+        })
+        device.State = nextState
+        if nextState == StateDeleted {
+            delete(this.devices, topicBase)
+        }
+    }
+    return transitions
+}
+
+func (this *Registry) List() []Device {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    result := make([]Device, 0, len(this.devices))
+    for _, device := range this.devices {
+        result = append(result, *device)
+    }
+    return result
+}
+
+func (this *Registry) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this.List())
+    return string(jsonBytes)
+}
+//EOF
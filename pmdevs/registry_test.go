@@ -0,0 +1,89 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "testing"
+    "time"
+)
+
+func TestRegistryExpire(t *testing.T) {
+    full := Policy{ OfflineAfter: 60, DisableAfter: 600, DeleteAfter: 6000 }
+    tests := []struct {
+        name        string
+        policy      Policy
+        enabled     bool
+        silence     int64
+        expected    string
+    }{
+        { "active",             full,   true,   10,     StateOnline },
+        { "offline",            full,   true,   100,    StateOffline },
+        { "disabled",           full,   true,   1000,   StateDisabled },
+        { "deleted",            full,   true,   10000,  StateDeleted },
+        { "disabled deleted",   full,   false,  10000,  StateDeleted },
+        { "disabled kept",      full,   false,  100,    StateDisabled },
+        { "no offline",         Policy{ DisableAfter: 600, DeleteAfter: 6000 },     true,   1000,   StateDisabled },
+        { "no offline delete",  Policy{ DisableAfter: 600, DeleteAfter: 6000 },     true,   10000,  StateDeleted },
+        { "no disable",         Policy{ OfflineAfter: 60, DeleteAfter: 6000 },      true,   10000,  StateDeleted },
+        { "no disable offline", Policy{ OfflineAfter: 60, DeleteAfter: 6000 },      true,   1000,   StateOffline },
+        { "delete only",        Policy{ DeleteAfter: 6000 },                        true,   10000,  StateDeleted },
+        { "no delete",          Policy{ OfflineAfter: 60, DisableAfter: 600 },      true,   100000, StateDisabled },
+        { "zero policy",        Policy{},                                           true,   100000, StateOnline },
+    }
+    for _, test := range tests {
+        registry := NewRegistry()
+        registry.Register("dev/a", "object", test.enabled, time.Now().Unix() - test.silence)
+        registry.Expire(test.policy)
+
+        state := StateDeleted
+        devices := registry.List()
+        if len(devices) > 0 {
+            state = devices[0].State
+        }
+        if state != test.expected {
+            t.Errorf("%s: state %s, expected %s", test.name, state, test.expected)
+        }
+    }
+}
+
+func TestRegistryExpireSteps(t *testing.T) {
+    registry := NewRegistry()
+    registry.Register("dev/a", "object", true, time.Now().Unix() - 100)
+
+    policy := Policy{ OfflineAfter: 60, DisableAfter: 600, DeleteAfter: 6000 }
+    transitions := registry.Expire(policy)
+    if len(transitions) != 1 || transitions[0].From != StateOnline || transitions[0].To != StateOffline {
+        t.Fatal("wrong transitions:", transitions)
+    }
+    if len(registry.Expire(policy)) != 0 {
+        t.Error("transition repeated")
+    }
+    if _, prevState, _ := registry.Touch("dev/a"); prevState != StateOffline {
+        t.Error("wrong previous state:", prevState)
+    }
+    if len(registry.Expire(policy)) != 0 {
+        t.Error("touched device expired")
+    }
+}
+
+func TestRegistryRegisterLastSeen(t *testing.T) {
+    registry := NewRegistry()
+    registry.Register("dev/a", "object", true, 0)
+    registry.Register("dev/b", "object", true, 1000)
+
+    for _, device := range registry.List() {
+        switch device.TopicBase {
+            case "dev/a":
+                if time.Now().Unix() - device.LastSeen > 1 {
+                    t.Error("zero last seen not set to now:", device.LastSeen)
+                }
+            case "dev/b":
+                if device.LastSeen != 1000 {
+                    t.Error("stored last seen not kept:", device.LastSeen)
+                }
+        }
+    }
+}
+//EOF
@@ -0,0 +1,121 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "encoding/json"
+    "sync"
+    "time"
+)
+
+const (
+    DefaultUnmatchedSize    int     = 100
+)
+//
+// Unmatched
+//
+// Unmatched keeps recent topic bases which no device object claimed,
+// the oldest entry is dropped when the list is full.
+//
+type Unmatched struct {
+    size        int
+    total       int64
+    entries     map[string]*UnmatchedEntry
+    order       []string
+    mutex       sync.Mutex
+}
+
+type UnmatchedEntry struct {
+    TopicBase   string      `json:"topicBase"`
+    Topic       string      `json:"topic"`
+    FirstSeen   int64       `json:"firstSeen"`     // epoch
+    LastSeen    int64       `json:"lastSeen"`      // epoch
+    Count       int64       `json:"count"`
+}
+
+func NewUnmatched(size int) *Unmatched {
+    if size <= 0 {
+        size = DefaultUnmatchedSize
+    }
+    return &Unmatched{
+        size:       size,
+        entries:    make(map[string]*UnmatchedEntry),
+        order:      make([]string, 0, size),
+    }
+}
+//
+// Add() counts unmatched message and returns true for new topic base
+//
+func (this *Unmatched) Add(topicBase string, topic string) (UnmatchedEntry, bool) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    now := time.Now().Unix()
+    this.total += 1
+    entry, exists := this.entries[topicBase]
+    if exists {
+        entry.Topic     = topic
+        entry.LastSeen  = now
+        entry.Count     += 1
+        return *entry, false
+    }
+    if len(this.order) >= this.size {
+        delete(this.entries, this.order[0])
+        this.order = this.order[1:]
+    }
+    entry = &UnmatchedEntry{
+        TopicBase:  topicBase,
+        Topic:      topic,
+        FirstSeen:  now,
+        LastSeen:   now,
+        Count:      1,
+    }
+    this.entries[topicBase] = entry
+    this.order = append(this.order, topicBase)
+    return *entry, true
+}
+//
+// Remove() forgets topic base, e.g. after device provisioning
+//
+func (this *Unmatched) Remove(topicBase string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    if _, exists := this.entries[topicBase]; !exists {
+        return
+    }
+    delete(this.entries, topicBase)
+    for i, item := range this.order {
+        if item == topicBase {
+            this.order = append(this.order[:i], this.order[i + 1:]...)
+            break
+        }
+    }
+}
+
+func (this *Unmatched) Total() int64 {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.total
+}
+//
+// List() returns entries from newest to oldest
+//
+func (this *Unmatched) List() []UnmatchedEntry {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    result := make([]UnmatchedEntry, 0, len(this.order))
+    for i := len(this.order) - 1; i >= 0; i-- {
+        result = append(result, *this.entries[this.order[i]])
+    }
+    return result
+}
+
+func (this *Unmatched) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this.List())
+    return string(jsonBytes)
+}
+//EOF
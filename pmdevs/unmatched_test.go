@@ -0,0 +1,35 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdevs
+
+import (
+    "testing"
+)
+
+
+func TestUnmatched(t *testing.T) {
+    unmatched := NewUnmatched(2)
+
+    if _, isNew := unmatched.Add("dev/a", "dev/a/status"); !isNew {
+        t.Error("first topic base not reported as new")
+    }
+    if entry, isNew := unmatched.Add("dev/a", "dev/a/data"); isNew || entry.Count != 2 {
+        t.Error("known topic base not counted", entry)
+    }
+    unmatched.Add("dev/b", "dev/b/status")
+    unmatched.Add("dev/c", "dev/c/status")
+
+    list := unmatched.List()
+    if len(list) != 2 || list[0].TopicBase != "dev/c" || list[1].TopicBase != "dev/b" {
+        t.Error("wrong recent list", list)
+    }
+    if unmatched.Total() != 4 {
+        t.Error("wrong total", unmatched.Total())
+    }
+    unmatched.Remove("dev/c")
+    if len(unmatched.List()) != 1 {
+        t.Error("topic base not removed")
+    }
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmdown
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+
+    "app/pgcore"
+    "app/pgschema"
+)
+
+const (
+    startDelimeter  string  = "<<"
+    endDelimeter    string  = ">>"
+
+    TopicBaseKey    string  = "topicBase"
+)
+//
+// Route
+//
+// Route maps control execution on device object to MQTT publish.
+// Topic and payload are templates with <<topicBase>> and control
+// argument names as placeholders.
+//
+type Route struct {
+    SchemaId    pgschema.UUID   `json:"schemaId"`      // empty matches any schema
+    Control     string          `json:"control"`
+    Topic       string          `json:"topic"`
+    Payload     string          `json:"payload"`
+    Encoding    string          `json:"encoding,omitempty"`     // utf8, base64, hex
+}
+//
+// Routes
+//
+type Routes struct {
+    Routes      []Route         `json:"routes"`
+}
+
+func NewRoutes() *Routes {
+    return &Routes{
+        Routes:     make([]Route, 0),
+    }
+}
+
+func RoutesFromString(source string) (*Routes, error) {
+    var err error
+    routes := NewRoutes()
+    if len(strings.TrimSpace(source)) == 0 {
+        return routes, err
+    }
+    err = json.Unmarshal([]byte(source), routes)
+    if err != nil {
+        return routes, err
+    }
+    for i, route := range routes.Routes {
+        if len(route.Control) == 0 {
+            return routes, fmt.Errorf("downlink route %d: empty control name", i)
+        }
+        if len(route.Topic) == 0 {
+            return routes, fmt.Errorf("downlink route %d: empty topic template", i)
+        }
+        err = pgcore.CheckEncoding(route.Encoding)
+        if err != nil {
+            return routes, fmt.Errorf("downlink route %d: %s", i, err)
+        }
+    }
+    return routes, err
+}
+
+func (this *Routes) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// Find() returns route for schema and control, exact schema first
+//
+func (this *Routes) Find(schemaId pgschema.UUID, control string) (Route, bool) {
+    for _, route := range this.Routes {
+        if route.Control == control && route.SchemaId == schemaId {
+            return route, true
+        }
+    }
+    for _, route := range this.Routes {
+        if route.Control == control && len(route.SchemaId) == 0 {
+            return route, true
+        }
+    }
+    return Route{}, false
+}
+//
+// Render() replaces placeholders without touching other text
+//
+func Render(template string, values map[string]string) string {
+    pairs := make([]string, 0, len(values) * 2)
+    for key, value := range values {
+        pairs = append(pairs, startDelimeter + key + endDelimeter, value)
+    }
+    return strings.NewReplacer(pairs...).Replace(template)
+}
+//
+// ArgumentValues() flattens control params object to strings
+//
+func ArgumentValues(params string) (map[string]string, error) {
+    var err error
+    values := make(map[string]string)
+    if len(strings.TrimSpace(params)) == 0 {
+        return values, err
+    }
+    arguments := make(map[string]interface{})
+    err = json.Unmarshal([]byte(params), &arguments)
+    if err != nil {
+        return values, errors.New("downlink: control params is not json object")
+    }
+    for key, value := range arguments {
+        switch value.(type) {
+            case string:
+                values[key] = value.(string)
+            default:
+                jsonBytes, _ := json.Marshal(value)
+                values[key] = string(jsonBytes)
+        }
+    }
+    return values, err
+}
+//EOF
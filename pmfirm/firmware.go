@@ -0,0 +1,195 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmfirm
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    DefaultChunkSize    int     = 1024
+    DefaultTimeout      int     = 10    // sec
+    DefaultRetries      int     = 3
+
+    progressStep        int     = 10    // percent
+)
+//
+// Layout
+//
+// Layout holds topic templates of firmware transfer, <<topicBase>>
+// and <<index>> are placeholders. Device acknowledges every chunk
+// by publishing chunk index to ack topic.
+//
+type Layout struct {
+    BeginTopic  string  `json:"begin"`
+    ChunkTopic  string  `json:"chunk"`
+    EndTopic    string  `json:"end"`
+    AckTopic    string  `json:"ack"`
+}
+
+func NewLayout() *Layout {
+    return &Layout{
+        BeginTopic: "<<topicBase>>/fw/begin",
+        ChunkTopic: "<<topicBase>>/fw/chunk/<<index>>",
+        EndTopic:   "<<topicBase>>/fw/end",
+        AckTopic:   "<<topicBase>>/fw/ack",
+    }
+}
+
+func LayoutFromString(source string) (*Layout, error) {
+    var err error
+    layout := NewLayout()
+    if len(strings.TrimSpace(source)) == 0 {
+        return layout, err
+    }
+    err = json.Unmarshal([]byte(source), layout)
+    if err != nil {
+        return layout, err
+    }
+    if len(layout.ChunkTopic) == 0 || len(layout.AckTopic) == 0 {
+        return layout, fmt.Errorf("firmware layout: chunk and ack topics are required")
+    }
+    return layout, err
+}
+
+func (this *Layout) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+
+func (this *Layout) topic(template string, topicBase string, index int) string {
+    return strings.NewReplacer("<<topicBase>>", topicBase, "<<index>>", strconv.Itoa(index)).Replace(template)
+}
+//
+// Transfer
+//
+type PublishFunc  = func(topic string, payload []byte) error
+type ProgressFunc = func(sent int, total int)
+
+type Transfer struct {
+    Layout      *Layout
+    TopicBase   string
+    ChunkSize   int
+    Timeout     time.Duration
+    Retries     int
+
+    acks        chan int
+}
+
+type header struct {
+    Size        int     `json:"size"`
+    ChunkSize   int     `json:"chunkSize"`
+    Chunks      int     `json:"chunks"`
+}
+
+func NewTransfer(layout *Layout, topicBase string) *Transfer {
+    return &Transfer{
+        Layout:     layout,
+        TopicBase:  topicBase,
+        ChunkSize:  DefaultChunkSize,
+        Timeout:    time.Duration(DefaultTimeout) * time.Second,
+        Retries:    DefaultRetries,
+        acks:       make(chan int, 16),
+    }
+}
+
+func (this *Transfer) AckTopic() string {
+    return this.Layout.topic(this.Layout.AckTopic, this.TopicBase, 0)
+}
+//
+// Ack() passes ack payload from device to running transfer
+//
+func (this *Transfer) Ack(payload []byte) {
+    index, err := strconv.Atoi(strings.TrimSpace(string(payload)))
+    if err != nil {
+        return
+    }
+    select {
+        case this.acks <- index:
+        default:
+    }
+}
+//
+// Run() sends image chunk by chunk and waits ack for every chunk
+//
+func (this *Transfer) Run(ctx context.Context, image []byte, publish PublishFunc, progress ProgressFunc) error {
+    var err error
+
+    chunkSize := this.ChunkSize
+    if chunkSize <= 0 {
+        chunkSize = DefaultChunkSize
+    }
+    total := (len(image) + chunkSize - 1) / chunkSize
+
+    if len(this.Layout.BeginTopic) > 0 {
+        headerBytes, _ := json.Marshal(header{ Size: len(image), ChunkSize: chunkSize, Chunks: total })
+        err = publish(this.Layout.topic(this.Layout.BeginTopic, this.TopicBase, 0), headerBytes)
+        if err != nil {
+            return err
+        }
+    }
+
+    lastPercent := 0
+    for index := 0; index < total; index++ {
+        start := index * chunkSize
+        end := start + chunkSize
+        if end > len(image) {
+            end = len(image)
+        }
+        topic := this.Layout.topic(this.Layout.ChunkTopic, this.TopicBase, index)
+
+        acked := false
+        for attempt := 0; attempt <= this.Retries && !acked; attempt++ {
+            err = publish(topic, image[start:end])
+            if err != nil {
+                return err
+            }
+            acked, err = this.waitAck(ctx, index)
+            if err != nil {
+                return err
+            }
+        }
+        if !acked {
+            return fmt.Errorf("firmware transfer: chunk %d not acknowledged after %d retries", index, this.Retries)
+        }
+
+        percent := (index + 1) * 100 / total
+        if progress != nil && (percent - lastPercent >= progressStep || index + 1 == total) {
+            lastPercent = percent
+            progress(index + 1, total)
+        }
+    }
+
+    if len(this.Layout.EndTopic) > 0 {
+        err = publish(this.Layout.topic(this.Layout.EndTopic, this.TopicBase, total), []byte(strconv.Itoa(total)))
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+
+func (this *Transfer) waitAck(ctx context.Context, index int) (bool, error) {
+    timer := time.NewTimer(this.Timeout)
+    defer timer.Stop()
+    for {
+        select {
+            case <- ctx.Done():
+                return false, ctx.Err()
+            case <- timer.C:
+                return false, nil
+            case ack := <- this.acks:
+                if ack == index {
+                    return true, nil
+                }
+        }
+    }
+}
+//EOF
@@ -0,0 +1,64 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmfirm
+
+import (
+    "bytes"
+    "context"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+
+func TestTransfer(t *testing.T) {
+    transfer := NewTransfer(NewLayout(), "gw/a1")
+    transfer.ChunkSize  = 4
+    transfer.Timeout    = 100 * time.Millisecond
+
+    image := []byte("0123456789")
+    received := make([]byte, 0)
+    dropped := false
+
+    publish := func(topic string, payload []byte) error {
+        if !strings.HasPrefix(topic, "gw/a1/fw/chunk/") {
+            return nil
+        }
+        index, _ := strconv.Atoi(strings.TrimPrefix(topic, "gw/a1/fw/chunk/"))
+        // lose first ack of second chunk to force retry
+        if index == 1 && !dropped {
+            dropped = true
+            return nil
+        }
+        received = append(received, payload...)
+        transfer.Ack([]byte(strconv.Itoa(index)))
+        return nil
+    }
+
+    steps := 0
+    err := transfer.Run(context.Background(), image, publish, func(sent, total int) { steps++ })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(received, image) {
+        t.Error("wrong received image", string(received))
+    }
+    if steps == 0 {
+        t.Error("progress not reported")
+    }
+}
+
+func TestTransferTimeout(t *testing.T) {
+    transfer := NewTransfer(NewLayout(), "gw/a1")
+    transfer.Timeout    = 10 * time.Millisecond
+    transfer.Retries    = 1
+
+    publish := func(topic string, payload []byte) error { return nil }
+    err := transfer.Run(context.Background(), []byte("data"), publish, nil)
+    if err == nil {
+        t.Error("transfer without acks succeeded")
+    }
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmfwd
+
+import (
+    "crypto/tls"
+    "sync"
+
+    "app/mqtrans"
+    "app/pmlog"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+//
+// Forwarder
+//
+// Forwarder owns broker connections and moves messages between
+// local and remote brokers by config rules. Local broker gets own
+// connection to keep bridge topic subscriptions untouched.
+//
+type Forwarder struct {
+    config      *Config
+    local       Broker
+    localTLS    *tls.Config
+    resolver    func(string) (string, error)
+    remotes     map[string]*mqtrans.Transport
+    guard       *Guard
+    stats       *Stats
+    mutex       sync.Mutex
+}
+
+func NewForwarder() *Forwarder {
+    return &Forwarder{
+        config:     NewConfig(),
+        local:      Broker{ Name: LocalBroker },
+        remotes:    make(map[string]*mqtrans.Transport),
+        guard:      NewGuard(),
+        stats:      NewStats(),
+    }
+}
+
+func (this *Forwarder) GetStats() *Stats {
+    return this.stats
+}
+//
+// SetLocal() sets local broker credentials and drops connections
+//
+func (this *Forwarder) SetLocal(url string, username string, password string) {
+    this.Stop()
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.local.URL         = url
+    this.local.Username    = username
+    this.local.Password    = password
+}
+//
+// SetResolver() sets decoder of broker credentials from rules, e.g.
+// ${env:...} references or encrypted values
+//
+func (this *Forwarder) SetResolver(resolver func(string) (string, error)) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.resolver = resolver
+}
+//
+// SetLocalTLS() sets tls config of local broker connection
+//
+func (this *Forwarder) SetLocalTLS(config *tls.Config) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.localTLS = config
+}
+//
+// SetConfig() replaces rules and drops connections, next Connect()
+// applies the new config
+//
+func (this *Forwarder) SetConfig(config *Config) {
+    this.Stop()
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.config = config
+}
+
+func (this *Forwarder) GetConfig() *Config {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.config
+}
+//
+// Connect() connects brokers which are not connected yet, returns
+// the last broker or subscription error
+//
+func (this *Forwarder) Connect() error {
+    var err error
+    var result error
+    config := this.GetConfig()
+    if len(config.Rules) == 0 {
+        return err
+    }
+    this.mutex.Lock()
+    brokers := append([]Broker{ this.local }, config.Brokers...)
+    this.mutex.Unlock()
+
+    for _, broker := range brokers {
+        this.mutex.Lock()
+        remote, exists := this.remotes[broker.Name]
+        this.mutex.Unlock()
+        if exists && remote.IsConnected() {
+            continue
+        }
+        if exists {
+            remote.Disconnect()
+        }
+        remote = mqtrans.NewTransport()
+        if broker.Name == LocalBroker {
+            this.mutex.Lock()
+            remote.SetTLSConfig(this.localTLS)
+            this.mutex.Unlock()
+        }
+        var username, password string
+        username, password, err = this.credentials(broker)
+        if err != nil {
+            pmlog.LogError("forwarder: broker", broker.Name, "credentials error:", err)
+            result = err
+            continue
+        }
+        err = remote.Bind(broker.URL, username, password)
+        if err != nil {
+            pmlog.LogError("forwarder: broker", broker.Name, "connect error:", err)
+            result = err
+            continue
+        }
+        this.mutex.Lock()
+        this.remotes[broker.Name] = remote
+        this.mutex.Unlock()
+        pmlog.LogInfo("forwarder: connected to broker", broker.Name)
+
+        for i := range config.Rules {
+            rule := config.Rules[i]
+            var filter string
+            var handler mqtrans.Handler
+            switch {
+                case broker.Name == LocalBroker && rule.Outbound():
+                    filter  = rule.LocalFilter()
+                    handler = this.outboundHandler(rule)
+                case broker.Name == rule.Broker && rule.Inbound():
+                    filter  = rule.RemoteFilter()
+                    handler = this.inboundHandler(rule)
+                default:
+                    continue
+            }
+            err = remote.SubscribeQos(filter, byte(rule.Qos), handler)
+            if err != nil {
+                pmlog.LogError("forwarder: rule", rule.Name, "subscription error:", err)
+                result = err
+                continue
+            }
+            pmlog.LogInfo("forwarder: rule", rule.Name, "subscribed to", broker.Name, filter)
+        }
+    }
+    return result
+}
+//
+// credentials() decodes rule broker credentials, local broker has
+// decoded ones already
+//
+func (this *Forwarder) credentials(broker Broker) (string, string, error) {
+    var err error
+    this.mutex.Lock()
+    resolver := this.resolver
+    this.mutex.Unlock()
+    if resolver == nil || broker.Name == LocalBroker {
+        return broker.Username, broker.Password, err
+    }
+    username, err := resolver(broker.Username)
+    if err != nil {
+        return "", "", err
+    }
+    password, err := resolver(broker.Password)
+    if err != nil {
+        return "", "", err
+    }
+    return username, password, err
+}
+//
+// Stop() disconnects brokers
+//
+func (this *Forwarder) Stop() {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    for name, remote := range this.remotes {
+        remote.Disconnect()
+        delete(this.remotes, name)
+    }
+}
+
+func (this *Forwarder) remote(name string) (*mqtrans.Transport, bool) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    remote, exists := this.remotes[name]
+    return remote, exists
+}
+
+func (this *Forwarder) outboundHandler(rule Rule) mqtrans.Handler {
+    return func(client mqtt.Client, message mqtt.Message) {
+        topic   := message.Topic()
+        payload := message.Payload()
+        destination := rule.RewriteOut(topic)
+        if this.guard.Looped(LocalBroker, topic, rule.Broker, destination, payload) {
+            this.stats.Looped(rule.Name)
+            return
+        }
+        remote, exists := this.remote(rule.Broker)
+        if !exists {
+            this.stats.Error(rule.Name)
+            return
+        }
+        this.guard.Record(LocalBroker, topic, rule.Broker, destination, payload)
+        err := remote.PublishQos(destination, byte(rule.Qos), payload)
+        if err != nil {
+            pmlog.LogError("forwarder: rule", rule.Name, "publish error:", err)
+            this.stats.Error(rule.Name)
+            return
+        }
+        this.stats.Forwarded(rule.Name)
+    }
+}
+
+func (this *Forwarder) inboundHandler(rule Rule) mqtrans.Handler {
+    return func(client mqtt.Client, message mqtt.Message) {
+        topic   := message.Topic()
+        payload := message.Payload()
+        destination := rule.RewriteIn(topic)
+        if this.guard.Looped(rule.Broker, topic, LocalBroker, destination, payload) {
+            this.stats.Looped(rule.Name)
+            return
+        }
+        local, exists := this.remote(LocalBroker)
+        if !exists {
+            this.stats.Error(rule.Name)
+            return
+        }
+        this.guard.Record(rule.Broker, topic, LocalBroker, destination, payload)
+        err := local.PublishQos(destination, byte(rule.Qos), payload)
+        if err != nil {
+            pmlog.LogError("forwarder: rule", rule.Name, "publish error:", err)
+            this.stats.Error(rule.Name)
+            return
+        }
+        this.stats.Forwarded(rule.Name)
+    }
+}
+//EOF
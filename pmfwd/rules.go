@@ -0,0 +1,298 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmfwd
+
+import (
+    "crypto/sha1"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    LocalBroker     string  = "local"
+
+    DirectionOut    string  = "out"     // local to remote broker
+    DirectionIn     string  = "in"      // remote broker to local
+    DirectionBoth   string  = "both"
+
+    guardTimeout    int64   = 10        // sec
+)
+//
+// Broker
+//
+type Broker struct {
+    Name        string      `json:"name"`
+    URL         string      `json:"url"`
+    Username    string      `json:"username"`
+    Password    string      `json:"password"`
+}
+//
+// Rule
+//
+// Rule forwards topics matched by filter between local and remote
+// broker. Destination topic is source topic with strip prefix replaced
+// by prefix, inbound direction applies the reverse rewrite.
+//
+type Rule struct {
+    Name        string      `json:"name"`
+    Broker      string      `json:"broker"`
+    Direction   string      `json:"direction"`
+    Filter      string      `json:"filter"`
+    Strip       string      `json:"strip"`
+    Prefix      string      `json:"prefix"`
+    Qos         int         `json:"qos"`
+}
+//
+// Config
+//
+type Config struct {
+    Brokers     []Broker    `json:"brokers"`
+    Rules       []Rule      `json:"rules"`
+}
+
+func NewConfig() *Config {
+    return &Config{
+        Brokers:    make([]Broker, 0),
+        Rules:      make([]Rule, 0),
+    }
+}
+
+func ConfigFromString(source string) (*Config, error) {
+    var err error
+    config := NewConfig()
+    if len(strings.TrimSpace(source)) == 0 {
+        return config, err
+    }
+    err = json.Unmarshal([]byte(source), config)
+    if err != nil {
+        return config, err
+    }
+    err = config.Validate()
+    if err != nil {
+        return config, err
+    }
+    return config, err
+}
+
+func (this *Config) Validate() error {
+    var err error
+    brokers := make(map[string]bool)
+    for i, broker := range this.Brokers {
+        if len(broker.Name) == 0 || broker.Name == LocalBroker {
+            return fmt.Errorf("forward broker %d: wrong name %s", i, broker.Name)
+        }
+        if brokers[broker.Name] {
+            return fmt.Errorf("forward broker %d: duplicate name %s", i, broker.Name)
+        }
+        if len(broker.URL) == 0 {
+            return fmt.Errorf("forward broker %s: empty url", broker.Name)
+        }
+        brokers[broker.Name] = true
+    }
+    for i := range this.Rules {
+        rule := &this.Rules[i]
+        if len(rule.Name) == 0 {
+            rule.Name = fmt.Sprintf("rule%d", i)
+        }
+        if !brokers[rule.Broker] {
+            return fmt.Errorf("forward rule %s: unknown broker %s", rule.Name, rule.Broker)
+        }
+        if len(rule.Filter) == 0 {
+            return fmt.Errorf("forward rule %s: empty filter", rule.Name)
+        }
+        switch rule.Direction {
+            case DirectionOut, DirectionIn, DirectionBoth:
+            case "":
+                rule.Direction = DirectionOut
+            default:
+                return fmt.Errorf("forward rule %s: wrong direction %s", rule.Name, rule.Direction)
+        }
+        if rule.Qos < 0 || rule.Qos > 2 {
+            return fmt.Errorf("forward rule %s: wrong qos %d", rule.Name, rule.Qos)
+        }
+    }
+    return err
+}
+
+func (this *Config) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// Outbound() and Inbound() tell if rule forwards in the direction
+//
+func (this *Rule) Outbound() bool {
+    return this.Direction == DirectionOut || this.Direction == DirectionBoth
+}
+
+func (this *Rule) Inbound() bool {
+    return this.Direction == DirectionIn || this.Direction == DirectionBoth
+}
+//
+// RemoteFilter() returns subscription filter on remote broker
+//
+func (this *Rule) RemoteFilter() string {
+    if this.Direction == DirectionIn {
+        return this.Filter
+    }
+    return this.RewriteOut(this.Filter)
+}
+//
+// LocalFilter() returns subscription filter on local broker
+//
+func (this *Rule) LocalFilter() string {
+    return this.Filter
+}
+
+func (this *Rule) RewriteOut(topic string) string {
+    return this.Prefix + strings.TrimPrefix(topic, this.Strip)
+}
+
+func (this *Rule) RewriteIn(topic string) string {
+    if this.Direction == DirectionIn {
+        return this.RewriteOut(topic)
+    }
+    return this.Strip + strings.TrimPrefix(topic, this.Prefix)
+}
+//
+// Guard
+//
+// Guard remembers recently forwarded messages by hop: origin broker
+// and topic, destination broker and topic. Message is a loop when it
+// comes from destination of recorded hop and goes back to its origin,
+// the same payload on other hops is forwarded as usual. Every forward
+// excuses one returned copy.
+//
+type Guard struct {
+    sent        map[string]*guardEntry
+    mutex       sync.Mutex
+}
+
+type guardEntry struct {
+    count       int
+    timestamp   int64
+}
+
+func NewGuard() *Guard {
+    return &Guard{
+        sent:   make(map[string]*guardEntry),
+    }
+}
+
+func guardKey(fromBroker string, fromTopic string, toBroker string, toTopic string, payload []byte) string {
+    digest := sha1.Sum(payload)
+    return fmt.Sprintf("%s|%s>%s|%s|%x", fromBroker, fromTopic, toBroker, toTopic, digest)
+}
+//
+// Record() marks message forwarded from origin to destination
+//
+func (this *Guard) Record(fromBroker string, fromTopic string, toBroker string, toTopic string, payload []byte) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    now := time.Now().Unix()
+    for key, entry := range this.sent {
+        if now - entry.timestamp > guardTimeout {
+            delete(this.sent, key)
+        }
+    }
+    key := guardKey(fromBroker, fromTopic, toBroker, toTopic, payload)
+    entry, exists := this.sent[key]
+    if !exists {
+        entry = &guardEntry{}
+        this.sent[key] = entry
+    }
+    entry.count     += 1
+    entry.timestamp = now
+}
+//
+// Looped() checks message to be forwarded is a recorded hop going back
+// and forgets one copy of it
+//
+func (this *Guard) Looped(fromBroker string, fromTopic string, toBroker string, toTopic string, payload []byte) bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    key := guardKey(toBroker, toTopic, fromBroker, fromTopic, payload)
+    entry, exists := this.sent[key]
+    if !exists {
+        return false
+    }
+    entry.count -= 1
+    if entry.count <= 0 {
+        delete(this.sent, key)
+    }
+    return time.Now().Unix() - entry.timestamp <= guardTimeout
+}
+//
+// Stats
+//
+type RuleStats struct {
+    Forwarded   int64       `json:"forwarded"`
+    Looped      int64       `json:"looped"`
+    Errors      int64       `json:"errors"`
+}
+
+type Stats struct {
+    rules       map[string]*RuleStats
+    mutex       sync.Mutex
+}
+
+func NewStats() *Stats {
+    return &Stats{
+        rules:  make(map[string]*RuleStats),
+    }
+}
+
+func (this *Stats) get(rule string) *RuleStats {
+    stats, exists := this.rules[rule]
+    if !exists {
+        stats = &RuleStats{}
+        this.rules[rule] = stats
+    }
+    return stats
+}
+
+func (this *Stats) Forwarded(rule string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.get(rule).Forwarded += 1
+}
+
+func (this *Stats) Looped(rule string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.get(rule).Looped += 1
+}
+
+func (this *Stats) Error(rule string) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.get(rule).Errors += 1
+}
+
+func (this *Stats) Total() RuleStats {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    var total RuleStats
+    for _, stats := range this.rules {
+        total.Forwarded += stats.Forwarded
+        total.Looped    += stats.Looped
+        total.Errors    += stats.Errors
+    }
+    return total
+}
+
+func (this *Stats) GetJSON() string {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    jsonBytes, _ := json.Marshal(this.rules)
+    return string(jsonBytes)
+}
+//EOF
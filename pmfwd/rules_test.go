@@ -0,0 +1,83 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmfwd
+
+import (
+    "testing"
+)
+
+
+func TestConfig(t *testing.T) {
+    config, err := ConfigFromString(`{"brokers": [{"name": "cloud", "url": "tcp://cloud:1883"}],
+                                        "rules": [{"broker": "cloud", "direction": "both", "filter": "site/#",
+                                                    "strip": "site/", "prefix": "cloud/site1/", "qos": 1}]}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    rule := config.Rules[0]
+    if rule.Name != "rule0" {
+        t.Error("default rule name not set", rule.Name)
+    }
+    if rule.RemoteFilter() != "cloud/site1/#" {
+        t.Error("wrong remote filter", rule.RemoteFilter())
+    }
+    if rule.RewriteOut("site/dev1/data") != "cloud/site1/dev1/data" {
+        t.Error("wrong outbound rewrite", rule.RewriteOut("site/dev1/data"))
+    }
+    if rule.RewriteIn("cloud/site1/dev1/cmd") != "site/dev1/cmd" {
+        t.Error("wrong inbound rewrite", rule.RewriteIn("cloud/site1/dev1/cmd"))
+    }
+
+    _, err = ConfigFromString(`{"brokers": [], "rules": [{"broker": "cloud", "filter": "#"}]}`)
+    if err == nil {
+        t.Error("rule with unknown broker accepted")
+    }
+    _, err = ConfigFromString(`{"brokers": [{"name": "local", "url": "tcp://x:1883"}]}`)
+    if err == nil {
+        t.Error("reserved broker name accepted")
+    }
+}
+
+func TestGuard(t *testing.T) {
+    guard := NewGuard()
+    guard.Record("local", "a/b", "cloud", "site/a/b", []byte("1"))
+    if guard.Looped("cloud", "site/a/b", "local", "a/b", []byte("2")) {
+        t.Error("other payload reported as loop")
+    }
+    if guard.Looped("local", "a/b", "cloud", "site/a/b", []byte("1")) {
+        t.Error("repeated payload of the same hop reported as loop")
+    }
+    if guard.Looped("cloud", "site/a/b", "local", "x/a/b", []byte("1")) {
+        t.Error("payload to other destination reported as loop")
+    }
+    if !guard.Looped("cloud", "site/a/b", "local", "a/b", []byte("1")) {
+        t.Error("loop not detected")
+    }
+    if guard.Looped("cloud", "site/a/b", "local", "a/b", []byte("1")) {
+        t.Error("loop reported twice")
+    }
+
+    guard.Record("local", "a/b", "cloud", "site/a/b", []byte("1"))
+    guard.Record("local", "a/b", "cloud", "site/a/b", []byte("1"))
+    for i := 0; i < 2; i++ {
+        if !guard.Looped("cloud", "site/a/b", "local", "a/b", []byte("1")) {
+            t.Error("returned copy", i, "not detected")
+        }
+    }
+    if guard.Looped("cloud", "site/a/b", "local", "a/b", []byte("1")) {
+        t.Error("more copies reported than forwarded")
+    }
+}
+
+func TestForwarderConnectError(t *testing.T) {
+    forwarder := NewForwarder()
+    forwarder.SetLocal("tcp://127.0.0.1:1", "", "")
+    config := NewConfig()
+    config.Rules = append(config.Rules, Rule{ Name: "out", Broker: "cloud", Direction: DirectionOut })
+    forwarder.SetConfig(config)
+    if forwarder.Connect() == nil {
+        t.Error("connect error not reported")
+    }
+}
@@ -1,14 +1,46 @@
 /*
- * Copyright: Pixel Networks <support@pixel-networks.com> 
+ * Copyright: Pixel Networks <support@pixel-networks.com>
  */
 
 package pmlog
 
 import (
     "log"
+    "sync/atomic"
 )
 
+const (
+    levelDebug      int32 = iota
+    levelInfo
+    levelWarning
+    levelError
+)
+
+var level int32 = levelDebug
+//
+// SetLevel() hides messages below level: debug, info, warning, error
+//
+func SetLevel(name string) {
+    switch name {
+        case "debug":
+            atomic.StoreInt32(&level, levelDebug)
+        case "info":
+            atomic.StoreInt32(&level, levelInfo)
+        case "warning":
+            atomic.StoreInt32(&level, levelWarning)
+        case "error":
+            atomic.StoreInt32(&level, levelError)
+    }
+}
+
+func enabled(messageLevel int32) bool {
+    return messageLevel >= atomic.LoadInt32(&level)
+}
+
 func LogDebug(message ...interface{}) {
+    if !enabled(levelDebug) {
+        return
+    }
     log.Println("debug:", message)
     return
 }
@@ -19,21 +51,28 @@ func LogError(message ...interface{}) {
 }
 
 func LogWarning(message ...interface{}) {
+    if !enabled(levelWarning) {
+        return
+    }
     log.Println("warning:", message)
     return
 }
 
 func LogInfo(message ...interface{}) {
+    if !enabled(levelInfo) {
+        return
+    }
     log.Println("info:", message)
     return
 }
 
 func LogDetail(message ...interface{}) {
+    if !enabled(levelDebug) {
+        return
+    }
     log.Println("detail:", message)
     return
 }
 
 
 //EOF
-
-
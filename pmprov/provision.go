@@ -0,0 +1,246 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmprov
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+
+    "app/pgschema"
+    "app/pgtmpl"
+)
+//
+// Rule
+//
+// Rule describes how a device is created for a topic that matches
+// none of the built-in gateway patterns.
+//
+type Rule struct {
+    Enabled         bool                `json:"enabled"`
+    Segment         int                 `json:"segment"`       // topic segment with device code, negative counts from end
+    SchemaId        pgschema.UUID       `json:"schemaId"`
+    NameTemplate    string              `json:"name"`          // <<device>>, <<topic>>, <<topicBase>>
+    Properties      map[string]string   `json:"properties"`    // initial property values, same placeholders
+    Allow           []string            `json:"allow"`         // topic regexp list, empty allows all
+    Deny            []string            `json:"deny"`          // topic regexp list
+    RateLimit       int                 `json:"rateLimit"`     // new devices per minute, zero is unlimited
+
+    allowRe         []*regexp.Regexp
+    denyRe          []*regexp.Regexp
+}
+
+const (
+    defaultNameTemplate string  = "Generic MQTT Device (<<topicBase>>) #<<device>>"
+    defaultRateLimit    int     = 10
+)
+
+func NewRule() *Rule {
+    var rule Rule
+    rule.NameTemplate   = defaultNameTemplate
+    rule.RateLimit      = defaultRateLimit
+    rule.Properties     = make(map[string]string)
+    rule.Allow          = make([]string, 0)
+    rule.Deny           = make([]string, 0)
+    return &rule
+}
+
+func RuleFromString(source string) (*Rule, error) {
+    var err error
+    rule := NewRule()
+    if len(strings.TrimSpace(source)) == 0 {
+        return rule, err
+    }
+    err = json.Unmarshal([]byte(source), rule)
+    if err != nil {
+        return rule, err
+    }
+    err = rule.Compile()
+    if err != nil {
+        return rule, err
+    }
+    return rule, err
+}
+
+func (this *Rule) Compile() error {
+    var err error
+    if this.Enabled && len(this.SchemaId) == 0 {
+        return errors.New("provision rule: schema id is empty")
+    }
+    this.allowRe = make([]*regexp.Regexp, 0)
+    for _, pattern := range this.Allow {
+        re, err := regexp.Compile(pattern)
+        if err != nil {
+            return fmt.Errorf("provision rule: wrong allow pattern %s: %s", pattern, err)
+        }
+        this.allowRe = append(this.allowRe, re)
+    }
+    this.denyRe = make([]*regexp.Regexp, 0)
+    for _, pattern := range this.Deny {
+        re, err := regexp.Compile(pattern)
+        if err != nil {
+            return fmt.Errorf("provision rule: wrong deny pattern %s: %s", pattern, err)
+        }
+        this.denyRe = append(this.denyRe, re)
+    }
+    return err
+}
+
+func (this *Rule) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// Permitted() checks topic against deny and allow lists
+//
+func (this *Rule) Permitted(topic string) bool {
+    if !this.Enabled {
+        return false
+    }
+    for _, re := range this.denyRe {
+        if re.MatchString(topic) {
+            return false
+        }
+    }
+    if len(this.allowRe) == 0 {
+        return true
+    }
+    for _, re := range this.allowRe {
+        if re.MatchString(topic) {
+            return true
+        }
+    }
+    return false
+}
+//
+// Device() returns device code and topic base for topic
+//
+func (this *Rule) Device(topic string) (string, string, error) {
+    var err error
+    var device, topicBase string
+
+    trimmed := strings.TrimPrefix(topic, "/")
+    segments := strings.Split(trimmed, "/")
+
+    index := this.Segment
+    if index < 0 {
+        index = len(segments) + index
+    }
+    if index < 0 || index >= len(segments) {
+        return device, topicBase, fmt.Errorf("provision rule: topic %s have not segment %d", topic, this.Segment)
+    }
+    device = segments[index]
+    if len(device) == 0 {
+        return device, topicBase, fmt.Errorf("provision rule: topic %s have empty device segment", topic)
+    }
+    topicBase = strings.Join(segments[:index + 1], "/")
+    if strings.HasPrefix(topic, "/") {
+        topicBase = "/" + topicBase
+    }
+    return device, topicBase, err
+}
+//
+// Name() and Values() render templates for new device
+//
+func (this *Rule) Name(topic, topicBase, device string) string {
+    return this.render(this.NameTemplate, topic, topicBase, device)
+}
+
+func (this *Rule) Values(topic, topicBase, device string) map[string]string {
+    values := make(map[string]string)
+    for key, value := range this.Properties {
+        values[key] = this.render(value, topic, topicBase, device)
+    }
+    return values
+}
+
+func (this *Rule) render(source, topic, topicBase, device string) string {
+    tmpl := pgtmpl.NewTemplate(source)
+    tmpl.SetStrRepl("topic",      topic)
+    tmpl.SetStrRepl("topicBase",  topicBase)
+    tmpl.SetStrRepl("device",     device)
+    return tmpl.Pack()
+}
+//
+// Suggest() returns disabled rule which would provision device of
+// topic base seen in topic, the operator reviews and enables it.
+// Pattern is built from topic because data topic may be not under
+// topic base, device segment is the last topic base segment found
+// in topic.
+//
+func Suggest(topicBase string, topic string, schemaId pgschema.UUID) *Rule {
+    rule := NewRule()
+    rule.SchemaId   = schemaId
+
+    baseSegments := strings.Split(strings.TrimPrefix(topicBase, "/"), "/")
+    device := baseSegments[len(baseSegments) - 1]
+    segments := strings.Split(strings.TrimPrefix(topic, "/"), "/")
+    index := -1
+    for i := len(segments) - 1; i >= 0; i-- {
+        if segments[i] == device {
+            index = i
+            break
+        }
+    }
+    if index < 0 {
+        rule.Segment    = len(segments) - 1
+        rule.Allow      = append(rule.Allow, "^" + regexp.QuoteMeta(topic) + "$")
+        return rule
+    }
+    prefix := strings.Join(segments[:index + 1], "/")
+    if strings.HasPrefix(topic, "/") {
+        prefix = "/" + prefix
+    }
+    rule.Segment    = index
+    rule.Allow      = append(rule.Allow, "^" + regexp.QuoteMeta(prefix) + "(/|$)")
+    return rule
+}
+//
+// Limiter
+//
+// Limiter counts device creations in one minute window.
+//
+type Limiter struct {
+    limit       int
+    count       int
+    window      int64
+    mutex       sync.Mutex
+}
+
+func NewLimiter(limit int) *Limiter {
+    return &Limiter{
+        limit:  limit,
+    }
+}
+
+func (this *Limiter) SetLimit(limit int) {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    this.limit = limit
+}
+
+func (this *Limiter) Allow() bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    if this.limit <= 0 {
+        return true
+    }
+    window := time.Now().Unix() / 60
+    if window != this.window {
+        this.window = window
+        this.count  = 0
+    }
+    if this.count >= this.limit {
+        return false
+    }
+    this.count += 1
+    return true
+}
+//EOF
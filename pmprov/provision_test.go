@@ -0,0 +1,113 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmprov
+
+import (
+    "strings"
+    "testing"
+)
+
+
+func TestRule(t *testing.T) {
+
+    rule, err := RuleFromString(`{"enabled": true, "segment": 1, "schemaId": "6a34e442-cc3c-4586-853e-9058e1fd7739",
+                                    "deny": ["^dev/test/"], "properties": {"Message": "<<device>>"}}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if rule.Permitted("dev/test/status") {
+        t.Error("denied topic permitted")
+    }
+    if !rule.Permitted("dev/a1b2/status") {
+        t.Error("topic not permitted")
+    }
+
+    device, topicBase, err := rule.Device("/dev/a1b2/status")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if device != "a1b2" || topicBase != "/dev/a1b2" {
+        t.Error("wrong device", device, topicBase)
+    }
+    if rule.Values("", topicBase, device)["Message"] != "a1b2" {
+        t.Error("wrong property value")
+    }
+
+    _, err = RuleFromString(`{"enabled": true}`)
+    if err == nil {
+        t.Error("rule without schema id accepted")
+    }
+}
+
+func TestSuggest(t *testing.T) {
+    rule := Suggest("/dev/a1b2", "/dev/a1b2/status", "6a34e442-cc3c-4586-853e-9058e1fd7739")
+    rule.Enabled = true
+    err := rule.Compile()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !rule.Permitted("/dev/a1b2/status") || rule.Permitted("/dev/a1b3/status") {
+        t.Error("wrong suggested allow list", rule.Allow)
+    }
+    _, topicBase, _ := rule.Device("/dev/a1b2/status")
+    if topicBase != "/dev/a1b2" {
+        t.Error("wrong suggested segment", rule.Segment)
+    }
+}
+//
+// TestSuggestRoundTrip checks suggested rule permits observed topic
+// and finds device code in it, also for data topic not under base
+//
+func TestSuggestRoundTrip(t *testing.T) {
+    tests := []struct {
+        topicBase   string
+        topic       string
+        other       string
+    }{
+        { "/dev/a1b2",          "/dev/a1b2/status",             "/dev/a1b3/status" },
+        { "dev/a1b2",           "dev/a1b2",                     "dev/a1b3" },
+        { "SENSO8/a1b2",        "SENSO8/nbiot/data/a1b2",       "SENSO8/nbiot/data/a1b3" },
+        { "gw/a1b2",            "sys/a1b2/data/up",             "sys/a1b3/data/up" },
+        { "gw/a1b2",            "sys/data/up",                  "sys/data/down" },
+    }
+    for _, test := range tests {
+        rule := Suggest(test.topicBase, test.topic, "6a34e442-cc3c-4586-853e-9058e1fd7739")
+        if rule.Enabled {
+            t.Error("suggested rule enabled")
+        }
+        decoded, err := RuleFromString(rule.GetJSON())
+        if err != nil {
+            t.Fatal(err)
+        }
+        decoded.Enabled = true
+        err = decoded.Compile()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !decoded.Permitted(test.topic) {
+            t.Error("observed topic not permitted:", test.topic, decoded.Allow)
+        }
+        if decoded.Permitted(test.other) {
+            t.Error("other topic permitted:", test.other, decoded.Allow)
+        }
+        device, _, err := decoded.Device(test.topic)
+        if err != nil {
+            t.Error(err)
+        }
+        if strings.Contains(test.topic, "a1b2") && device != "a1b2" {
+            t.Error("wrong device code of", test.topic, ":", device)
+        }
+    }
+}
+
+func TestLimiter(t *testing.T) {
+    limiter := NewLimiter(2)
+    if !limiter.Allow() || !limiter.Allow() {
+        t.Error("limiter denied under limit")
+    }
+    if limiter.Allow() {
+        t.Error("limiter allowed over limit")
+    }
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmqueue
+
+import (
+    "sync"
+)
+//
+// Backlog
+//
+// Backlog buffers items while they cannot be delivered, e.g. when core
+// is unavailable. Full backlog evicts the oldest item.
+//
+type Backlog struct {
+    size        int
+    items       []interface{}
+    evicted     int64
+    mutex       sync.Mutex
+}
+
+func NewBacklog(size int) *Backlog {
+    return &Backlog{
+        size:   size,
+        items:  make([]interface{}, 0),
+    }
+}
+//
+// Push() adds item, returns evicted item or nil. Zero size backlog
+// returns pushed item back.
+//
+func (this *Backlog) Push(item interface{}) interface{} {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    if this.size <= 0 {
+        this.evicted += 1
+        return item
+    }
+    var evicted interface{}
+    if len(this.items) >= this.size {
+        evicted = this.items[0]
+        this.items = this.items[1:]
+        this.evicted += 1
+    }
+    this.items = append(this.items, item)
+    return evicted
+}
+//
+// Drain() takes all items in push order
+//
+func (this *Backlog) Drain() []interface{} {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    items := this.items
+    this.items = make([]interface{}, 0)
+    return items
+}
+
+//
+// Requeue() puts back undelivered items before pushed ones keeping
+// their order, returns items evicted by size, the oldest first
+//
+func (this *Backlog) Requeue(items []interface{}) []interface{} {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    merged := append(append(make([]interface{}, 0, len(items) + len(this.items)), items...), this.items...)
+    evicted := make([]interface{}, 0)
+    if len(merged) > this.size {
+        overflow := len(merged) - this.size
+        evicted = merged[:overflow]
+        merged  = merged[overflow:]
+        this.evicted += int64(overflow)
+    }
+    this.items = merged
+    return evicted
+}
+
+func (this *Backlog) Len() int {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return len(this.items)
+}
+
+func (this *Backlog) Evicted() int64 {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return this.evicted
+}
+//EOF
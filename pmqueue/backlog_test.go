@@ -0,0 +1,42 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmqueue
+
+import (
+    "testing"
+)
+
+func TestBacklog(t *testing.T) {
+    backlog := NewBacklog(2)
+    for _, item := range []string{ "a", "b" } {
+        if backlog.Push(item) != nil {
+            t.Error("item evicted before backlog is full")
+        }
+    }
+    evicted := backlog.Push("c")
+    if evicted != "a" || backlog.Evicted() != 1 {
+        t.Error("wrong evicted item", evicted)
+    }
+    items := backlog.Drain()
+    if len(items) != 2 || items[0] != "b" || items[1] != "c" || backlog.Len() != 0 {
+        t.Error("wrong drained items", items)
+    }
+
+    backlog.Push("d")
+    evictedItems := backlog.Requeue([]interface{}{ "b", "c" })
+    if len(evictedItems) != 1 || evictedItems[0] != "b" || backlog.Evicted() != 2 {
+        t.Error("wrong requeue eviction", evictedItems)
+    }
+    items = backlog.Drain()
+    if len(items) != 2 || items[0] != "c" || items[1] != "d" {
+        t.Error("wrong requeued items", items)
+    }
+
+    disabled := NewBacklog(0)
+    if disabled.Push("a") != "a" {
+        t.Error("zero size backlog kept item")
+    }
+}
+//EOF
@@ -0,0 +1,218 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmrpc
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+)
+
+const (
+    DefaultField        string  = "correlationId"
+    DefaultTimeout      int     = 10    // sec
+)
+//
+// Requester
+//
+// Requester keeps requests waiting for device reply. Correlation id
+// is carried in payload json field, the device echoes it in the reply.
+// Reply topics are reference counted to share one subscription
+// between concurrent requests. Requests wait for subscribe of the
+// first request and get its error, request after the last one waits
+// for unsubscribe to finish.
+//
+type Requester struct {
+    pending     map[string]*request
+    topics      map[string]*replyTopic
+    mutex       sync.Mutex
+}
+
+type request struct {
+    field       string
+    reply       chan []byte
+}
+
+type replyTopic struct {
+    users       int
+    ready       chan struct{}   // closed after subscribe
+    closing     chan struct{}   // closed after unsubscribe
+    err         error
+}
+
+type SubscribeFunc = func() error
+
+func NewRequester() *Requester {
+    return &Requester{
+        pending:    make(map[string]*request),
+        topics:     make(map[string]*replyTopic),
+    }
+}
+//
+// Inject() sets correlation id field of json object payload
+//
+func Inject(payload []byte, field string, id string) ([]byte, error) {
+    var err error
+    object := make(map[string]interface{})
+    if len(payload) > 0 {
+        err = json.Unmarshal(payload, &object)
+        if err != nil {
+            return payload, errors.New("request payload is not json object")
+        }
+    }
+    object[field] = id
+    return json.Marshal(object)
+}
+//
+// Extract() returns correlation id of reply payload
+//
+func Extract(payload []byte, field string) (string, bool) {
+    object := make(map[string]interface{})
+    err := json.Unmarshal(payload, &object)
+    if err != nil {
+        return "", false
+    }
+    id, ok := object[field].(string)
+    return id, ok
+}
+//
+// Acquire() registers request, subscribe is called on first use of
+// reply topic. Request is not registered on subscribe error.
+//
+func (this *Requester) Acquire(id string, field string, topic string, subscribe SubscribeFunc) error {
+    var entry *replyTopic
+    first := false
+    for {
+        this.mutex.Lock()
+        exists := false
+        entry, exists = this.topics[topic]
+        if exists && entry.closing != nil {
+            closing := entry.closing
+            this.mutex.Unlock()
+            <-closing
+            continue
+        }
+        if !exists {
+            entry = &replyTopic{
+                ready:  make(chan struct{}),
+            }
+            this.topics[topic] = entry
+            first = true
+        }
+        entry.users += 1
+        this.mutex.Unlock()
+        break
+    }
+
+    if first {
+        err := subscribe()
+        this.mutex.Lock()
+        entry.err = err
+        if err != nil && this.topics[topic] == entry {
+            delete(this.topics, topic)
+        }
+        close(entry.ready)
+        this.mutex.Unlock()
+    }
+    <-entry.ready
+
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    if entry.err != nil {
+        entry.users -= 1
+        return entry.err
+    }
+    this.pending[id] = &request{
+        field:  field,
+        reply:  make(chan []byte, 1),
+    }
+    return nil
+}
+//
+// Release() drops request, unsubscribe is called on last use of reply topic
+//
+func (this *Requester) Release(id string, topic string, unsubscribe SubscribeFunc) error {
+    this.mutex.Lock()
+    delete(this.pending, id)
+    entry, exists := this.topics[topic]
+    if !exists {
+        this.mutex.Unlock()
+        return nil
+    }
+    entry.users -= 1
+    if entry.users > 0 {
+        this.mutex.Unlock()
+        return nil
+    }
+    entry.closing = make(chan struct{})
+    this.mutex.Unlock()
+
+    err := unsubscribe()
+
+    this.mutex.Lock()
+    delete(this.topics, topic)
+    close(entry.closing)
+    this.mutex.Unlock()
+    return err
+}
+//
+// Pending() returns number of requests waiting reply
+//
+func (this *Requester) Pending() int {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    return len(this.pending)
+}
+//
+// Dispatch() passes reply to waiting request
+//
+func (this *Requester) Dispatch(payload []byte) bool {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+
+    fields := make(map[string]bool)
+    for _, pending := range this.pending {
+        fields[pending.field] = true
+    }
+    for field := range fields {
+        id, ok := Extract(payload, field)
+        if !ok {
+            continue
+        }
+        pending, exists := this.pending[id]
+        if !exists || pending.field != field {
+            continue
+        }
+        select {
+            case pending.reply <- payload:
+            default:
+        }
+        return true
+    }
+    return false
+}
+//
+// Wait() blocks until reply, timeout or context done
+//
+func (this *Requester) Wait(ctx context.Context, id string, timeout time.Duration) ([]byte, error) {
+    this.mutex.Lock()
+    pending, exists := this.pending[id]
+    this.mutex.Unlock()
+    if !exists {
+        return nil, fmt.Errorf("request %s not registered", id)
+    }
+    select {
+        case payload := <-pending.reply:
+            return payload, nil
+        case <-time.After(timeout):
+            return nil, fmt.Errorf("request %s reply timeout", id)
+        case <-ctx.Done():
+            return nil, ctx.Err()
+    }
+}
+//EOF
@@ -0,0 +1,152 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmrpc
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+
+func TestRequester(t *testing.T) {
+    requester := NewRequester()
+
+    payload, err := Inject([]byte(`{"cmd": "status"}`), DefaultField, "a1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if id, _ := Extract(payload, DefaultField); id != "a1" {
+        t.Error("wrong injected id", string(payload))
+    }
+    _, err = Inject([]byte(`status`), DefaultField, "a1")
+    if err == nil {
+        t.Error("non json payload accepted")
+    }
+
+    var subscribes, unsubscribes int32
+    subscribe := func() error {
+        atomic.AddInt32(&subscribes, 1)
+        return nil
+    }
+    unsubscribe := func() error {
+        atomic.AddInt32(&unsubscribes, 1)
+        return nil
+    }
+    if requester.Acquire("a1", DefaultField, "dev/1/reply", subscribe) != nil {
+        t.Error("first request not acquired")
+    }
+    if requester.Acquire("a2", DefaultField, "dev/1/reply", subscribe) != nil {
+        t.Error("second request not acquired")
+    }
+    if atomic.LoadInt32(&subscribes) != 1 {
+        t.Error("reply topic subscribed", subscribes, "times")
+    }
+    if requester.Dispatch([]byte(`{"correlationId": "b1"}`)) {
+        t.Error("unknown reply dispatched")
+    }
+    go requester.Dispatch([]byte(`{"correlationId": "a2", "status": "ok"}`))
+
+    reply, err := requester.Wait(context.Background(), "a2", time.Second)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(reply) != `{"correlationId": "a2", "status": "ok"}` {
+        t.Error("wrong reply", string(reply))
+    }
+    _, err = requester.Wait(context.Background(), "a1", 10 * time.Millisecond)
+    if err == nil {
+        t.Error("timeout not reported")
+    }
+    requester.Release("a1", "dev/1/reply", unsubscribe)
+    if atomic.LoadInt32(&unsubscribes) != 0 {
+        t.Error("reply topic released while in use")
+    }
+    requester.Release("a2", "dev/1/reply", unsubscribe)
+    if atomic.LoadInt32(&unsubscribes) != 1 || requester.Pending() != 0 {
+        t.Error("last reply topic use not released")
+    }
+}
+
+func TestRequesterSubscribeError(t *testing.T) {
+    requester := NewRequester()
+    failure := errors.New("subscribe failed")
+    started := make(chan struct{})
+    proceed := make(chan struct{})
+    subscribe := func() error {
+        close(started)
+        <-proceed
+        return failure
+    }
+
+    var wg sync.WaitGroup
+    errs := make([]error, 2)
+    wg.Add(1)
+    go func() {
+        defer wg.Done()
+        errs[0] = requester.Acquire("a1", DefaultField, "dev/1/reply", subscribe)
+    }()
+    <-started
+    wg.Add(1)
+    go func() {
+        defer wg.Done()
+        errs[1] = requester.Acquire("a2", DefaultField, "dev/1/reply", func() error {
+            t.Error("second request subscribed")
+            return nil
+        })
+    }()
+    time.Sleep(10 * time.Millisecond)
+    close(proceed)
+    wg.Wait()
+
+    if errs[0] != failure || errs[1] != failure {
+        t.Error("subscribe error not propagated:", errs)
+    }
+    if requester.Pending() != 0 {
+        t.Error("failed requests registered")
+    }
+    err := requester.Acquire("a3", DefaultField, "dev/1/reply", func() error { return nil })
+    if err != nil {
+        t.Error("subscribe not retried after error:", err)
+    }
+}
+
+func TestRequesterReleaseOrder(t *testing.T) {
+    requester := NewRequester()
+    ok := func() error { return nil }
+    requester.Acquire("a1", DefaultField, "dev/1/reply", ok)
+
+    var subscribed int32
+    unsubscribing := make(chan struct{})
+    proceed := make(chan struct{})
+    done := make(chan struct{})
+    go func() {
+        requester.Release("a1", "dev/1/reply", func() error {
+            close(unsubscribing)
+            <-proceed
+            if atomic.LoadInt32(&subscribed) != 0 {
+                t.Error("subscribe before unsubscribe finished")
+            }
+            return nil
+        })
+    }()
+    <-unsubscribing
+    go func() {
+        requester.Acquire("a2", DefaultField, "dev/1/reply", func() error {
+            atomic.StoreInt32(&subscribed, 1)
+            return nil
+        })
+        close(done)
+    }()
+    time.Sleep(10 * time.Millisecond)
+    close(proceed)
+    <-done
+    if atomic.LoadInt32(&subscribed) != 1 {
+        t.Error("reply topic not subscribed again")
+    }
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmrules
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+)
+//
+// Path
+//
+// Path is JSONPath subset: root $, dot members, quoted bracket
+// members and array indexes, e.g. $.data[0].temp or $['sensor id'].
+//
+type Path struct {
+    source      string
+    steps       []step
+}
+
+type step struct {
+    member      string
+    index       int
+    isIndex     bool
+}
+
+func CompilePath(source string) (*Path, error) {
+    var err error
+    path := &Path{
+        source: source,
+        steps:  make([]step, 0),
+    }
+    if !strings.HasPrefix(source, "$") {
+        return path, fmt.Errorf("json path %s: must start with $", source)
+    }
+    rest := source[1:]
+    for len(rest) > 0 {
+        switch rest[0] {
+            case '.':
+                rest = rest[1:]
+                end := strings.IndexAny(rest, ".[")
+                if end < 0 {
+                    end = len(rest)
+                }
+                if end == 0 {
+                    return path, fmt.Errorf("json path %s: empty member", source)
+                }
+                path.steps = append(path.steps, step{ member: rest[:end] })
+                rest = rest[end:]
+            case '[':
+                end := strings.Index(rest, "]")
+                if end < 0 {
+                    return path, fmt.Errorf("json path %s: unclosed bracket", source)
+                }
+                inner := rest[1:end]
+                rest = rest[end + 1:]
+                if len(inner) > 1 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner) - 1] == inner[0] {
+                    path.steps = append(path.steps, step{ member: inner[1:len(inner) - 1] })
+                    continue
+                }
+                index, err := strconv.Atoi(inner)
+                if err != nil || index < 0 {
+                    return path, fmt.Errorf("json path %s: wrong index %s", source, inner)
+                }
+                path.steps = append(path.steps, step{ index: index, isIndex: true })
+            default:
+                return path, fmt.Errorf("json path %s: unexpected %c", source, rest[0])
+        }
+    }
+    return path, err
+}
+//
+// Lookup() returns value at path in decoded json document
+//
+func (this *Path) Lookup(document interface{}) (interface{}, bool) {
+    current := document
+    for _, step := range this.steps {
+        if step.isIndex {
+            array, ok := current.([]interface{})
+            if !ok || step.index >= len(array) {
+                return nil, false
+            }
+            current = array[step.index]
+            continue
+        }
+        object, ok := current.(map[string]interface{})
+        if !ok {
+            return nil, false
+        }
+        current, ok = object[step.member]
+        if !ok {
+            return nil, false
+        }
+    }
+    return current, true
+}
+//
+// valueString() formats scalar as is and other values as json
+//
+func valueString(value interface{}) string {
+    switch value.(type) {
+        case string:
+            return value.(string)
+        case nil:
+            return ""
+        case float64:
+            return strconv.FormatFloat(value.(float64), 'f', -1, 64)
+        case bool:
+            return strconv.FormatBool(value.(bool))
+    }
+    jsonBytes, _ := json.Marshal(value)
+    return string(jsonBytes)
+}
+//EOF
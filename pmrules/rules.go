@@ -0,0 +1,235 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmrules
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+const (
+    OpExists    string  = "exists"
+    OpMissing   string  = "missing"
+    OpEqual     string  = "eq"
+    OpNotEqual  string  = "ne"
+    OpMatch     string  = "match"
+)
+//
+// Condition
+//
+// Condition checks payload json value at path, rule applies
+// only when condition holds.
+//
+type Condition struct {
+    Path        string      `json:"path"`
+    Op          string      `json:"op"`
+    Value       string      `json:"value"`
+
+    path        *Path
+    valueRe     *regexp.Regexp
+}
+//
+// Rule
+//
+// Rule runs on topics matching regexp. Topic, topic base and tag
+// values are templates with $1 or ${name} capture group references.
+//
+type Rule struct {
+    Name        string              `json:"name"`
+    Match       string              `json:"match"`
+    When        *Condition          `json:"when,omitempty"`
+    Drop        bool                `json:"drop,omitempty"`
+    Topic       string              `json:"topic,omitempty"`       // topic rewrite
+    TopicBase   string              `json:"topicBase,omitempty"`   // device lookup key
+    Extract     map[string]string   `json:"extract,omitempty"`     // tag name to json path
+    Tags        map[string]string   `json:"tags,omitempty"`
+    Continue    bool                `json:"continue,omitempty"`    // evaluate next rules
+
+    matchRe     *regexp.Regexp
+    extract     map[string]*Path
+}
+//
+// Rules
+//
+type Rules struct {
+    Rules       []Rule              `json:"rules"`
+}
+//
+// Result
+//
+type Result struct {
+    Topic       string
+    TopicBase   string
+    Tags        map[string]string
+    Dropped     bool
+    Applied     []string
+}
+
+func NewRules() *Rules {
+    return &Rules{
+        Rules:  make([]Rule, 0),
+    }
+}
+
+func RulesFromString(source string) (*Rules, error) {
+    var err error
+    rules := NewRules()
+    if len(strings.TrimSpace(source)) == 0 {
+        return rules, err
+    }
+    err = json.Unmarshal([]byte(source), rules)
+    if err != nil {
+        return rules, err
+    }
+    err = rules.Compile()
+    if err != nil {
+        return rules, err
+    }
+    return rules, err
+}
+
+func (this *Rules) Compile() error {
+    var err error
+    for i := range this.Rules {
+        rule := &this.Rules[i]
+        if len(rule.Name) == 0 {
+            rule.Name = fmt.Sprintf("rule%d", i)
+        }
+        err = rule.compile()
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+
+func (this *Rule) compile() error {
+    var err error
+    this.matchRe, err = regexp.Compile(this.Match)
+    if err != nil {
+        return fmt.Errorf("topic rule %s: wrong match pattern: %s", this.Name, err)
+    }
+    this.extract = make(map[string]*Path)
+    for tag, source := range this.Extract {
+        path, err := CompilePath(source)
+        if err != nil {
+            return fmt.Errorf("topic rule %s: %s", this.Name, err)
+        }
+        this.extract[tag] = path
+    }
+    if this.When != nil {
+        err = this.When.compile()
+        if err != nil {
+            return fmt.Errorf("topic rule %s: %s", this.Name, err)
+        }
+    }
+    return err
+}
+
+func (this *Condition) compile() error {
+    var err error
+    this.path, err = CompilePath(this.Path)
+    if err != nil {
+        return err
+    }
+    switch this.Op {
+        case OpExists, OpMissing, OpEqual, OpNotEqual:
+        case OpMatch:
+            this.valueRe, err = regexp.Compile(this.Value)
+            if err != nil {
+                return fmt.Errorf("condition: wrong value pattern: %s", err)
+            }
+        default:
+            return fmt.Errorf("condition: unknown op %s", this.Op)
+    }
+    return err
+}
+
+func (this *Condition) holds(document interface{}) bool {
+    value, exists := this.path.Lookup(document)
+    switch this.Op {
+        case OpExists:
+            return exists
+        case OpMissing:
+            return !exists
+        case OpEqual:
+            return exists && valueString(value) == this.Value
+        case OpNotEqual:
+            return !exists || valueString(value) != this.Value
+        case OpMatch:
+            return exists && this.valueRe.MatchString(valueString(value))
+    }
+    return false
+}
+
+func (this *Rules) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// Apply() runs rules in order, first applied rule stops evaluation
+// unless it has continue flag. Topic base defaults to topic.
+//
+func (this *Rules) Apply(topic string, payload []byte) Result {
+    result := Result{
+        Topic:      topic,
+        Tags:       make(map[string]string),
+        Applied:    make([]string, 0),
+    }
+    var document interface{}
+    var decoded bool
+
+    for i := range this.Rules {
+        rule := &this.Rules[i]
+        match := rule.matchRe.FindStringSubmatchIndex(result.Topic)
+        if match == nil {
+            continue
+        }
+        if rule.When != nil || len(rule.extract) > 0 {
+            if !decoded {
+                json.Unmarshal(payload, &document)
+                decoded = true
+            }
+        }
+        if rule.When != nil && !rule.When.holds(document) {
+            continue
+        }
+        result.Applied = append(result.Applied, rule.Name)
+        if rule.Drop {
+            result.Dropped = true
+            return result
+        }
+        source := result.Topic
+        if len(rule.TopicBase) > 0 {
+            result.TopicBase = rule.expand(rule.TopicBase, source, match)
+        }
+        if len(rule.Topic) > 0 {
+            result.Topic = rule.expand(rule.Topic, source, match)
+        }
+        for tag, template := range rule.Tags {
+            result.Tags[tag] = rule.expand(template, source, match)
+        }
+        for tag, path := range rule.extract {
+            value, exists := path.Lookup(document)
+            if exists {
+                result.Tags[tag] = valueString(value)
+            }
+        }
+        if !rule.Continue {
+            break
+        }
+    }
+    if len(result.TopicBase) == 0 {
+        result.TopicBase = result.Topic
+    }
+    return result
+}
+
+func (this *Rule) expand(template string, source string, match []int) string {
+    return string(this.matchRe.ExpandString(nil, template, source, match))
+}
+//EOF
@@ -0,0 +1,65 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmrules
+
+import (
+    "testing"
+)
+
+
+func TestRules(t *testing.T) {
+    rules, err := RulesFromString(`{"rules": [
+            {"name": "drop-test", "match": "^dev/", "when": {"path": "$.test", "op": "eq", "value": "true"}, "drop": true},
+            {"name": "rewrite", "match": "^dev/(?P<code>[^/]+)/(.*)$", "topic": "site/${code}/$2",
+                "tags": {"device": "$1"}, "extract": {"temp": "$.data[0].t"}, "continue": true},
+            {"name": "base", "match": "^site/([^/]+)/", "topicBase": "site/$1"}
+        ]}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    result := rules.Apply("dev/a1/status", []byte(`{"test": true}`))
+    if !result.Dropped {
+        t.Error("message not dropped")
+    }
+
+    result = rules.Apply("dev/a1/status", []byte(`{"data": [{"t": 21.5}]}`))
+    if result.Dropped {
+        t.Error("message dropped")
+    }
+    if result.Topic != "site/a1/status" || result.TopicBase != "site/a1" {
+        t.Error("wrong rewrite", result.Topic, result.TopicBase)
+    }
+    if result.Tags["device"] != "a1" || result.Tags["temp"] != "21.5" {
+        t.Error("wrong tags", result.Tags)
+    }
+
+    result = rules.Apply("other/topic", nil)
+    if result.Topic != "other/topic" || result.TopicBase != "other/topic" || len(result.Applied) != 0 {
+        t.Error("unmatched topic changed", result)
+    }
+
+    _, err = RulesFromString(`{"rules": [{"match": "(", "topic": "x"}]}`)
+    if err == nil {
+        t.Error("wrong pattern accepted")
+    }
+    _, err = RulesFromString(`{"rules": [{"match": "x", "extract": {"a": "data.a"}}]}`)
+    if err == nil {
+        t.Error("wrong json path accepted")
+    }
+}
+
+func TestPath(t *testing.T) {
+    path, err := CompilePath(`$.a['b c'][1]`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    value, exists := path.Lookup(map[string]interface{}{
+        "a": map[string]interface{}{ "b c": []interface{}{ "x", "y" } },
+    })
+    if !exists || value != "y" {
+        t.Error("wrong lookup", value)
+    }
+}
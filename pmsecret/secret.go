@@ -0,0 +1,185 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmsecret
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "regexp"
+    "strings"
+)
+
+const (
+    Masked              string  = "******"
+    encryptedPrefix     string  = "enc:v1:"
+)
+
+var referencePattern = regexp.MustCompile(`^\$\{(env|file):([^}]+)\}$`)
+//
+// Resolve() replaces reference by secret, e.g. ${env:MQTT_PASSWORD}
+// or ${file:/run/secrets/mqtt-password}, other values returned as is.
+// Trailing newline of secret file is dropped.
+//
+func Resolve(value string) (string, error) {
+    var err error
+    match := referencePattern.FindStringSubmatch(strings.TrimSpace(value))
+    if match == nil {
+        return value, err
+    }
+    switch match[1] {
+        case "env":
+            secret, exists := os.LookupEnv(match[2])
+            if !exists {
+                return "", fmt.Errorf("secret env %s not set", match[2])
+            }
+            return secret, err
+        default:
+            data, err := ioutil.ReadFile(match[2])
+            if err != nil {
+                return "", fmt.Errorf("secret file: %s", err)
+            }
+            return strings.TrimRight(string(data), "\r\n"), err
+    }
+}
+
+func IsReference(value string) bool {
+    return referencePattern.MatchString(strings.TrimSpace(value))
+}
+//
+// Mask() hides non-empty secret for logs and reports
+//
+func Mask(value string) string {
+    if len(value) == 0 {
+        return value
+    }
+    return Masked
+}
+//
+// Cipher
+//
+// Cipher encrypts values stored in core properties with AES-GCM,
+// key is derived from passphrase held by the bridge. Encrypted value
+// looks like enc:v1:<base64 nonce and ciphertext>.
+//
+type Cipher struct {
+    aead        cipher.AEAD
+}
+
+func NewCipher(passphrase string) (*Cipher, error) {
+    if len(passphrase) == 0 {
+        return nil, errors.New("empty secret key")
+    }
+    key := sha256.Sum256([]byte(passphrase))
+    block, err := aes.NewCipher(key[:])
+    if err != nil {
+        return nil, err
+    }
+    aead, err := cipher.NewGCM(block)
+    if err != nil {
+        return nil, err
+    }
+    return &Cipher{ aead: aead }, err
+}
+
+func IsEncrypted(value string) bool {
+    return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func (this *Cipher) Encrypt(value string) (string, error) {
+    var err error
+    nonce := make([]byte, this.aead.NonceSize())
+    _, err = io.ReadFull(rand.Reader, nonce)
+    if err != nil {
+        return "", err
+    }
+    sealed := this.aead.Seal(nonce, nonce, []byte(value), nil)
+    return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), err
+}
+//
+// Decrypt() opens encrypted value, plain value returned as is
+//
+func (this *Cipher) Decrypt(value string) (string, error) {
+    var err error
+    if !IsEncrypted(value) {
+        return value, err
+    }
+    sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+    if err != nil {
+        return "", err
+    }
+    nonceSize := this.aead.NonceSize()
+    if len(sealed) < nonceSize {
+        return "", errors.New("encrypted value too short")
+    }
+    plain, err := this.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+    if err != nil {
+        return "", errors.New("unable decrypt value, wrong secret key")
+    }
+    return string(plain), err
+}
+//
+// Store
+//
+// Store encodes credentials written to core properties and decodes
+// values read back, references are resolved on decode.
+//
+type Store struct {
+    cipher      *Cipher
+    encrypt     bool
+}
+//
+// NewStore() makes store, empty key disables encryption
+//
+func NewStore(key string, encrypt bool) (*Store, error) {
+    var err error
+    store := &Store{}
+    if len(key) == 0 {
+        if encrypt {
+            return store, errors.New("empty secret key")
+        }
+        return store, err
+    }
+    store.cipher, err = NewCipher(key)
+    if err != nil {
+        return store, err
+    }
+    store.encrypt = encrypt
+    return store, err
+}
+//
+// Encrypting() reports if Encode() encrypts values
+//
+func (this *Store) Encrypting() bool {
+    return this.encrypt
+}
+//
+// Encode() encrypts value if encryption enabled, references and empty
+// values are kept as is
+//
+func (this *Store) Encode(value string) (string, error) {
+    if !this.encrypt || len(value) == 0 || IsEncrypted(value) || IsReference(value) {
+        return value, nil
+    }
+    return this.cipher.Encrypt(value)
+}
+
+func (this *Store) Decode(value string) (string, error) {
+    if IsEncrypted(value) {
+        if this.cipher == nil {
+            return "", errors.New("encrypted value but no secret key")
+        }
+        return this.cipher.Decrypt(value)
+    }
+    return Resolve(value)
+}
+//EOF
@@ -0,0 +1,93 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmsecret
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestResolve(t *testing.T) {
+    os.Setenv("PMSECRET_TEST", "from-env")
+    defer os.Unsetenv("PMSECRET_TEST")
+
+    dir, err := ioutil.TempDir("", "pmsecret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+    fileName := filepath.Join(dir, "password")
+    ioutil.WriteFile(fileName, []byte("from-file\n"), 0600)
+
+    cases := map[string]string{
+        "plain":                        "plain",
+        "${env:PMSECRET_TEST}":         "from-env",
+        "${file:" + fileName + "}":     "from-file",
+    }
+    for value, expected := range cases {
+        secret, err := Resolve(value)
+        if err != nil || secret != expected {
+            t.Error("wrong secret for", value, secret, err)
+        }
+    }
+    _, err = Resolve("${env:PMSECRET_MISSING}")
+    if err == nil {
+        t.Error("missing env not reported")
+    }
+}
+
+func TestCipher(t *testing.T) {
+    cipher, err := NewCipher("bridge key")
+    if err != nil {
+        t.Fatal(err)
+    }
+    encrypted, err := cipher.Encrypt("qwerty")
+    if err != nil || !IsEncrypted(encrypted) {
+        t.Fatal("wrong encrypted value", encrypted, err)
+    }
+    plain, err := cipher.Decrypt(encrypted)
+    if err != nil || plain != "qwerty" {
+        t.Error("wrong decrypted value", plain, err)
+    }
+    plain, err = cipher.Decrypt("not encrypted")
+    if err != nil || plain != "not encrypted" {
+        t.Error("plain value changed", plain, err)
+    }
+
+    other, _ := NewCipher("other key")
+    _, err = other.Decrypt(encrypted)
+    if err == nil {
+        t.Error("decrypted with wrong key")
+    }
+}
+
+func TestStore(t *testing.T) {
+    store, err := NewStore("bridge key", true)
+    if err != nil {
+        t.Fatal(err)
+    }
+    encoded, _ := store.Encode("qwerty")
+    decoded, err := store.Decode(encoded)
+    if !IsEncrypted(encoded) || err != nil || decoded != "qwerty" {
+        t.Error("wrong store round trip", encoded, decoded, err)
+    }
+    encoded, _ = store.Encode("${env:MQTT_PASSWORD}")
+    if encoded != "${env:MQTT_PASSWORD}" {
+        t.Error("reference encrypted", encoded)
+    }
+
+    plain, _ := NewStore("", false)
+    _, err = plain.Decode(encoded)
+    if err == nil {
+        t.Error("missing env not reported")
+    }
+    encrypted, _ := store.Encode("qwerty")
+    _, err = plain.Decode(encrypted)
+    if err == nil {
+        t.Error("encrypted value decoded without key")
+    }
+}
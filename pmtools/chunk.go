@@ -0,0 +1,27 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com> 
+ */
+
+
+package pmtools
+//
+// SplitChunks() splits data to chunks of size, the last chunk may be
+// shorter. Data not longer than size, including empty data, and zero
+// size give one chunk.
+//
+func SplitChunks(data []byte, size int) [][]byte {
+    chunks := make([][]byte, 0)
+    if size <= 0 || len(data) <= size {
+        return append(chunks, data)
+    }
+    for start := 0; start < len(data); start += size {
+        end := start + size
+        if end > len(data) {
+            end = len(data)
+        }
+        chunks = append(chunks, data[start:end])
+    }
+    return chunks
+}
+
+//EOF
@@ -0,0 +1,48 @@
+/*
+ * Copyright: Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmtools
+
+import (
+    "bytes"
+    "testing"
+)
+
+func TestSplitChunks(t *testing.T) {
+    tests := []struct {
+        name        string
+        length      int
+        size        int
+        expected    []int
+    }{
+        { "empty",              0,  4,  []int{ 0 } },
+        { "shorter",            3,  4,  []int{ 3 } },
+        { "single",             4,  4,  []int{ 4 } },
+        { "exact multiple",     12, 4,  []int{ 4, 4, 4 } },
+        { "partial last",       10, 4,  []int{ 4, 4, 2 } },
+        { "one byte last",      9,  4,  []int{ 4, 4, 1 } },
+        { "zero size",          10, 0,  []int{ 10 } },
+        { "negative size",      10, -1, []int{ 10 } },
+    }
+    for _, test := range tests {
+        data := make([]byte, test.length)
+        for i := range data {
+            data[i] = byte(i)
+        }
+        chunks := SplitChunks(data, test.size)
+        if len(chunks) != len(test.expected) {
+            t.Errorf("%s: %d chunks, expected %d", test.name, len(chunks), len(test.expected))
+            continue
+        }
+        for i, chunk := range chunks {
+            if len(chunk) != test.expected[i] {
+                t.Errorf("%s: chunk %d length %d, expected %d", test.name, i, len(chunk), test.expected[i])
+            }
+        }
+        if !bytes.Equal(bytes.Join(chunks, nil), data) {
+            t.Errorf("%s: joined chunks differ from data", test.name)
+        }
+    }
+}
+//EOF
@@ -60,6 +60,51 @@ func (this *Topics) Add(topicName string) error {
     return err
 }
 
+//
+// Covers() reports topic filter in list which receives every message
+// of given topic or filter
+//
+func (this *Topics) Covers(topic string) bool {
+    for i := range this.Payload {
+        if Covers(this.Payload[i], topic) {
+            return true
+        }
+    }
+    return false
+}
+//
+// Covers() reports filter matching every topic matched by other filter,
+// plain topic is filter matching itself only
+//
+func Covers(filter string, other string) bool {
+    filterLevels := strings.Split(filter, "/")
+    otherLevels  := strings.Split(other, "/")
+    for i := range filterLevels {
+        if filterLevels[i] == "#" {
+            return i == len(filterLevels) - 1
+        }
+        if i >= len(otherLevels) || otherLevels[i] == "#" {
+            return false
+        }
+        if filterLevels[i] == "+" {
+            continue
+        }
+        if filterLevels[i] != otherLevels[i] {
+            return false
+        }
+    }
+    return len(filterLevels) == len(otherLevels)
+}
+//
+// Match() reports filter matching topic name
+//
+func Match(filter string, topic string) bool {
+    if strings.ContainsAny(topic, "+#") {
+        return false
+    }
+    return Covers(filter, topic)
+}
+
 func (this *Topics) GetJSON() string {
     jBytes, _ := json.Marshal(this.Payload)
     return string(jBytes)
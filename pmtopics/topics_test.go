@@ -27,3 +27,38 @@ func TestTopics(t *testing.T) {
         fmt.Println(topics.Payload[i])
     }
 }
+
+func TestCovers(t *testing.T) {
+    tests := []struct {
+        filter      string
+        other       string
+        expected    bool
+    }{
+        { "dev/1/ack",  "dev/1/ack",    true },
+        { "dev/1/ack",  "dev/2/ack",    false },
+        { "dev/+/ack",  "dev/1/ack",    true },
+        { "dev/+/ack",  "dev/1/ack/x",  false },
+        { "dev/#",      "dev/1/ack",    true },
+        { "dev/#",      "dev",          true },
+        { "#",          "dev/1/ack",    true },
+        { "dev/#",      "dev/+/ack",    true },
+        { "dev/+/ack",  "dev/+/ack",    true },
+        { "dev/+/ack",  "dev/#",        false },
+        { "dev/1/ack",  "dev/+/ack",    false },
+        { "dev/+",      "dev/1/ack",    false },
+        { "dev/1",      "dev/1/ack",    false },
+    }
+    for _, test := range tests {
+        if Covers(test.filter, test.other) != test.expected {
+            t.Errorf("covers %s %s: expected %v", test.filter, test.other, test.expected)
+        }
+    }
+    if Match("dev/#", "dev/+/ack") {
+        t.Error("wildcard topic name matched")
+    }
+    topics := TopicsFromString("a/+/status,dev/#")
+    if !topics.Covers("dev/1/ack") || topics.Covers("b/1/status") {
+        t.Error("wrong topics cover")
+    }
+}
+//EOF
@@ -0,0 +1,154 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmvalid
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "sync"
+    "time"
+
+    "app/pmlog"
+    "app/pmqueue"
+)
+
+const DefaultDeadLetterSize int = 1000
+//
+// DeadLetter
+//
+// DeadLetter is envelope of rejected message published to
+// dead-letter topic, payload is base64 encoded.
+//
+type DeadLetter struct {
+    Topic       string      `json:"topic"`
+    Reason      string      `json:"reason"`
+    Error       string      `json:"error"`
+    Timestamp   string      `json:"timestamp"`
+    Encoding    string      `json:"encoding"`
+    Payload     string      `json:"payload"`
+}
+
+func NewDeadLetter(topic string, payload []byte, reason string, err error) *DeadLetter {
+    var letter DeadLetter
+    letter.Topic        = topic
+    letter.Reason       = reason
+    if err != nil {
+        letter.Error    = err.Error()
+    }
+    letter.Timestamp    = time.Now().UTC().Format(time.RFC3339)
+    letter.Encoding     = "base64"
+    letter.Payload      = base64.StdEncoding.EncodeToString(payload)
+    return &letter
+}
+
+func (this *DeadLetter) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+
+type Publisher = func(topic string, message string) error
+
+type outgoingLetter struct {
+    topic       string
+    message     string
+}
+//
+// Outbox
+//
+// Outbox publishes dead letters from own goroutine, so broker message
+// handlers never wait for publish. Full outbox drops the oldest letter.
+//
+type Outbox struct {
+    queue       *pmqueue.Backlog
+    publish     Publisher
+    notify      chan struct{}
+    stop        chan struct{}
+    done        chan struct{}
+    started     bool
+    mutex       sync.Mutex
+}
+
+func NewOutbox(size int, publish Publisher) *Outbox {
+    return &Outbox{
+        queue:      pmqueue.NewBacklog(size),
+        publish:    publish,
+        notify:     make(chan struct{}, 1),
+        stop:       make(chan struct{}),
+        done:       make(chan struct{}),
+    }
+}
+//
+// Send() queues letter, returns false when older letter was dropped
+//
+func (this *Outbox) Send(topic string, letter *DeadLetter) bool {
+    evicted := this.queue.Push(&outgoingLetter{ topic: topic, message: letter.GetJSON() })
+    select {
+        case this.notify <- struct{}{}:
+        default:
+    }
+    return evicted == nil
+}
+
+func (this *Outbox) Len() int {
+    return this.queue.Len()
+}
+
+func (this *Outbox) Dropped() int64 {
+    return this.queue.Evicted()
+}
+//
+// Start() runs publishing goroutine
+//
+func (this *Outbox) Start() {
+    this.mutex.Lock()
+    defer this.mutex.Unlock()
+    if this.started {
+        return
+    }
+    this.started = true
+    go this.loop()
+}
+//
+// Stop() publishes queued letters until deadline and stops goroutine
+//
+func (this *Outbox) Stop(deadline time.Time) {
+    this.mutex.Lock()
+    started := this.started
+    this.started = false
+    this.mutex.Unlock()
+    if !started {
+        return
+    }
+    close(this.stop)
+    select {
+        case <-this.done:
+        case <-time.After(time.Until(deadline)):
+            pmlog.LogWarning("dead-letter outbox not drained in time,", this.Len(), "letters left")
+    }
+}
+
+func (this *Outbox) loop() {
+    defer close(this.done)
+    for {
+        this.flush()
+        select {
+            case <-this.notify:
+            case <-this.stop:
+                this.flush()
+                return
+        }
+    }
+}
+
+func (this *Outbox) flush() {
+    for _, item := range this.queue.Drain() {
+        letter := item.(*outgoingLetter)
+        err := this.publish(letter.topic, letter.message)
+        if err != nil {
+            pmlog.LogError("dead-letter publish error:", err)
+        }
+    }
+}
+//EOF
@@ -0,0 +1,45 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmvalid
+
+import (
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestOutbox(t *testing.T) {
+    release := make(chan struct{})
+    published := make(chan string, 10)
+    outbox := NewOutbox(2, func(topic string, message string) error {
+        <-release
+        published <- topic
+        return nil
+    })
+    outbox.Start()
+
+    sent := make(chan struct{})
+    go func() {
+        for i := 0; i < 4; i++ {
+            outbox.Send("dead", NewDeadLetter("dev/a", nil, ReasonInvalid, errors.New("wrong")))
+        }
+        close(sent)
+    }()
+    select {
+        case <-sent:
+        case <-time.After(time.Second):
+            t.Fatal("send waits for publish")
+    }
+    if outbox.Dropped() == 0 {
+        t.Error("full outbox not bounded")
+    }
+
+    close(release)
+    outbox.Stop(time.Now().Add(time.Second))
+    if outbox.Len() != 0 || len(published) == 0 {
+        t.Error("outbox not drained on stop:", outbox.Len(), len(published))
+    }
+}
+//EOF
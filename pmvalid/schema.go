@@ -0,0 +1,258 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmvalid
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "regexp"
+    "sort"
+    "unicode/utf8"
+)
+//
+// Schema
+//
+// Schema is JSON Schema subset: type, enum, const, required,
+// properties, additionalProperties, items, minItems, maxItems,
+// minimum, maximum, minLength, maxLength and pattern keywords.
+//
+type Schema struct {
+    Type                    interface{}         `json:"type,omitempty"`        // string or list of strings
+    Enum                    []interface{}       `json:"enum,omitempty"`
+    Const                   interface{}         `json:"const,omitempty"`
+    Required                []string            `json:"required,omitempty"`
+    Properties              map[string]*Schema  `json:"properties,omitempty"`
+    AdditionalProperties    *bool               `json:"additionalProperties,omitempty"`
+    Items                   *Schema             `json:"items,omitempty"`
+    MinItems                *int                `json:"minItems,omitempty"`
+    MaxItems                *int                `json:"maxItems,omitempty"`
+    Minimum                 *float64            `json:"minimum,omitempty"`
+    Maximum                 *float64            `json:"maximum,omitempty"`
+    MinLength               *int                `json:"minLength,omitempty"`
+    MaxLength               *int                `json:"maxLength,omitempty"`
+    Pattern                 string              `json:"pattern,omitempty"`
+
+    types                   []string
+    patternRe               *regexp.Regexp
+}
+
+var knownTypes = map[string]bool{
+    "object": true, "array": true, "string": true, "number": true,
+    "integer": true, "boolean": true, "null": true,
+}
+
+func SchemaFromString(source string) (*Schema, error) {
+    var err error
+    var schema Schema
+    err = json.Unmarshal([]byte(source), &schema)
+    if err != nil {
+        return &schema, err
+    }
+    err = schema.Compile()
+    if err != nil {
+        return &schema, err
+    }
+    return &schema, err
+}
+
+func (this *Schema) Compile() error {
+    var err error
+    this.types = make([]string, 0)
+    switch this.Type.(type) {
+        case nil:
+        case string:
+            this.types = append(this.types, this.Type.(string))
+        case []interface{}:
+            for _, item := range this.Type.([]interface{}) {
+                name, ok := item.(string)
+                if !ok {
+                    return fmt.Errorf("schema: wrong type %v", item)
+                }
+                this.types = append(this.types, name)
+            }
+        default:
+            return fmt.Errorf("schema: wrong type %v", this.Type)
+    }
+    for _, name := range this.types {
+        if !knownTypes[name] {
+            return fmt.Errorf("schema: unknown type %s", name)
+        }
+    }
+    if len(this.Pattern) > 0 {
+        this.patternRe, err = regexp.Compile(this.Pattern)
+        if err != nil {
+            return fmt.Errorf("schema: wrong pattern %s: %s", this.Pattern, err)
+        }
+    }
+    for name, property := range this.Properties {
+        if property == nil {
+            return fmt.Errorf("schema: empty property %s", name)
+        }
+        err = property.Compile()
+        if err != nil {
+            return err
+        }
+    }
+    if this.Items != nil {
+        err = this.Items.Compile()
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+//
+// Validate() checks decoded json document
+//
+func (this *Schema) Validate(document interface{}) error {
+    return this.validate(document, "$")
+}
+
+func (this *Schema) validate(value interface{}, location string) error {
+    var err error
+    if len(this.types) > 0 {
+        matched := false
+        for _, name := range this.types {
+            if typeOf(value, name) {
+                matched = true
+                break
+            }
+        }
+        if !matched {
+            return fmt.Errorf("%s: expected type %v", location, this.types)
+        }
+    }
+    if len(this.Enum) > 0 {
+        matched := false
+        for _, item := range this.Enum {
+            if equal(value, item) {
+                matched = true
+                break
+            }
+        }
+        if !matched {
+            return fmt.Errorf("%s: value not in enum", location)
+        }
+    }
+    if this.Const != nil && !equal(value, this.Const) {
+        return fmt.Errorf("%s: value not equal const", location)
+    }
+
+    switch value.(type) {
+        case map[string]interface{}:
+            err = this.validateObject(value.(map[string]interface{}), location)
+        case []interface{}:
+            err = this.validateArray(value.([]interface{}), location)
+        case string:
+            err = this.validateString(value.(string), location)
+        case float64:
+            err = this.validateNumber(value.(float64), location)
+    }
+    return err
+}
+
+func (this *Schema) validateObject(object map[string]interface{}, location string) error {
+    var err error
+    for _, name := range this.Required {
+        if _, exists := object[name]; !exists {
+            return fmt.Errorf("%s: missing required property %s", location, name)
+        }
+    }
+    names := make([]string, 0, len(object))
+    for name := range object {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        property, exists := this.Properties[name]
+        if !exists {
+            if this.AdditionalProperties != nil && !*this.AdditionalProperties {
+                return fmt.Errorf("%s: additional property %s", location, name)
+            }
+            continue
+        }
+        err = property.validate(object[name], location + "." + name)
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+
+func (this *Schema) validateArray(array []interface{}, location string) error {
+    var err error
+    if this.MinItems != nil && len(array) < *this.MinItems {
+        return fmt.Errorf("%s: less than %d items", location, *this.MinItems)
+    }
+    if this.MaxItems != nil && len(array) > *this.MaxItems {
+        return fmt.Errorf("%s: more than %d items", location, *this.MaxItems)
+    }
+    if this.Items == nil {
+        return err
+    }
+    for i, item := range array {
+        err = this.Items.validate(item, fmt.Sprintf("%s[%d]", location, i))
+        if err != nil {
+            return err
+        }
+    }
+    return err
+}
+
+func (this *Schema) validateString(value string, location string) error {
+    var err error
+    length := utf8.RuneCountInString(value)
+    if this.MinLength != nil && length < *this.MinLength {
+        return fmt.Errorf("%s: shorter than %d", location, *this.MinLength)
+    }
+    if this.MaxLength != nil && length > *this.MaxLength {
+        return fmt.Errorf("%s: longer than %d", location, *this.MaxLength)
+    }
+    if this.patternRe != nil && !this.patternRe.MatchString(value) {
+        return fmt.Errorf("%s: not match pattern %s", location, this.Pattern)
+    }
+    return err
+}
+
+func (this *Schema) validateNumber(value float64, location string) error {
+    var err error
+    if this.Minimum != nil && value < *this.Minimum {
+        return fmt.Errorf("%s: less than %v", location, *this.Minimum)
+    }
+    if this.Maximum != nil && value > *this.Maximum {
+        return fmt.Errorf("%s: more than %v", location, *this.Maximum)
+    }
+    return err
+}
+
+func typeOf(value interface{}, name string) bool {
+    switch value.(type) {
+        case map[string]interface{}:
+            return name == "object"
+        case []interface{}:
+            return name == "array"
+        case string:
+            return name == "string"
+        case bool:
+            return name == "boolean"
+        case nil:
+            return name == "null"
+        case float64:
+            if name == "number" {
+                return true
+            }
+            number := value.(float64)
+            return name == "integer" && number == math.Trunc(number)
+    }
+    return false
+}
+
+func equal(a, b interface{}) bool {
+    aBytes, _ := json.Marshal(a)
+    bBytes, _ := json.Marshal(b)
+    return string(aBytes) == string(bBytes)
+}
+//EOF
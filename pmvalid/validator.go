@@ -0,0 +1,93 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmvalid
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+const (
+    ReasonInvalid       string  = "invalid"
+    ReasonUnclaimed     string  = "unclaimed"
+    ReasonUndelivered   string  = "undelivered"
+    ReasonOverflow      string  = "overflow"
+)
+//
+// Binding
+//
+// Binding applies schema to topics matching regexp.
+//
+type Binding struct {
+    Match       string              `json:"match"`
+    Schema      json.RawMessage     `json:"schema"`
+
+    matchRe     *regexp.Regexp
+    schema      *Schema
+}
+//
+// Validator
+//
+type Validator struct {
+    Bindings    []Binding           `json:"schemas"`
+}
+
+func NewValidator() *Validator {
+    return &Validator{
+        Bindings:   make([]Binding, 0),
+    }
+}
+
+func ValidatorFromString(source string) (*Validator, error) {
+    var err error
+    validator := NewValidator()
+    if len(strings.TrimSpace(source)) == 0 {
+        return validator, err
+    }
+    err = json.Unmarshal([]byte(source), validator)
+    if err != nil {
+        return validator, err
+    }
+    for i := range validator.Bindings {
+        binding := &validator.Bindings[i]
+        binding.matchRe, err = regexp.Compile(binding.Match)
+        if err != nil {
+            return validator, fmt.Errorf("payload schema %d: wrong match pattern: %s", i, err)
+        }
+        binding.schema, err = SchemaFromString(string(binding.Schema))
+        if err != nil {
+            return validator, fmt.Errorf("payload schema %d: %s", i, err)
+        }
+    }
+    return validator, err
+}
+
+func (this *Validator) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// Validate() checks payload by first schema bound to topic,
+// topics without schema pass
+//
+func (this *Validator) Validate(topic string, payload []byte) error {
+    var err error
+    for _, binding := range this.Bindings {
+        if !binding.matchRe.MatchString(topic) {
+            continue
+        }
+        var document interface{}
+        err = json.Unmarshal(payload, &document)
+        if err != nil {
+            return errors.New("payload is not json")
+        }
+        return binding.schema.Validate(document)
+    }
+    return err
+}
+//EOF
@@ -0,0 +1,52 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package pmvalid
+
+import (
+    "testing"
+)
+
+
+func TestValidator(t *testing.T) {
+    validator, err := ValidatorFromString(`{"schemas": [{"match": "^gw/.*/status$", "schema": {
+            "type": "object", "required": ["id", "temp"],
+            "properties": {
+                "id":   {"type": "string", "pattern": "^[0-9a-f]+$"},
+                "temp": {"type": "number", "minimum": -40, "maximum": 85},
+                "tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+            }}}]}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = validator.Validate("gw/a1/status", []byte(`{"id": "a1", "temp": 21.5, "tags": ["x"]}`))
+    if err != nil {
+        t.Error("valid payload rejected:", err)
+    }
+    err = validator.Validate("gw/a1/other", []byte(`not json`))
+    if err != nil {
+        t.Error("topic without schema rejected:", err)
+    }
+
+    invalid := []string{
+        `not json`,
+        `{"id": "a1"}`,
+        `{"id": "XYZ", "temp": 1}`,
+        `{"id": "a1", "temp": 100}`,
+        `{"id": "a1", "temp": 1, "tags": [1]}`,
+        `{"id": "a1", "temp": 1, "tags": ["a", "b", "c"]}`,
+    }
+    for _, payload := range invalid {
+        err = validator.Validate("gw/a1/status", []byte(payload))
+        if err == nil {
+            t.Error("invalid payload accepted:", payload)
+        }
+    }
+
+    _, err = ValidatorFromString(`{"schemas": [{"match": ".*", "schema": {"type": "nothing"}}]}`)
+    if err == nil {
+        t.Error("unknown schema type accepted")
+    }
+}
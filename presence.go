@@ -0,0 +1,105 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "context"
+    "time"
+
+    "app/pmlog"
+    "app/pmdevs"
+)
+//
+//
+const (
+    // Device presence properties
+    devicePropertyLastSeenName          string = "LastSeen"
+
+    deviceStatusOnlineValue             string = "true"
+    deviceStatusOfflineValue            string = "false"
+)
+//
+//
+func (this *Application) SetStatusTimeout(timeout int64) {
+    this.statusTimeoutMutex.Lock()
+    defer this.statusTimeoutMutex.Unlock()
+    this.statusTimeout = timeout
+}
+//
+//
+func (this *Application) GetStatusTimeout() int64 {
+    this.statusTimeoutMutex.Lock()
+    defer this.statusTimeoutMutex.Unlock()
+    return this.statusTimeout
+}
+//
+// UpdateDevicePresence() writes device status when topic base comes online
+//
+func (this *Application) UpdateDevicePresence(ctx context.Context, topicBase string) {
+    change, changed := this.presence.Seen(topicBase)
+    if !changed {
+        return
+    }
+    this.WriteDevicePresence(ctx, change)
+}
+//
+// CheckDevicePresence() writes device status for topic bases passed timeout
+//
+func (this *Application) CheckDevicePresence() {
+    forgetAfter := this.GetLifecyclePolicy().DeleteAfter
+    if forgetAfter <= 0 {
+        forgetAfter = presenceForgetAfter
+    }
+    changes := this.presence.Expire(this.GetStatusTimeout(), forgetAfter)
+    for _, change := range changes {
+        this.WriteDevicePresence(this.appCtx, change)
+    }
+}
+//
+//
+func (this *Application) WriteDevicePresence(ctx context.Context, change pmdevs.Change) {
+    var err error
+    objectIds := change.ObjectIds
+    if !change.Resolved {
+        objectIds, err = this.pg.ListObjectIdsByPropertyValueCtx(ctx, propertyGroupCredential, mqttPropertyTopicBaseName, change.TopicBase)
+        if err != nil {
+            pmlog.LogError("unable resolve devices for topic base", change.TopicBase, "error:", err)
+            return
+        }
+        this.presence.Resolve(change.TopicBase, objectIds)
+    }
+
+    status := deviceStatusOfflineValue
+    if change.Online {
+        status = deviceStatusOnlineValue
+    }
+    lastSeen := time.Unix(change.LastSeen, 0).UTC().Format(time.RFC3339)
+
+    for _, objectId := range objectIds {
+        pmlog.LogInfo("device", objectId, "with topic base", change.TopicBase, "online:", status)
+        _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, propertyStatusName, status)
+        if err != nil {
+            pmlog.LogDebug("unable update device status:", err)
+        }
+        _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, devicePropertyLastSeenName, lastSeen)
+        if err != nil {
+            pmlog.LogDebug("unable update device last seen:", err)
+        }
+    }
+}
+//
+// GetPresenceProperties() reads device status timeout of application
+//
+func (this *Application) GetPresenceProperties() error {
+    var err error
+
+    statusTimeout, err := this.getInt64Property(propertyTimeoutName, propertyTimeoutDefaultValue)
+    if err != nil {
+        return err
+    }
+    this.SetStatusTimeout(statusTimeout)
+    return err
+}
+//EOF
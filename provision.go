@@ -0,0 +1,189 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "context"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmtools"
+    "app/pmprov"
+)
+//
+//
+func (this *Application) SetProvisionRule(rule *pmprov.Rule) {
+    this.provisionRuleMutex.Lock()
+    defer this.provisionRuleMutex.Unlock()
+    this.provisionRule = rule
+    this.provisionLimiter.SetLimit(rule.RateLimit)
+}
+//
+//
+func (this *Application) GetProvisionRule() *pmprov.Rule {
+    this.provisionRuleMutex.Lock()
+    defer this.provisionRuleMutex.Unlock()
+    return this.provisionRule
+}
+//
+//*********************************************************************//
+//
+func (this *Application) newSetProvisionRuleControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set provision rule"
+    control.Hidden          = false
+    control.RPC             = controlSetProvisionRuleName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetProvisionRuleControlArgRule() *pgschema.Control {
+    control := this.newSetProvisionRuleControl()
+    control.Description     = "Provision rule"
+    control.Type            = pgschema.StringType
+    control.Argument        = "rule"
+    return control
+}
+//
+//
+func (this *Application) newProvisionRuleProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyProvisionRuleName
+    property.Type           = pgschema.StringType
+    property.Description    = "Provision rule for unknown topics"
+    property.GroupName      = propertyGroupProvisioning
+    return property
+}
+//
+//
+func (this *Application) CheckOrCreateRuleDevice(ctx context.Context, topicName string) (string, bool, error) {
+    var err             error
+    var patternDefined  bool
+
+    rule := this.GetProvisionRule()
+    if !rule.Permitted(topicName) {
+        return topicName, patternDefined, err
+    }
+
+    gwCode, topicBase, err := rule.Device(topicName)
+    if err != nil {
+        pmlog.LogWarning("application provision rule:", err)
+        return topicName, patternDefined, nil
+    }
+    patternDefined = true
+
+    exists, err := this.deviceExists(ctx, rule.SchemaId, topicBase)
+    if err != nil || exists {
+        return topicBase, patternDefined, err
+    }
+
+    if !this.provisionLimiter.Allow() {
+        pmlog.LogWarning("application provision rate limit exceeded, skip device", gwCode)
+        return topicBase, patternDefined, err
+    }
+
+    pmlog.LogInfo("application trying to create new device by rule for", gwCode)
+
+    object := pgschema.NewObject()
+    object.Id               = pmtools.GetNewUUID()
+    object.SchemaId         = rule.SchemaId
+    object.Name             = rule.Name(topicName, topicBase, gwCode)
+    object.Description      = object.Name
+
+    objectId, err := this.pg.CreateObjectCtx(ctx, object)
+    if err != nil {
+        pmlog.LogError("error create new device by rule:", err)
+        return topicBase, patternDefined, err
+    }
+
+    _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, mqttPropertyBridgeObjectIdName, this.objectId)
+    if err != nil {
+        pmlog.LogInfo("error update bridge property:", err)
+    }
+    _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, mqttPropertyTopicBaseName, topicBase)
+    if err != nil {
+        pmlog.LogInfo("error update topic base property:", err)
+    }
+    for name, value := range rule.Values(topicName, topicBase, gwCode) {
+        _, err = this.pg.UpdateObjectPropertyByNameCtx(ctx, objectId, name, value)
+        if err != nil {
+            pmlog.LogInfo("error update property", name, "error:", err)
+        }
+    }
+    this.devices.Register(topicBase, objectId, true, 0)
+    this.presence.Forget(topicBase)
+    pmlog.LogInfo("application created new device by rule", objectId)
+    return topicBase, patternDefined, nil
+}
+//
+//
+func (this *Application) SetProvisionRuleController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackProvisionRuleArguments(controlMessage.Params)
+
+    rule, err := pmprov.RuleFromString(arguments.Rule)
+    if err != nil {
+        pmlog.LogError("wrong provision rule:", err)
+        return err
+    }
+    this.SetProvisionRule(rule)
+
+    pmlog.LogInfo("set provision rule:", rule.GetJSON())
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyProvisionRuleName, arguments.Rule)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+//*********************************************************************//
+//
+type ProvisionRuleArguments struct {
+    Rule   string      `json:"rule"`
+}
+
+func NewProvisionRuleArguments() *ProvisionRuleArguments {
+    var arguments ProvisionRuleArguments
+    return &arguments
+}
+
+func UnpackProvisionRuleArguments(jsonString string) (*ProvisionRuleArguments, error) {
+    var err error
+    var arguments ProvisionRuleArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *ProvisionRuleArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *ProvisionRuleArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetProvisionProperties() reads device provisioning rule of application
+//
+func (this *Application) GetProvisionProperties() error {
+    var err error
+
+    provisionRuleStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyProvisionRuleName)
+    if err != nil {
+        return err
+    }
+    provisionRule, err := pmprov.RuleFromString(provisionRuleStr)
+    if err != nil {
+        pmlog.LogError("application provision rule error:", err)
+        provisionRule = pmprov.NewRule()
+        err = nil
+    }
+    this.SetProvisionRule(provisionRule)
+    return err
+}
+//EOF
@@ -0,0 +1,188 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "errors"
+    "encoding/json"
+    "encoding/base64"
+    "time"
+    "strconv"
+    "unicode/utf8"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmtools"
+    "app/pmrpc"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+//
+//
+func (this *Application) newRequestControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Send request and wait device reply"
+    control.Hidden          = false
+    control.RPC             = controlRequestName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newRequestControlArgTopicName() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Topic name"
+    control.Type            = pgschema.StringType
+    control.Argument        = "topicName"
+    return control
+}
+func (this *Application) newRequestControlArgPayload() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Request payload, json object"
+    control.Type            = pgschema.StringType
+    control.Argument        = "payload"
+    return control
+}
+func (this *Application) newRequestControlArgEncoding() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Payload encoding"
+    control.Type            = pgschema.StringType
+    control.Argument        = "encoding"
+    control.ValueSet        = "utf8,base64,hex"
+    return control
+}
+func (this *Application) newRequestControlArgReplyTopic() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Reply topic name"
+    control.Type            = pgschema.StringType
+    control.Argument        = "replyTopic"
+    return control
+}
+func (this *Application) newRequestControlArgCorrelationField() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Correlation id payload field"
+    control.Type            = pgschema.StringType
+    control.Argument        = "correlationField"
+    control.DefaultValue    = pmrpc.DefaultField
+    return control
+}
+func (this *Application) newRequestControlArgTimeout() *pgschema.Control {
+    control := this.newRequestControl()
+    control.Description     = "Reply timeout, sec"
+    control.Type            = pgschema.IntType
+    control.Argument        = "timeout"
+    control.DefaultValue    = strconv.Itoa(pmrpc.DefaultTimeout)
+    return control
+}
+//
+// RequestController() publishes request with correlation id and
+// reports device reply as control execution result
+//
+func (this *Application) RequestController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+
+    reply, err := this.SendRequest(controlMessage)
+    if err != nil {
+        pmlog.LogError("request error:", err)
+        return this.pg.CreateControlExecutionReport(controlMessage.Id, true, true, err.Error())
+    }
+    report := string(reply)
+    if !utf8.Valid(reply) {
+        report = base64.StdEncoding.EncodeToString(reply)
+    }
+    return this.pg.CreateControlExecutionReport(controlMessage.Id, false, true, report)
+}
+//
+//
+func (this *Application) SendRequest(controlMessage pgcore.ControlExecutionMessage) ([]byte, error) {
+    var err error
+    var reply []byte
+
+    arguments, err := UnpackRequestArguments(controlMessage.Params)
+    if err != nil {
+        return reply, err
+    }
+    if len(arguments.TopicName) == 0 || len(arguments.ReplyTopic) == 0 {
+        return reply, errors.New("request: empty topic or reply topic name")
+    }
+    field := arguments.CorrelationField
+    if len(field) == 0 {
+        field = pmrpc.DefaultField
+    }
+    timeout, err := parseIntArgument(arguments.Timeout, pmrpc.DefaultTimeout)
+    if err != nil {
+        return reply, err
+    }
+    payload, err := pgcore.DecodePayload(arguments.Payload, arguments.Encoding)
+    if err != nil {
+        return reply, err
+    }
+    correlationId := pmtools.GetNewUUID()
+    payload, err = pmrpc.Inject(payload, field, correlationId)
+    if err != nil {
+        return reply, err
+    }
+
+    replyHandler := func(client mqtt.Client, message mqtt.Message) {
+        this.requester.Dispatch(message.Payload())
+    }
+    subscribe := func() error {
+        return this.SubscribeTemporary(arguments.ReplyTopic, replyHandler)
+    }
+    unsubscribe := func() error {
+        return this.UnsubscribeTemporary(arguments.ReplyTopic)
+    }
+    err = this.requester.Acquire(correlationId, field, arguments.ReplyTopic, subscribe)
+    if err != nil {
+        return reply, err
+    }
+    defer this.requester.Release(correlationId, arguments.ReplyTopic, unsubscribe)
+
+    pmlog.LogDetail("send request", correlationId, "to topic", arguments.TopicName, "reply topic", arguments.ReplyTopic)
+    err = this.tr.PublishBytes(arguments.TopicName, payload)
+    if err != nil {
+        return reply, err
+    }
+    reply, err = this.requester.Wait(this.appCtx, correlationId, time.Duration(timeout) * time.Second)
+    if err != nil {
+        return reply, err
+    }
+    pmlog.LogDetail("got reply", correlationId, "size", len(reply))
+    return reply, err
+}
+//
+//*********************************************************************//
+//
+type RequestArguments struct {
+    TopicName           string      `json:"topicName"`
+    Payload             string      `json:"payload"`
+    Encoding            string      `json:"encoding"`
+    ReplyTopic          string      `json:"replyTopic"`
+    CorrelationField    string      `json:"correlationField"`
+    Timeout             string      `json:"timeout"`
+}
+
+func NewRequestArguments() *RequestArguments {
+    var arguments RequestArguments
+    return &arguments
+}
+
+func UnpackRequestArguments(jsonString string) (*RequestArguments, error) {
+    var err error
+    var arguments RequestArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *RequestArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *RequestArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//EOF
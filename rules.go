@@ -0,0 +1,140 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pgcore"
+    "app/pmrules"
+)
+//
+//
+const (
+    // Topic rewrite and transform rules, json
+    propertyTopicRulesName              string = "TopicRules"
+    propertyTopicRulesDefaultValue      string = `{"rules":[` +
+        `{"name":"senso8-sys","match":"^SENSO8/nbiot/sys/(.*)$","topicBase":"SENSO8/nbiot/data/$1"},` +
+        `{"name":"senso8-data","match":"^SENSO8/nbiot/data/(.*)$","topicBase":"SENSO8/nbiot/data/$1"},` +
+        `{"name":"minew-g1","match":"^/gw/(.*)/status$","topicBase":"gw/$1"}]}`
+)
+//
+//
+func (this *Application) SetTopicRules(rules *pmrules.Rules) {
+    this.topicRulesMutex.Lock()
+    defer this.topicRulesMutex.Unlock()
+    this.topicRules = rules
+}
+//
+//
+func (this *Application) GetTopicRules() *pmrules.Rules {
+    this.topicRulesMutex.Lock()
+    defer this.topicRulesMutex.Unlock()
+    return this.topicRules
+}
+//
+//*********************************************************************//
+//
+func (this *Application) newSetTopicRulesControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "Set topic rewrite rules"
+    control.Hidden          = false
+    control.RPC             = controlSetTopicRulesName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+func (this *Application) newSetTopicRulesControlArgRules() *pgschema.Control {
+    control := this.newSetTopicRulesControl()
+    control.Description     = "Topic rules"
+    control.Type            = pgschema.StringType
+    control.Argument        = "rules"
+    return control
+}
+//
+//
+func (this *Application) newTopicRulesProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyTopicRulesName
+    property.Type           = pgschema.StringType
+    property.Description    = "Topic rewrite and transform rules"
+    property.GroupName      = propertyGroupRouting
+    property.DefaultValue   = propertyTopicRulesDefaultValue
+    return property
+}
+//
+// SetTopicRulesController() validates and applies rules without transport restart
+//
+func (this *Application) SetTopicRulesController(controlMessage pgcore.ControlExecutionMessage) error {
+    var err error
+    arguments, _ := UnpackTopicRulesArguments(controlMessage.Params)
+
+    rules, err := pmrules.RulesFromString(arguments.Rules)
+    if err != nil {
+        pmlog.LogError("wrong topic rules:", err)
+        return err
+    }
+    this.SetTopicRules(rules)
+    pmlog.LogInfo("set topic rules:", rules.GetJSON())
+
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyTopicRulesName, arguments.Rules)
+    if err != nil {
+        return err
+    }
+    return err
+}
+//
+//*********************************************************************//
+//
+type TopicRulesArguments struct {
+    Rules   string      `json:"rules"`
+}
+
+func NewTopicRulesArguments() *TopicRulesArguments {
+    var arguments TopicRulesArguments
+    return &arguments
+}
+
+func UnpackTopicRulesArguments(jsonString string) (*TopicRulesArguments, error) {
+    var err error
+    var arguments TopicRulesArguments
+    err = json.Unmarshal([]byte(jsonString), &arguments)
+    return &arguments, err
+}
+
+func (this *TopicRulesArguments) Pack() string {
+    jsonBytes, _ := json.Marshal(this)
+    return pgcore.Escape(string(jsonBytes))
+}
+
+func (this *TopicRulesArguments) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//
+// GetTopicRulesProperties() reads topic rewrite and transform rules of application
+//
+func (this *Application) GetTopicRulesProperties() error {
+    var err error
+
+    topicRulesStr, err := this.pg.GetObjectPropertyValue(this.objectId, propertyTopicRulesName)
+    if err != nil {
+        return err
+    }
+    if len(topicRulesStr) == 0 {
+        topicRulesStr = propertyTopicRulesDefaultValue
+    }
+    topicRules, err := pmrules.RulesFromString(topicRulesStr)
+    if err != nil {
+        pmlog.LogError("application topic rules error:", err)
+        topicRules, _ = pmrules.RulesFromString(propertyTopicRulesDefaultValue)
+        err = nil
+    }
+    this.SetTopicRules(topicRules)
+    return err
+}
+//EOF
@@ -0,0 +1,104 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "context"
+    "os"
+    "os/signal"
+    "time"
+    "sync/atomic"
+    "syscall"
+
+    "app/pmlog"
+)
+//
+// WaitSignals() starts shutdown on SIGTERM or SIGINT
+//
+func (this *Application) WaitSignals() {
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+    sig := <-signals
+    pmlog.LogInfo("application got signal", sig)
+    this.Shutdown()
+    close(this.shutdownDone)
+}
+//
+//
+func (this *Application) WaitShutdown() {
+    <-this.shutdownDone
+    pmlog.LogInfo("application stopped")
+}
+//
+//
+const (
+    drainInterval       time.Duration   = 100   // ms
+    closeQuiesce        time.Duration   = 1     // sec
+)
+//
+// Shutdown() stops intake, drains in-flight messages within deadline,
+// publishes offline state and closes broker and core connections
+//
+func (this *Application) Shutdown() {
+    pmlog.LogInfo("application trying to shutdown")
+    atomic.StoreInt32(&this.stopping, 1)
+    deadline := time.Now().Add(time.Duration(this.config.ShutdownTimeout) * time.Second)
+
+    for _, topic := range this.topics.GetArray() {
+        err := this.tr.Unsubscribe(topic)
+        if err != nil {
+            pmlog.LogWarning("application unsubscribe topic", topic, "error:", err)
+        }
+    }
+    this.forwarder.Stop()
+
+    for atomic.LoadInt64(&this.inflight) > 0 && time.Now().Before(deadline) {
+        time.Sleep(drainInterval * time.Millisecond)
+    }
+    inflight := atomic.LoadInt64(&this.inflight)
+    if inflight > 0 {
+        pmlog.LogWarning("application shutdown with", inflight, "messages in flight")
+    }
+    this.DrainBacklog(deadline)
+    this.deadLetters.Stop(deadline)
+    this.tr.Close(closeQuiesce * time.Second)
+
+    this.WriteDeadLetterCount()
+    this.WriteUnmatchedCount()
+    this.WriteForwardStats()
+    _, err := this.pg.UpdateObjectPropertyByName(this.objectId, propertyStatusName, "false")
+    if err != nil {
+        pmlog.LogError("error update status property:", err)
+    }
+    this.UnbindTransport()
+
+    stopped := make(chan struct{})
+    go func() {
+        this.StopWoWControlSubsrWatcher()
+        this.StopWWControlSubsription()
+        close(stopped)
+    }()
+    select {
+        case <-stopped:
+        case <-time.After(time.Until(deadline)):
+            pmlog.LogWarning("application control subscription not stopped in time")
+    }
+    this.appCancel()
+    this.tokens.Wait()
+
+    ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(closeQuiesce * time.Second))
+    defer cancel()
+    if this.admin != nil {
+        this.admin.Stop(ctx)
+    }
+    if this.probes != nil {
+        this.probes.Stop(ctx)
+    }
+    if this.metrics != nil {
+        this.metrics.Stop(ctx)
+    }
+    pmlog.LogInfo("application shutdown done")
+}
+//EOF
@@ -0,0 +1,55 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "app/pmtopics"
+    "app/mqtrans"
+
+    mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+//
+// SubscribeTemporary() subscribes handler to topic of one operation.
+// Topic covered by bridge topics is not subscribed to keep bridge
+// subscription intact, topic handler passes its messages to handler.
+//
+func (this *Application) SubscribeTemporary(topic string, handler mqtrans.Handler) error {
+    if this.topics.Covers(topic) {
+        this.tapsMutex.Lock()
+        defer this.tapsMutex.Unlock()
+        this.taps[topic] = handler
+        return nil
+    }
+    return this.tr.Subscribe(topic, handler)
+}
+//
+//
+func (this *Application) UnsubscribeTemporary(topic string) error {
+    this.tapsMutex.Lock()
+    _, tapped := this.taps[topic]
+    delete(this.taps, topic)
+    this.tapsMutex.Unlock()
+    if tapped {
+        return nil
+    }
+    return this.tr.Unsubscribe(topic)
+}
+//
+// DispatchTaps() passes bridge topic message to temporary handlers
+//
+func (this *Application) DispatchTaps(client mqtt.Client, message mqtt.Message) {
+    handlers := make([]mqtrans.Handler, 0)
+    this.tapsMutex.Lock()
+    for topic, handler := range this.taps {
+        if pmtopics.Match(topic, message.Topic()) {
+            handlers = append(handlers, handler)
+        }
+    }
+    this.tapsMutex.Unlock()
+    for _, handler := range handlers {
+        handler(client, message)
+    }
+}
+//EOF
@@ -0,0 +1,105 @@
+/*
+ * Copyright:  Pixel Networks <support@pixel-networks.com>
+ */
+
+package main
+
+import (
+    "encoding/json"
+    "sync/atomic"
+    "strconv"
+
+    "app/pmlog"
+    "app/pgschema"
+    "app/pmprov"
+)
+//
+//
+const (
+    // Uplinks which no device claimed
+    propertyUnmatchedCountName          string = "UnmatchedCount"
+    propertyProvisionSuggestionName     string = "ProvisionSuggestion"
+)
+//
+//*********************************************************************//
+//
+func (this *Application) newListUnmatchedControl() *pgschema.Control {
+    control := pgschema.NewControl()
+    control.Description     = "List recent unmatched topic bases"
+    control.Hidden          = false
+    control.RPC             = controlListUnmatchedName
+    control.Type            = pgschema.StringType
+    control.Argument        = control.RPC
+    return control
+}
+//
+//
+func (this *Application) newUnmatchedCountProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyUnmatchedCountName
+    property.Type           = pgschema.IntType
+    property.Description    = "Messages matched no device"
+    property.GroupName      = propertyGroupProvisioning
+    property.DefaultValue   = "0"
+    return property
+}
+//
+//
+func (this *Application) newProvisionSuggestionProperty() *pgschema.Property {
+    property := pgschema.NewProperty()
+    property.Property       = propertyProvisionSuggestionName
+    property.Type           = pgschema.StringType
+    property.Description    = "Provision rule suggestion for unmatched topic base"
+    property.GroupName      = propertyGroupProvisioning
+    property.DefaultValue   = ""
+    return property
+}
+//
+// UnmatchedMessage() tracks topic base without device and suggests
+// provision rule for new one, also with disabled auto provision
+//
+func (this *Application) UnmatchedMessage(topicBase string, topic string) {
+    var err error
+    entry, isNew := this.unmatched.Add(topicBase, topic)
+    if !isNew || !this.suggestionLimiter.Allow() {
+        return
+    }
+    suggestion := ProvisionSuggestion{
+        TopicBase:  entry.TopicBase,
+        Topic:      entry.Topic,
+        Rule:       pmprov.Suggest(topicBase, entry.Topic, genericDriverSchemaId),
+    }
+    pmlog.LogWarning("no device for topic base", topicBase, "suggested provision rule:", suggestion.Rule.GetJSON())
+    _, err = this.pg.UpdateObjectPropertyByName(this.objectId, propertyProvisionSuggestionName, suggestion.GetJSON())
+    if err != nil {
+        pmlog.LogError("error update provision suggestion property:", err)
+    }
+}
+//
+//
+func (this *Application) WriteUnmatchedCount() {
+    count := this.unmatched.Total()
+    if count == atomic.LoadInt64(&this.unmatchedWritten) {
+        return
+    }
+    _, err := this.pg.UpdateObjectPropertyByName(this.objectId, propertyUnmatchedCountName, strconv.FormatInt(count, 10))
+    if err != nil {
+        pmlog.LogError("error update unmatched count property:", err)
+        return
+    }
+    atomic.StoreInt64(&this.unmatchedWritten, count)
+}
+//
+//*********************************************************************//
+//
+type ProvisionSuggestion struct {
+    TopicBase   string          `json:"topicBase"`
+    Topic       string          `json:"topic"`
+    Rule        *pmprov.Rule    `json:"rule"`
+}
+
+func (this *ProvisionSuggestion) GetJSON() string {
+    jsonBytes, _ := json.Marshal(this)
+    return string(jsonBytes)
+}
+//EOF
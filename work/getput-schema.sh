@@ -1,8 +0,0 @@
-#!/bin/sh
-
-
-export PGPASSWORD="3Ah6XTr1LFXQezWN_j3_95w18NXm7Z-n" 
-
-psql -h 127.0.0.1 -U postgres pixelcore < getput-schema.sql
-#EOF
-